@@ -42,6 +42,10 @@ type ApicurioRegistrySpecConfiguration struct {
 	Sql ApicurioRegistrySpecConfigurationSql `json:"sql,omitempty"`
 	// Configuration of Apicurio Registry KafkaSQL storage
 	Kafkasql ApicurioRegistrySpecConfigurationKafkasql `json:"kafkasql,omitempty"`
+	// Persistence migration:
+	//
+	// Configure the migration of Apicurio Registry data when the storage type is changed.
+	PersistenceMigration ApicurioRegistrySpecConfigurationPersistenceMigration `json:"persistenceMigration,omitempty"`
 	// Configuration of Apicurio Registry web console
 	UI ApicurioRegistrySpecConfigurationUI `json:"ui,omitempty"`
 	// Third-party (non-Apicurio) library log level
@@ -55,6 +59,38 @@ type ApicurioRegistrySpecConfiguration struct {
 	// List of additional environment variables that will be
 	// provided to the Apicurio Registry application.
 	Env []core.EnvVar `json:"env,omitempty"`
+	// Environment variables from ConfigMaps and Secrets:
+	//
+	// List of ConfigMaps and Secrets, whose keys will be provided
+	// to the Apicurio Registry application as environment variables, with an optional prefix.
+	// Environment variables managed by the operator, or configured using the `env` field, take precedence.
+	EnvFrom []core.EnvFromSource `json:"envFrom,omitempty"`
+	// Application properties ConfigMap:
+	//
+	// Reference to a ConfigMap that contains Apicurio Registry (Quarkus) configuration properties
+	// under the `application.properties` key.
+	// The file is mounted to the `/deployments/config` directory, and the pods are restarted when it changes.
+	// Environment variables take precedence over the properties.
+	PropertiesConfigMapRef *core.LocalObjectReference `json:"propertiesConfigMapRef,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationPersistenceMigration struct {
+	// Enabled:
+	//
+	// When the storage type is changed, make the existing deployment read-only and export the data,
+	// roll out the new storage, import the data, and only then remove the configuration of the previous storage.
+	// The configuration of the previous storage must be kept in the resource until the migration is completed.
+	Enabled bool `json:"enabled,omitempty"`
+	// Storage:
+	//
+	// Location where the exported data is stored during the migration.
+	// Required when the migration is enabled.
+	Storage ApicurioRegistryBackupStorage `json:"storage,omitempty"`
+	// Registry authentication:
+	//
+	// Credentials used to export and import the data, with the same options as the ApicurioRegistryBackup `registryAuth` section.
+	// Required when authentication is enabled.
+	RegistryAuth ApicurioRegistryBackupRegistryAuth `json:"registryAuth,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationDataSource struct {
@@ -67,11 +103,82 @@ type ApicurioRegistrySpecConfigurationDataSource struct {
 	UserName string `json:"userName,omitempty"`
 	// Data source password
 	Password string `json:"password,omitempty"` // TODO Support Secrets
+	// TLS:
+	//
+	// Connect to the database using TLS. The TLS parameters of the data source URL
+	// are replaced by the values configured here.
+	Tls ApicurioRegistrySpecConfigurationDataSourceTls `json:"tls,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationDataSourceTls struct {
+	// SSL mode:
+	//
+	// One of `disable`, `allow`, `prefer`, `require`, `verify-ca`, or `verify-full`.
+	// Default value is `verify-full` if the CA Secret is configured, `require` otherwise.
+	SslMode string `json:"sslMode,omitempty"`
+	// CA Secret name:
+	//
+	// Name of a Secret that contains the CA certificate of the database server
+	// under the `ca.crt` key. Required for the `verify-ca` and `verify-full` modes.
+	CaSecretName string `json:"caSecretName,omitempty"`
+	// Client certificate Secret name:
+	//
+	// Name of a Secret that contains the client certificate under the `tls.crt` key,
+	// and the unencrypted private key (in PKCS #8 DER format) under the `key.der` key,
+	// for example, a Secret created by cert-manager with the DER additional output format.
+	ClientCertificateSecretName string `json:"clientCertificateSecretName,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationSql struct {
 	// SQL data source
 	DataSource ApicurioRegistrySpecConfigurationDataSource `json:"dataSource,omitempty"`
+	// PostgreSQL cluster reference:
+	//
+	// Use a PostgreSQL cluster managed by CloudNativePG or Crunchy Postgres for Kubernetes (PGO).
+	// The data source URL and credentials are resolved from the referenced resource,
+	// and must not be configured in the data source section.
+	PostgresClusterRef *ApicurioRegistrySpecConfigurationSqlPostgresClusterRef `json:"postgresClusterRef,omitempty"`
+	// Connection pool:
+	//
+	// Configuration of the database connection pool.
+	Pool ApicurioRegistrySpecConfigurationSqlPool `json:"pool,omitempty"`
+	// JDBC properties:
+	//
+	// Additional properties of the PostgreSQL JDBC driver, for example, `connectTimeout`.
+	// The properties are added to the data source URL.
+	JdbcProperties map[string]string `json:"jdbcProperties,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationSqlPostgresClusterRef struct {
+	// Kind:
+	//
+	// `Cluster` for a CloudNativePG cluster, or `PostgresCluster` for a PGO cluster.
+	Kind string `json:"kind,omitempty"`
+	// Name:
+	//
+	// Name of the resource in the same namespace.
+	Name string `json:"name,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationSqlPool struct {
+	// Minimum size:
+	//
+	// Minimum number of connections in the pool, default value is 20.
+	// The pool is initialized with the same number of connections.
+	MinSize int32 `json:"minSize,omitempty"`
+	// Maximum size:
+	//
+	// Maximum number of connections in the pool, default value is 100.
+	MaxSize int32 `json:"maxSize,omitempty"`
+	// Acquisition timeout:
+	//
+	// How long to wait for a connection when the pool is exhausted, for example, `5s`.
+	AcquisitionTimeout string `json:"acquisitionTimeout,omitempty"`
+	// Leak detection interval:
+	//
+	// Interval of the checks for connections that have been held for too long, for example, `10m`.
+	// Leak detection is disabled by default.
+	LeakDetectionInterval string `json:"leakDetectionInterval,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationKafkasql struct {
@@ -83,8 +190,61 @@ type ApicurioRegistrySpecConfigurationKafkasql struct {
 	// Kafka security configuration:
 	//
 	// Provide the following configuration options if your Kafka cluster
-	// is secured using TLS or SCRAM.
+	// is secured using TLS, SCRAM, or OAuth.
 	Security ApicurioRegistrySpecConfigurationKafkaSecurity `json:"security,omitempty"`
+	// Strimzi:
+	//
+	// Use the Kafka cluster and user managed by Strimzi.
+	// The bootstrap servers and security configuration are read from the referenced resources,
+	// and the storage topic is created as a KafkaTopic resource.
+	Strimzi ApicurioRegistrySpecConfigurationKafkasqlStrimzi `json:"strimzi,omitempty"`
+	// Topic:
+	//
+	// Name of the Kafka topic used to store the data, default value is `kafkasql-journal`.
+	// Use a different topic for each Apicurio Registry instance that shares the Kafka cluster.
+	Topic string `json:"topic,omitempty"`
+	// Consumer group ID prefix:
+	//
+	// Prefix of the Kafka consumer group ID, default value is `apicurio-registry-`.
+	// Each replica uses a unique consumer group, because it has to read all of the data.
+	ConsumerGroupIdPrefix string `json:"consumerGroupIdPrefix,omitempty"`
+	// Kafka client properties:
+	//
+	// Additional properties of the Kafka clients, for example, timeouts.
+	Properties ApicurioRegistrySpecConfigurationKafkasqlProperties `json:"properties,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationKafkasqlStrimzi struct {
+	// Kafka reference:
+	//
+	// Name of the Strimzi Kafka resource in the same namespace.
+	KafkaRef *core.LocalObjectReference `json:"kafkaRef,omitempty"`
+	// KafkaUser reference:
+	//
+	// Name of the Strimzi KafkaUser resource in the same namespace, with `tls` or `scram-sha-512` authentication.
+	// If not set, the Kafka cluster is used without authentication.
+	KafkaUserRef *core.LocalObjectReference `json:"kafkaUserRef,omitempty"`
+	// Listener name:
+	//
+	// Name of the Kafka listener to use. By default, the first internal listener
+	// with the authentication matching the KafkaUser is used.
+	ListenerName string `json:"listenerName,omitempty"`
+	// Topic replication factor:
+	//
+	// Replication factor of the KafkaTopic created by the operator for the storage topic.
+	// By default, the number of Kafka brokers, up to 3.
+	TopicReplicationFactor int32 `json:"topicReplicationFactor,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationKafkasqlProperties struct {
+	// Properties of all Kafka clients
+	Common map[string]string `json:"common,omitempty"`
+	// Properties of the Kafka producer
+	Producer map[string]string `json:"producer,omitempty"`
+	// Properties of the Kafka consumer
+	Consumer map[string]string `json:"consumer,omitempty"`
+	// Properties of the Kafka admin client
+	Admin map[string]string `json:"admin,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationKafkaSecurity struct {
@@ -96,6 +256,10 @@ type ApicurioRegistrySpecConfigurationKafkaSecurity struct {
 	//
 	// Kafka is secured using SCRAM.
 	Scram ApicurioRegistrySpecConfigurationKafkaSecurityScram `json:"scram,omitempty"`
+	// OAuth:
+	//
+	// Kafka is secured using OAuth, the client authenticates with an access token (SASL OAUTHBEARER).
+	Oauth ApicurioRegistrySpecConfigurationKafkaSecurityOauth `json:"oauth,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationKafkaSecurityTls struct {
@@ -107,8 +271,18 @@ type ApicurioRegistrySpecConfigurationKafkaSecurityTls struct {
 	// Keystore Secret name:
 	//
 	// Name of a Secret that contains TLS keystore (in PKCS12 format)
-	// under the `user.p12` key, and keystore password under the `user.password` key.
+	// under the `user.p12` key, and keystore password under the `user.password` key,
+	// or a keystore in PEM format.
 	KeystoreSecretName string `json:"keystoreSecretName,omitempty"`
+	// Format:
+	//
+	// Format of the truststore and keystore, `PKCS12` or `PEM`.
+	// If not set, the format is detected from the keys of the Secrets.
+	// A PEM truststore Secret contains the CA certificates under the `ca.crt` key,
+	// and a PEM keystore Secret contains the certificate chain and the unencrypted private key (in PKCS #8 format)
+	// under the `tls.crt` and `tls.key` keys, for example, a Secret created by cert-manager
+	// with the `PKCS8` private key encoding.
+	Format string `json:"format,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationKafkaSecurityScram struct {
@@ -128,6 +302,34 @@ type ApicurioRegistrySpecConfigurationKafkaSecurityScram struct {
 	//
 	// Name of the SCRAM mechanism, default value is SCRAM-SHA-512.
 	Mechanism string `json:"mechanism,omitempty"`
+	// Format:
+	//
+	// Format of the truststore, `PKCS12` or `PEM`.
+	// If not set, the format is detected from the keys of the Secret.
+	// A PEM truststore Secret contains the CA certificates under the `ca.crt` key.
+	Format string `json:"format,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationKafkaSecurityOauth struct {
+	// Token endpoint URL:
+	//
+	// URL of the OAuth token endpoint, for example:
+	// `https://<keycloak host>/realms/<realm>/protocol/openid-connect/token`.
+	TokenEndpointUri string `json:"tokenEndpointUri,omitempty"`
+	// Client ID
+	ClientId string `json:"clientId,omitempty"`
+	// Client secret Secret name:
+	//
+	// Name of a Secret that contains the OAuth client secret
+	// under the `clientSecret` key.
+	ClientSecretName string `json:"clientSecretName,omitempty"`
+	// Truststore Secret name:
+	//
+	// Name of a Secret that contains TLS truststore (in PKCS12 format)
+	// under the `ca.p12` key, and truststore password under the `ca.password` key.
+	// The truststore is used to connect to the Kafka cluster and to the token endpoint.
+	// If not set, the default Java truststore is used.
+	TruststoreSecretName string `json:"truststoreSecretName,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationUI struct {
@@ -143,10 +345,40 @@ type ApicurioRegistrySpecConfigurationSecurity struct {
 	//
 	// Configure Apicurio Registry to use Keycloak for Identity and Access Management (IAM).
 	Keycloak ApicurioRegistrySpecConfigurationSecurityKeycloak `json:"keycloak,omitempty"`
+	// OIDC:
+	//
+	// Configure Apicurio Registry to use a generic OpenID Connect (OIDC) provider for Identity and Access Management (IAM).
+	// Can not be configured together with Keycloak.
+	Oidc ApicurioRegistrySpecConfigurationSecurityOidc `json:"oidc,omitempty"`
+	// Authorization:
+	//
+	// Configure the authorization of the REST API and UI users. Requires Keycloak or OIDC to be configured.
+	Authorization ApicurioRegistrySpecConfigurationSecurityAuthorization `json:"authorization,omitempty"`
 	// HTTPS:
 	//
 	// Configure Apicurio Registry to be accessible using HTTPS.
 	Https ApicurioRegistrySpecConfigurationSecurityHttps `json:"https,omitempty"`
+	// Trusted CA certificates:
+	//
+	// Additional CA certificates trusted by Apicurio Registry for outbound TLS connections,
+	// for example to Keycloak or an OIDC provider that uses an internal CA.
+	TrustedCa ApicurioRegistrySpecConfigurationSecurityTrustedCa `json:"trustedCa,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationSecurityTrustedCa struct {
+	// CA ConfigMap name:
+	//
+	// Name of a ConfigMap that contains the CA certificates (in PEM format) under the `ca-bundle.crt` key.
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// CA Secret name:
+	//
+	// Name of a Secret that contains the CA certificates (in PEM format) under the `ca.crt` key.
+	SecretName string `json:"secretName,omitempty"`
+	// Inject the cluster trusted CA bundle:
+	//
+	// On OpenShift, trust the CA certificates of the cluster-wide proxy configuration,
+	// which are injected into a ConfigMap managed by the operator.
+	InjectClusterBundle bool `json:"injectClusterBundle,omitempty"`
 }
 
 type ApicurioRegistrySpecConfigurationSecurityHttps struct {
@@ -174,6 +406,73 @@ type ApicurioRegistrySpecConfigurationSecurityKeycloak struct {
 	UiClientId string `json:"uiClientId,omitempty"`
 }
 
+type ApicurioRegistrySpecConfigurationSecurityOidc struct {
+	// Server URL:
+	//
+	// URL of the OIDC provider, usually the token issuer URL, for example, `https://login.example.com/realms/registry`.
+	// The provider configuration is discovered from `<server URL>/.well-known/openid-configuration`.
+	ServerUrl string `json:"serverUrl,omitempty"`
+	// Client ID for the REST API
+	ApiClientId string `json:"apiClientId,omitempty"`
+	// Client ID for the UI
+	UiClientId string `json:"uiClientId,omitempty"`
+	// Client secret Secret name:
+	//
+	// Name of a Secret that contains the secret of the REST API client under the `clientSecret` key.
+	// Required only if the provider requires client authentication.
+	ClientSecretName string `json:"clientSecretName,omitempty"`
+	// Token audience:
+	//
+	// Comma-separated list of the accepted values of the token `aud` claim.
+	// If not set, the audience is not verified.
+	TokenAudience string `json:"tokenAudience,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationSecurityAuthorization struct {
+	// Role-based authorization:
+	//
+	// Grant access based on the admin, developer, and read-only user roles.
+	RoleBased ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased `json:"roleBased,omitempty"`
+	// Owner-only authorization:
+	//
+	// Only the user who created an artifact can modify or delete it.
+	OwnerOnly bool `json:"ownerOnly,omitempty"`
+	// Anonymous read access:
+	//
+	// Allow anonymous users to read artifacts.
+	AnonymousReadAccess bool `json:"anonymousReadAccess,omitempty"`
+	// Authenticated read access:
+	//
+	// Allow any authenticated user to read artifacts, regardless of the role. Requires role-based authorization.
+	AuthenticatedReadAccess bool `json:"authenticatedReadAccess,omitempty"`
+	// Basic client credentials authentication:
+	//
+	// Allow clients to authenticate using HTTP basic authentication with the client ID and secret,
+	// which are exchanged for an access token.
+	BasicClientCredentials bool `json:"basicClientCredentials,omitempty"`
+}
+
+type ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased struct {
+	// Enabled
+	Enabled bool `json:"enabled,omitempty"`
+	// Role source:
+	//
+	// Either `token` to read the roles from the access token (default), or `application` to manage the roles in Apicurio Registry.
+	RoleSource string `json:"roleSource,omitempty"`
+	// Admin role:
+	//
+	// Name of the admin role in the access token, default value is `sr-admin`.
+	AdminRole string `json:"adminRole,omitempty"`
+	// Developer role:
+	//
+	// Name of the developer role in the access token, default value is `sr-developer`.
+	DeveloperRole string `json:"developerRole,omitempty"`
+	// Read-only role:
+	//
+	// Name of the read-only role in the access token, default value is `sr-readonly`.
+	ReadOnlyRole string `json:"readOnlyRole,omitempty"`
+}
+
 type ApicurioRegistrySpecDeploymentMetadata struct {
 	// Annotations:
 	//
@@ -240,6 +539,11 @@ type ApicurioRegistrySpecDeploymentManagedResources struct {
 // ### Status
 
 type ApicurioRegistryStatus struct {
+	// Observed generation:
+	//
+	// The `metadata.generation` of the ApicurioRegistry resource the status has been computed for.
+	// If it is lower than the current generation, the latest changes to the spec have not been processed yet.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// Information about the Apicurio Registry application
 	Info ApicurioRegistryStatusInfo `json:"info,omitempty"`
 	// Conditions:
@@ -250,11 +554,114 @@ type ApicurioRegistryStatus struct {
 	//
 	// Kubernetes resources managed by the Apicurio Registry Operator.
 	ManagedResources []ApicurioRegistryStatusManagedResource `json:"managedResources,omitempty"`
+	// Persistence:
+	//
+	// Storage used by the deployed Apicurio Registry application, and the state of the persistence migration.
+	Persistence *ApicurioRegistryStatusPersistence `json:"persistence,omitempty"`
+	// Replicas:
+	//
+	// Number of Apicurio Registry pods.
+	Replicas int32 `json:"replicas,omitempty"`
+	// Ready replicas:
+	//
+	// Number of Apicurio Registry pods that pass the readiness check.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Pods:
+	//
+	// Health of each Apicurio Registry pod.
+	Pods []ApicurioRegistryStatusPod `json:"pods,omitempty"`
+}
+
+type ApicurioRegistryStatusPod struct {
+	// Pod name
+	Name string `json:"name,omitempty"`
+	// Ready:
+	//
+	// The pod passes the readiness check.
+	Ready bool `json:"ready"`
+	// Live:
+	//
+	// The pod passes the liveness check.
+	Live bool `json:"live"`
+	// Message:
+	//
+	// Details about the failing check.
+	Message string `json:"message,omitempty"`
+}
+
+type ApicurioRegistryStatusPersistence struct {
+	// Storage type:
+	//
+	// Type of storage used by the deployed Apicurio Registry application.
+	Type string `json:"type,omitempty"`
+	// Migration:
+	//
+	// State of the most recent persistence migration.
+	Migration *ApicurioRegistryStatusPersistenceMigration `json:"migration,omitempty"`
+}
+
+type ApicurioRegistryStatusPersistenceMigration struct {
+	// Phase:
+	//
+	// One of `Exporting`, `RollingOut`, `Importing`, `CleaningUp`, `Completed` or `Failed`.
+	Phase string `json:"phase,omitempty"`
+	// Source storage type
+	From string `json:"from,omitempty"`
+	// Target storage type
+	To string `json:"to,omitempty"`
+	// Message:
+	//
+	// Details about the current phase, e.g. the error in case of failure.
+	Message string `json:"message,omitempty"`
+	// ApicurioRegistryBackup name:
+	//
+	// Name of the ApicurioRegistryBackup resource that exports the data.
+	BackupName string `json:"backupName,omitempty"`
+	// ApicurioRegistryRestore name:
+	//
+	// Name of the ApicurioRegistryRestore resource that imports the data.
+	RestoreName string `json:"restoreName,omitempty"`
+	// Start time
+	StartTime *meta.Time `json:"startTime,omitempty"`
+	// Completion time
+	CompletionTime *meta.Time `json:"completionTime,omitempty"`
 }
 
 type ApicurioRegistryStatusInfo struct {
 	// Apicurio Registry URL
 	Host string `json:"host,omitempty"`
+	// Image:
+	//
+	// Apicurio Registry image used by the deployment.
+	Image string `json:"image,omitempty"`
+	// Version:
+	//
+	// Version of the running Apicurio Registry application, as reported by the `/apis/registry/v2/system/info` endpoint.
+	Version string `json:"version,omitempty"`
+	// Persistence:
+	//
+	// Storage used by the deployed Apicurio Registry application, one of `mem`, `sql` or `kafkasql`.
+	Persistence string `json:"persistence,omitempty"`
+	// HTTPS enabled:
+	//
+	// Apicurio Registry is accessible using HTTPS via the Service.
+	HttpsEnabled bool `json:"httpsEnabled,omitempty"`
+	// Service URL:
+	//
+	// URL of Apicurio Registry inside the cluster, using the Service.
+	ServiceUrl string `json:"serviceUrl,omitempty"`
+	// UI URL:
+	//
+	// URL of the Apicurio Registry web console, using the Ingress host.
+	UiUrl string `json:"uiUrl,omitempty"`
+	// REST API URL:
+	//
+	// URL of the Apicurio Registry core REST API v2, using the Ingress host.
+	RestApiUrl string `json:"restApiUrl,omitempty"`
+	// Confluent compatible API URL:
+	//
+	// URL of the Confluent Schema Registry compatible REST API, using the Ingress host.
+	CcompatApiUrl string `json:"ccompatApiUrl,omitempty"`
 }
 
 type ApicurioRegistryStatusManagedResource struct {
@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BACKUP_RESULT_RUNNING   = "Running"
+	BACKUP_RESULT_SUCCEEDED = "Succeeded"
+	BACKUP_RESULT_FAILED    = "Failed"
+)
+
+// ### Spec
+
+// ApicurioRegistryBackupSpec defines the desired state of ApicurioRegistryBackup
+type ApicurioRegistryBackupSpec struct {
+	// Apicurio Registry name:
+	//
+	// Name of the ApicurioRegistry resource, in the same namespace, whose data will be exported.
+	RegistryName string `json:"registryName"`
+	// Schedule:
+	//
+	// Cron expression (in the standard 5-field format, e.g. `0 2 * * *`) that defines when to create backups.
+	// If empty, a single backup is created as soon as possible.
+	Schedule string `json:"schedule,omitempty"`
+	// Suspend:
+	//
+	// Do not create new scheduled backups while set.
+	Suspend bool `json:"suspend,omitempty"`
+	// Storage:
+	//
+	// Where the exported data (a ZIP file) is written.
+	// Exactly one of `pvc` or `s3` must be configured.
+	Storage ApicurioRegistryBackupStorage `json:"storage,omitempty"`
+	// Retention:
+	//
+	// Configure how many backups are kept in the storage.
+	Retention ApicurioRegistryBackupRetention `json:"retention,omitempty"`
+	// Apicurio Registry authentication:
+	//
+	// Credentials used to call Apicurio Registry, required if authentication is enabled.
+	RegistryAuth ApicurioRegistryBackupRegistryAuth `json:"registryAuth,omitempty"`
+}
+
+type ApicurioRegistryBackupStorage struct {
+	// PersistentVolumeClaim:
+	//
+	// Store the backup files on a PersistentVolumeClaim.
+	Pvc *ApicurioRegistryBackupStoragePvc `json:"pvc,omitempty"`
+	// S3:
+	//
+	// Store the backup files in an S3-compatible object storage, such as Amazon S3 or MinIO.
+	S3 *ApicurioRegistryBackupStorageS3 `json:"s3,omitempty"`
+}
+
+type ApicurioRegistryBackupStoragePvc struct {
+	// PersistentVolumeClaim name:
+	//
+	// Name of an existing PersistentVolumeClaim in the same namespace.
+	ClaimName string `json:"claimName"`
+	// Path:
+	//
+	// Directory within the volume where the backup files are stored. Default value is the volume root.
+	Path string `json:"path,omitempty"`
+}
+
+type ApicurioRegistryBackupStorageS3 struct {
+	// Endpoint URL:
+	//
+	// URL of the S3-compatible endpoint, for example: `https://s3.eu-west-1.amazonaws.com`
+	// or `http://minio.<namespace>.svc:9000`. Path-style bucket addressing is used.
+	Endpoint string `json:"endpoint"`
+	// Bucket
+	Bucket string `json:"bucket"`
+	// Key prefix:
+	//
+	// Prefix of the object keys, for example: `backups/registry/`.
+	Prefix string `json:"prefix,omitempty"`
+	// Region:
+	//
+	// Region used to sign the requests. Default value is `us-east-1`.
+	Region string `json:"region,omitempty"`
+	// Credentials Secret name:
+	//
+	// Name of a Secret that contains the access key under the `AWS_ACCESS_KEY_ID` key,
+	// and the secret key under the `AWS_SECRET_ACCESS_KEY` key.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+	// Skip TLS verification:
+	//
+	// Do not verify the certificate of the S3 endpoint.
+	// WARNING: Use only for testing.
+	InsecureSkipTlsVerify bool `json:"insecureSkipTlsVerify,omitempty"`
+}
+
+type ApicurioRegistryBackupRetention struct {
+	// Maximum number of backups:
+	//
+	// Older backup files are deleted when the number of successful backups exceeds this value.
+	// If zero or empty, backup files are never deleted.
+	// +kubebuilder:validation:Minimum=0
+	MaxBackups int32 `json:"maxBackups,omitempty"`
+}
+
+type ApicurioRegistryBackupRegistryAuth struct {
+	// Credentials Secret name:
+	//
+	// Name of a Secret that contains an access token under the `token` key,
+	// or the client ID and secret under the `clientId` and `clientSecret` keys.
+	// The client credentials are exchanged for an access token using the OAuth 2.0 client credentials grant.
+	// The client must have the admin role.
+	SecretName string `json:"secretName,omitempty"`
+	// Token endpoint:
+	//
+	// URL of the OAuth 2.0 token endpoint used to exchange the client credentials.
+	// Default value is derived from the Keycloak or OIDC configuration of the ApicurioRegistry.
+	TokenEndpoint string `json:"tokenEndpoint,omitempty"`
+}
+
+// ### Status
+
+type ApicurioRegistryBackupStatus struct {
+	// Conditions:
+	//
+	// Conditions of the backup process.
+	Conditions []meta.Condition `json:"conditions,omitempty"`
+	// Last scheduled time:
+	//
+	// The last time a backup was started.
+	LastScheduleTime *meta.Time `json:"lastScheduleTime,omitempty"`
+	// Next scheduled time:
+	//
+	// The next time a backup will be started, if a schedule is configured.
+	NextScheduleTime *meta.Time `json:"nextScheduleTime,omitempty"`
+	// Last backup:
+	//
+	// Result of the most recent backup attempt.
+	LastBackup *ApicurioRegistryBackupStatusEntry `json:"lastBackup,omitempty"`
+	// Backups:
+	//
+	// Successful backups that are currently retained in the storage, newest first.
+	Backups []ApicurioRegistryBackupStatusEntry `json:"backups,omitempty"`
+}
+
+type ApicurioRegistryBackupStatusEntry struct {
+	// Location:
+	//
+	// Path of the backup file within the PersistentVolumeClaim, or the S3 object key.
+	Location string `json:"location,omitempty"`
+	// Start time
+	StartTime *meta.Time `json:"startTime,omitempty"`
+	// Completion time
+	CompletionTime *meta.Time `json:"completionTime,omitempty"`
+	// Size in bytes
+	Size int64 `json:"size,omitempty"`
+	// Result:
+	//
+	// One of `Running`, `Succeeded` or `Failed`.
+	Result string `json:"result,omitempty"`
+	// Message:
+	//
+	// Details about the result, e.g. the error in case of failure.
+	Message string `json:"message,omitempty"`
+	// Job name:
+	//
+	// Name of the Job that writes the backup, when using PersistentVolumeClaim storage.
+	JobName string `json:"jobName,omitempty"`
+}
+
+// ### Roots
+
+// ApicurioRegistryBackup represents a one-shot or scheduled export of Apicurio Registry data
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.spec.registryName`
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Last Result",type=string,JSONPath=`.status.lastBackup.result`
+// +kubebuilder:printcolumn:name="Last Backup",type=date,JSONPath=`.status.lastBackup.completionTime`
+type ApicurioRegistryBackup struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ApicurioRegistryBackupSpec   `json:"spec,omitempty"`
+	Status ApicurioRegistryBackupStatus `json:"status,omitempty"`
+}
+
+// ApicurioRegistryBackupList contains a list of ApicurioRegistryBackup
+// +kubebuilder:object:root=true
+type ApicurioRegistryBackupList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []ApicurioRegistryBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApicurioRegistryBackup{}, &ApicurioRegistryBackupList{})
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	RESTORE_PHASE_PENDING              = "Pending"
+	RESTORE_PHASE_WAITING_FOR_REGISTRY = "WaitingForRegistry"
+	RESTORE_PHASE_IMPORTING            = "Importing"
+	RESTORE_PHASE_SUCCEEDED            = "Succeeded"
+	RESTORE_PHASE_FAILED               = "Failed"
+)
+
+// ### Spec
+
+// ApicurioRegistryRestoreSpec defines the desired state of ApicurioRegistryRestore
+type ApicurioRegistryRestoreSpec struct {
+	// Apicurio Registry name:
+	//
+	// Name of the ApicurioRegistry resource, in the same namespace, into which the data will be imported.
+	RegistryName string `json:"registryName"`
+	// Source:
+	//
+	// Location of the backup file (a ZIP file created by the export).
+	// Exactly one of the source options must be configured.
+	Source ApicurioRegistryRestoreSource `json:"source,omitempty"`
+	// Apicurio Registry authentication:
+	//
+	// Credentials used to call Apicurio Registry, required if authentication is enabled.
+	RegistryAuth ApicurioRegistryBackupRegistryAuth `json:"registryAuth,omitempty"`
+}
+
+type ApicurioRegistryRestoreSource struct {
+	// PersistentVolumeClaim:
+	//
+	// Read the backup file from a PersistentVolumeClaim.
+	Pvc *ApicurioRegistryRestoreSourcePvc `json:"pvc,omitempty"`
+	// Secret:
+	//
+	// Read the backup file from a key of a Secret.
+	Secret *ApicurioRegistryRestoreSourceKeyRef `json:"secret,omitempty"`
+	// ConfigMap:
+	//
+	// Read the backup file from a key of a ConfigMap, `binaryData` takes precedence over `data`.
+	ConfigMap *ApicurioRegistryRestoreSourceKeyRef `json:"configMap,omitempty"`
+	// S3:
+	//
+	// Download the backup file from an S3-compatible object storage, such as Amazon S3 or MinIO.
+	S3 *ApicurioRegistryRestoreSourceS3 `json:"s3,omitempty"`
+	// URL:
+	//
+	// Download the backup file from an HTTP(S) URL, for example a pre-signed S3 URL.
+	// The file is downloaded by the import Job, using the network identity of a pod in this namespace.
+	Url string `json:"url,omitempty"`
+	// Backup:
+	//
+	// Use a backup created by an ApicurioRegistryBackup resource in the same namespace.
+	Backup *ApicurioRegistryRestoreSourceBackup `json:"backup,omitempty"`
+}
+
+type ApicurioRegistryRestoreSourcePvc struct {
+	// PersistentVolumeClaim name
+	ClaimName string `json:"claimName"`
+	// Path:
+	//
+	// Path of the backup file within the volume.
+	Path string `json:"path"`
+}
+
+type ApicurioRegistryRestoreSourceKeyRef struct {
+	// Name
+	Name string `json:"name"`
+	// Key:
+	//
+	// Key that contains the backup file. Default value is `backup.zip`.
+	Key string `json:"key,omitempty"`
+}
+
+type ApicurioRegistryRestoreSourceS3 struct {
+	// Endpoint URL:
+	//
+	// URL of the S3-compatible endpoint. Path-style bucket addressing is used.
+	Endpoint string `json:"endpoint"`
+	// Bucket
+	Bucket string `json:"bucket"`
+	// Object key
+	Key string `json:"key"`
+	// Region:
+	//
+	// Region used to sign the requests. Default value is `us-east-1`.
+	Region string `json:"region,omitempty"`
+	// Credentials Secret name:
+	//
+	// Name of a Secret that contains the access key under the `AWS_ACCESS_KEY_ID` key,
+	// and the secret key under the `AWS_SECRET_ACCESS_KEY` key.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+	// Skip TLS verification:
+	//
+	// Do not verify the certificate of the S3 endpoint.
+	// WARNING: Use only for testing.
+	InsecureSkipTlsVerify bool `json:"insecureSkipTlsVerify,omitempty"`
+}
+
+type ApicurioRegistryRestoreSourceBackup struct {
+	// ApicurioRegistryBackup name
+	Name string `json:"name"`
+	// Location:
+	//
+	// Location of a retained backup, as listed in the ApicurioRegistryBackup status.
+	// Default value is the location of the most recent successful backup.
+	Location string `json:"location,omitempty"`
+}
+
+// ### Status
+
+type ApicurioRegistryRestoreStatus struct {
+	// Phase:
+	//
+	// One of `Pending`, `WaitingForRegistry`, `Importing`, `Succeeded` or `Failed`.
+	Phase string `json:"phase,omitempty"`
+	// Message:
+	//
+	// Details about the current phase, e.g. the error in case of failure.
+	Message string `json:"message,omitempty"`
+	// Start time
+	StartTime *meta.Time `json:"startTime,omitempty"`
+	// Completion time
+	CompletionTime *meta.Time `json:"completionTime,omitempty"`
+	// Job name:
+	//
+	// Name of the Job that imports the backup, when using PersistentVolumeClaim source.
+	JobName string `json:"jobName,omitempty"`
+	// Conditions:
+	//
+	// Conditions of the restore process.
+	Conditions []meta.Condition `json:"conditions,omitempty"`
+}
+
+// ### Roots
+
+// ApicurioRegistryRestore represents an import of previously exported data into an Apicurio Registry instance
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.spec.registryName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+type ApicurioRegistryRestore struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ApicurioRegistryRestoreSpec   `json:"spec,omitempty"`
+	Status ApicurioRegistryRestoreStatus `json:"status,omitempty"`
+}
+
+// ApicurioRegistryRestoreList contains a list of ApicurioRegistryRestore
+// +kubebuilder:object:root=true
+type ApicurioRegistryRestoreList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []ApicurioRegistryRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApicurioRegistryRestore{}, &ApicurioRegistryRestoreList{})
+}
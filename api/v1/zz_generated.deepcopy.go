@@ -54,6 +54,233 @@ func (in *ApicurioRegistry) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackup) DeepCopyInto(out *ApicurioRegistryBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackup.
+func (in *ApicurioRegistryBackup) DeepCopy() *ApicurioRegistryBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApicurioRegistryBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupList) DeepCopyInto(out *ApicurioRegistryBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ApicurioRegistryBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupList.
+func (in *ApicurioRegistryBackupList) DeepCopy() *ApicurioRegistryBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApicurioRegistryBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupRegistryAuth) DeepCopyInto(out *ApicurioRegistryBackupRegistryAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupRegistryAuth.
+func (in *ApicurioRegistryBackupRegistryAuth) DeepCopy() *ApicurioRegistryBackupRegistryAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupRegistryAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupRetention) DeepCopyInto(out *ApicurioRegistryBackupRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupRetention.
+func (in *ApicurioRegistryBackupRetention) DeepCopy() *ApicurioRegistryBackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupSpec) DeepCopyInto(out *ApicurioRegistryBackupSpec) {
+	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+	out.Retention = in.Retention
+	out.RegistryAuth = in.RegistryAuth
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupSpec.
+func (in *ApicurioRegistryBackupSpec) DeepCopy() *ApicurioRegistryBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupStatus) DeepCopyInto(out *ApicurioRegistryBackupStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastBackup != nil {
+		in, out := &in.LastBackup, &out.LastBackup
+		*out = new(ApicurioRegistryBackupStatusEntry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make([]ApicurioRegistryBackupStatusEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupStatus.
+func (in *ApicurioRegistryBackupStatus) DeepCopy() *ApicurioRegistryBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupStatusEntry) DeepCopyInto(out *ApicurioRegistryBackupStatusEntry) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupStatusEntry.
+func (in *ApicurioRegistryBackupStatusEntry) DeepCopy() *ApicurioRegistryBackupStatusEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupStatusEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupStorage) DeepCopyInto(out *ApicurioRegistryBackupStorage) {
+	*out = *in
+	if in.Pvc != nil {
+		in, out := &in.Pvc, &out.Pvc
+		*out = new(ApicurioRegistryBackupStoragePvc)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ApicurioRegistryBackupStorageS3)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupStorage.
+func (in *ApicurioRegistryBackupStorage) DeepCopy() *ApicurioRegistryBackupStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupStoragePvc) DeepCopyInto(out *ApicurioRegistryBackupStoragePvc) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupStoragePvc.
+func (in *ApicurioRegistryBackupStoragePvc) DeepCopy() *ApicurioRegistryBackupStoragePvc {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupStoragePvc)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryBackupStorageS3) DeepCopyInto(out *ApicurioRegistryBackupStorageS3) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryBackupStorageS3.
+func (in *ApicurioRegistryBackupStorageS3) DeepCopy() *ApicurioRegistryBackupStorageS3 {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryBackupStorageS3)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistryList) DeepCopyInto(out *ApicurioRegistryList) {
 	*out = *in
@@ -317,6 +544,212 @@ func (in *ApicurioRegistryPodTemplateSpec) DeepCopy() *ApicurioRegistryPodTempla
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestore) DeepCopyInto(out *ApicurioRegistryRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestore.
+func (in *ApicurioRegistryRestore) DeepCopy() *ApicurioRegistryRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApicurioRegistryRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreList) DeepCopyInto(out *ApicurioRegistryRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ApicurioRegistryRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreList.
+func (in *ApicurioRegistryRestoreList) DeepCopy() *ApicurioRegistryRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApicurioRegistryRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSource) DeepCopyInto(out *ApicurioRegistryRestoreSource) {
+	*out = *in
+	if in.Pvc != nil {
+		in, out := &in.Pvc, &out.Pvc
+		*out = new(ApicurioRegistryRestoreSourcePvc)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(ApicurioRegistryRestoreSourceKeyRef)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ApicurioRegistryRestoreSourceKeyRef)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ApicurioRegistryRestoreSourceS3)
+		**out = **in
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(ApicurioRegistryRestoreSourceBackup)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSource.
+func (in *ApicurioRegistryRestoreSource) DeepCopy() *ApicurioRegistryRestoreSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSourceBackup) DeepCopyInto(out *ApicurioRegistryRestoreSourceBackup) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSourceBackup.
+func (in *ApicurioRegistryRestoreSourceBackup) DeepCopy() *ApicurioRegistryRestoreSourceBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSourceBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSourceKeyRef) DeepCopyInto(out *ApicurioRegistryRestoreSourceKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSourceKeyRef.
+func (in *ApicurioRegistryRestoreSourceKeyRef) DeepCopy() *ApicurioRegistryRestoreSourceKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSourceKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSourcePvc) DeepCopyInto(out *ApicurioRegistryRestoreSourcePvc) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSourcePvc.
+func (in *ApicurioRegistryRestoreSourcePvc) DeepCopy() *ApicurioRegistryRestoreSourcePvc {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSourcePvc)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSourceS3) DeepCopyInto(out *ApicurioRegistryRestoreSourceS3) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSourceS3.
+func (in *ApicurioRegistryRestoreSourceS3) DeepCopy() *ApicurioRegistryRestoreSourceS3 {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSourceS3)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreSpec) DeepCopyInto(out *ApicurioRegistryRestoreSpec) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+	out.RegistryAuth = in.RegistryAuth
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreSpec.
+func (in *ApicurioRegistryRestoreSpec) DeepCopy() *ApicurioRegistryRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryRestoreStatus) DeepCopyInto(out *ApicurioRegistryRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryRestoreStatus.
+func (in *ApicurioRegistryRestoreStatus) DeepCopy() *ApicurioRegistryRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpec) DeepCopyInto(out *ApicurioRegistrySpec) {
 	*out = *in
@@ -337,8 +770,9 @@ func (in *ApicurioRegistrySpec) DeepCopy() *ApicurioRegistrySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfiguration) DeepCopyInto(out *ApicurioRegistrySpecConfiguration) {
 	*out = *in
-	out.Sql = in.Sql
-	out.Kafkasql = in.Kafkasql
+	in.Sql.DeepCopyInto(&out.Sql)
+	in.Kafkasql.DeepCopyInto(&out.Kafkasql)
+	in.PersistenceMigration.DeepCopyInto(&out.PersistenceMigration)
 	out.UI = in.UI
 	out.Security = in.Security
 	if in.Env != nil {
@@ -348,6 +782,18 @@ func (in *ApicurioRegistrySpecConfiguration) DeepCopyInto(out *ApicurioRegistryS
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]corev1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PropertiesConfigMapRef != nil {
+		in, out := &in.PropertiesConfigMapRef, &out.PropertiesConfigMapRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfiguration.
@@ -363,6 +809,7 @@ func (in *ApicurioRegistrySpecConfiguration) DeepCopy() *ApicurioRegistrySpecCon
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationDataSource) DeepCopyInto(out *ApicurioRegistrySpecConfigurationDataSource) {
 	*out = *in
+	out.Tls = in.Tls
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationDataSource.
@@ -375,11 +822,27 @@ func (in *ApicurioRegistrySpecConfigurationDataSource) DeepCopy() *ApicurioRegis
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationDataSourceTls) DeepCopyInto(out *ApicurioRegistrySpecConfigurationDataSourceTls) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationDataSourceTls.
+func (in *ApicurioRegistrySpecConfigurationDataSourceTls) DeepCopy() *ApicurioRegistrySpecConfigurationDataSourceTls {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationDataSourceTls)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationKafkaSecurity) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkaSecurity) {
 	*out = *in
 	out.Tls = in.Tls
 	out.Scram = in.Scram
+	out.Oauth = in.Oauth
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationKafkaSecurity.
@@ -392,6 +855,21 @@ func (in *ApicurioRegistrySpecConfigurationKafkaSecurity) DeepCopy() *ApicurioRe
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationKafkaSecurityOauth) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkaSecurityOauth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationKafkaSecurityOauth.
+func (in *ApicurioRegistrySpecConfigurationKafkaSecurityOauth) DeepCopy() *ApicurioRegistrySpecConfigurationKafkaSecurityOauth {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationKafkaSecurityOauth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationKafkaSecurityScram) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkaSecurityScram) {
 	*out = *in
@@ -426,6 +904,8 @@ func (in *ApicurioRegistrySpecConfigurationKafkaSecurityTls) DeepCopy() *Apicuri
 func (in *ApicurioRegistrySpecConfigurationKafkasql) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkasql) {
 	*out = *in
 	out.Security = in.Security
+	in.Strimzi.DeepCopyInto(&out.Strimzi)
+	in.Properties.DeepCopyInto(&out.Properties)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationKafkasql.
@@ -438,11 +918,99 @@ func (in *ApicurioRegistrySpecConfigurationKafkasql) DeepCopy() *ApicurioRegistr
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationKafkasqlProperties) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkasqlProperties) {
+	*out = *in
+	if in.Common != nil {
+		in, out := &in.Common, &out.Common
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Producer != nil {
+		in, out := &in.Producer, &out.Producer
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Consumer != nil {
+		in, out := &in.Consumer, &out.Consumer
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Admin != nil {
+		in, out := &in.Admin, &out.Admin
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationKafkasqlProperties.
+func (in *ApicurioRegistrySpecConfigurationKafkasqlProperties) DeepCopy() *ApicurioRegistrySpecConfigurationKafkasqlProperties {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationKafkasqlProperties)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationKafkasqlStrimzi) DeepCopyInto(out *ApicurioRegistrySpecConfigurationKafkasqlStrimzi) {
+	*out = *in
+	if in.KafkaRef != nil {
+		in, out := &in.KafkaRef, &out.KafkaRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.KafkaUserRef != nil {
+		in, out := &in.KafkaUserRef, &out.KafkaUserRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationKafkasqlStrimzi.
+func (in *ApicurioRegistrySpecConfigurationKafkasqlStrimzi) DeepCopy() *ApicurioRegistrySpecConfigurationKafkasqlStrimzi {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationKafkasqlStrimzi)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationPersistenceMigration) DeepCopyInto(out *ApicurioRegistrySpecConfigurationPersistenceMigration) {
+	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+	out.RegistryAuth = in.RegistryAuth
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationPersistenceMigration.
+func (in *ApicurioRegistrySpecConfigurationPersistenceMigration) DeepCopy() *ApicurioRegistrySpecConfigurationPersistenceMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationPersistenceMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationSecurity) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurity) {
 	*out = *in
 	out.Keycloak = in.Keycloak
+	out.Oidc = in.Oidc
+	out.Authorization = in.Authorization
 	out.Https = in.Https
+	out.TrustedCa = in.TrustedCa
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSecurity.
@@ -455,6 +1023,37 @@ func (in *ApicurioRegistrySpecConfigurationSecurity) DeepCopy() *ApicurioRegistr
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSecurityAuthorization) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurityAuthorization) {
+	*out = *in
+	out.RoleBased = in.RoleBased
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSecurityAuthorization.
+func (in *ApicurioRegistrySpecConfigurationSecurityAuthorization) DeepCopy() *ApicurioRegistrySpecConfigurationSecurityAuthorization {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSecurityAuthorization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased.
+func (in *ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased) DeepCopy() *ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationSecurityHttps) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurityHttps) {
 	*out = *in
@@ -485,10 +1084,53 @@ func (in *ApicurioRegistrySpecConfigurationSecurityKeycloak) DeepCopy() *Apicuri
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSecurityOidc) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurityOidc) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSecurityOidc.
+func (in *ApicurioRegistrySpecConfigurationSecurityOidc) DeepCopy() *ApicurioRegistrySpecConfigurationSecurityOidc {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSecurityOidc)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSecurityTrustedCa) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSecurityTrustedCa) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSecurityTrustedCa.
+func (in *ApicurioRegistrySpecConfigurationSecurityTrustedCa) DeepCopy() *ApicurioRegistrySpecConfigurationSecurityTrustedCa {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSecurityTrustedCa)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationSql) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSql) {
 	*out = *in
 	out.DataSource = in.DataSource
+	if in.PostgresClusterRef != nil {
+		in, out := &in.PostgresClusterRef, &out.PostgresClusterRef
+		*out = new(ApicurioRegistrySpecConfigurationSqlPostgresClusterRef)
+		**out = **in
+	}
+	out.Pool = in.Pool
+	if in.JdbcProperties != nil {
+		in, out := &in.JdbcProperties, &out.JdbcProperties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSql.
@@ -501,6 +1143,36 @@ func (in *ApicurioRegistrySpecConfigurationSql) DeepCopy() *ApicurioRegistrySpec
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSqlPool) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSqlPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSqlPool.
+func (in *ApicurioRegistrySpecConfigurationSqlPool) DeepCopy() *ApicurioRegistrySpecConfigurationSqlPool {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSqlPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistrySpecConfigurationSqlPostgresClusterRef) DeepCopyInto(out *ApicurioRegistrySpecConfigurationSqlPostgresClusterRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistrySpecConfigurationSqlPostgresClusterRef.
+func (in *ApicurioRegistrySpecConfigurationSqlPostgresClusterRef) DeepCopy() *ApicurioRegistrySpecConfigurationSqlPostgresClusterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistrySpecConfigurationSqlPostgresClusterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApicurioRegistrySpecConfigurationUI) DeepCopyInto(out *ApicurioRegistrySpecConfigurationUI) {
 	*out = *in
@@ -611,6 +1283,16 @@ func (in *ApicurioRegistryStatus) DeepCopyInto(out *ApicurioRegistryStatus) {
 		*out = make([]ApicurioRegistryStatusManagedResource, len(*in))
 		copy(*out, *in)
 	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(ApicurioRegistryStatusPersistence)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]ApicurioRegistryStatusPod, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryStatus.
@@ -652,3 +1334,61 @@ func (in *ApicurioRegistryStatusManagedResource) DeepCopy() *ApicurioRegistrySta
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryStatusPersistence) DeepCopyInto(out *ApicurioRegistryStatusPersistence) {
+	*out = *in
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(ApicurioRegistryStatusPersistenceMigration)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryStatusPersistence.
+func (in *ApicurioRegistryStatusPersistence) DeepCopy() *ApicurioRegistryStatusPersistence {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryStatusPersistence)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryStatusPersistenceMigration) DeepCopyInto(out *ApicurioRegistryStatusPersistenceMigration) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryStatusPersistenceMigration.
+func (in *ApicurioRegistryStatusPersistenceMigration) DeepCopy() *ApicurioRegistryStatusPersistenceMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryStatusPersistenceMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApicurioRegistryStatusPod) DeepCopyInto(out *ApicurioRegistryStatusPod) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApicurioRegistryStatusPod.
+func (in *ApicurioRegistryStatusPod) DeepCopy() *ApicurioRegistryStatusPod {
+	if in == nil {
+		return nil
+	}
+	out := new(ApicurioRegistryStatusPod)
+	in.DeepCopyInto(out)
+	return out
+}
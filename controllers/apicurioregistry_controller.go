@@ -21,11 +21,18 @@ import (
 	networking "k8s.io/api/networking/v1"
 	policy_v1 "k8s.io/api/policy/v1"
 	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"reflect"
 	cr "sigs.k8s.io/controller-runtime"
+	cr_builder "sigs.k8s.io/controller-runtime/pkg/builder"
+	cr_client "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 var _ reconcile.Reconciler = &ApicurioRegistryReconciler{}
@@ -36,13 +43,14 @@ type ApicurioRegistryReconciler struct {
 	testing  *c.TestSupport
 	loops    map[string]loop.ControlLoop
 	features *c.SupportedFeatures
+	recorder record.EventRecorder
 }
 
 func NewApicurioRegistryReconciler(mgr manager.Manager, rootLog *zap.Logger, testing *c.TestSupport) (*ApicurioRegistryReconciler, error) {
 
 	clients := client.NewClients(
 		rootLog.Named("clients"),
-		mgr.GetScheme(), mgr.GetConfig())
+		mgr.GetScheme(), mgr.GetConfig(), mgr.GetClient())
 
 	features := &c.SupportedFeatures{}
 
@@ -82,6 +90,36 @@ func NewApicurioRegistryReconciler(mgr manager.Manager, rootLog *zap.Logger, tes
 		rootLog.Sugar().Info("Install prometheus-operator in your cluster to create ServiceMonitor objects, restart apicurio-registry operator after installing prometheus-operator")
 	}
 	features.SupportsMonitoring = isMonitoring
+
+	isStrimzi, err := clients.Discovery().IsStrimziInstalled()
+	if err != nil {
+		rootLog.Sugar().Errorw("could not determine if Strimzi is installed", "error", err)
+		return nil, err
+	}
+	if isStrimzi {
+		rootLog.Info("Strimzi is installed, Kafka and KafkaUser resources can be referenced")
+	}
+	features.SupportsStrimzi = isStrimzi
+
+	isCloudNativePG, err := clients.Discovery().IsCloudNativePGInstalled()
+	if err != nil {
+		rootLog.Sugar().Errorw("could not determine if CloudNativePG is installed", "error", err)
+		return nil, err
+	}
+	if isCloudNativePG {
+		rootLog.Info("CloudNativePG is installed, Cluster resources can be referenced")
+	}
+	features.SupportsCloudNativePG = isCloudNativePG
+
+	isCrunchyPostgres, err := clients.Discovery().IsCrunchyPostgresInstalled()
+	if err != nil {
+		rootLog.Sugar().Errorw("could not determine if Crunchy Postgres for Kubernetes is installed", "error", err)
+		return nil, err
+	}
+	if isCrunchyPostgres {
+		rootLog.Info("Crunchy Postgres for Kubernetes is installed, PostgresCluster resources can be referenced")
+	}
+	features.SupportsCrunchyPostgres = isCrunchyPostgres
 	testing.SetSupportedFeatures(features)
 
 	result := &ApicurioRegistryReconciler{
@@ -90,6 +128,7 @@ func NewApicurioRegistryReconciler(mgr manager.Manager, rootLog *zap.Logger, tes
 		testing:  testing,
 		loops:    make(map[string]loop.ControlLoop),
 		features: features,
+		recorder: mgr.GetEventRecorderFor("apicurio-registry-operator"),
 	}
 
 	if err := result.setupWithManager(mgr); err != nil {
@@ -116,8 +155,27 @@ func (this *ApicurioRegistryReconciler) setupWithManager(mgr cr.Manager) error {
 	})
 
 	builder.Owns(&apps.Deployment{})
+	// The pods are owned by the ReplicaSets, so they are mapped to the ApicurioRegistry using the labels.
+	// This way the health is updated within seconds, instead of waiting for the periodic health checks.
+	builder.Watches(&source.Kind{Type: &core.Pod{}}, handler.EnqueueRequestsFromMapFunc(mapPodToApicurioRegistry),
+		cr_builder.WithPredicates(predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return isPodHealthChanged(e.ObjectOld.(*core.Pod), e.ObjectNew.(*core.Pod))
+			},
+		}))
+	// The ConfigMaps and Secrets referenced in the spec are not owned by the ApicurioRegistry, so they are mapped using the references.
+	// Only their metadata is cached, the content is read by the control functions.
+	builder.Watches(&source.Kind{Type: &core.ConfigMap{}},
+		handler.EnqueueRequestsFromMapFunc(this.mapReferencedObjectToApicurioRegistry(mgr.GetClient(), getReferencedConfigMaps)),
+		cr_builder.OnlyMetadata)
+	builder.Watches(&source.Kind{Type: &core.Secret{}},
+		handler.EnqueueRequestsFromMapFunc(this.mapReferencedObjectToApicurioRegistry(mgr.GetClient(), getReferencedSecrets)),
+		cr_builder.OnlyMetadata)
 	builder.Owns(&core.Service{})
 	builder.Owns(&networking.Ingress{})
+	// Persistence migration
+	builder.Owns(&ar.ApicurioRegistryBackup{})
+	builder.Owns(&ar.ApicurioRegistryRestore{})
 	if this.features.SupportsPDBv1beta1 {
 		builder.Owns(&policy_v1beta1.PodDisruptionBudget{})
 	}
@@ -132,6 +190,80 @@ func (this *ApicurioRegistryReconciler) setupWithManager(mgr cr.Manager) error {
 }
 
 // Apicurio Registry CR
+func mapPodToApicurioRegistry(object cr_client.Object) []reconcile.Request {
+	labels := object.GetLabels()
+	if labels["apicur.io/type"] != "apicurio-registry" || labels["apicur.io/name"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: object.GetNamespace(),
+		Name:      labels["apicur.io/name"],
+	}}}
+}
+
+// Map a ConfigMap or a Secret to the ApicurioRegistry resources in the same namespace that reference it
+func (this *ApicurioRegistryReconciler) mapReferencedObjectToApicurioRegistry(reader cr_client.Reader,
+	getReferences func(*ar.ApicurioRegistry) []string) handler.MapFunc {
+	return func(object cr_client.Object) []reconcile.Request {
+		registries := &ar.ApicurioRegistryList{}
+		if err := reader.List(go_ctx.TODO(), registries, cr_client.InNamespace(object.GetNamespace())); err != nil {
+			this.log.Sugar().Warnw("could not list ApicurioRegistry resources to map a referenced resource",
+				"namespace", object.GetNamespace(), "name", object.GetName(), "error", err)
+			return nil
+		}
+		res := make([]reconcile.Request, 0)
+		for i := range registries.Items {
+			if c.ContainsString(getReferences(&registries.Items[i]), object.GetName()) {
+				res = append(res, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: object.GetNamespace(),
+					Name:      registries.Items[i].Name,
+				}})
+			}
+		}
+		return res
+	}
+}
+
+// Return the names of the ConfigMaps, whose content is used by the control functions
+func getReferencedConfigMaps(registry *ar.ApicurioRegistry) []string {
+	res := make([]string, 0)
+	if ref := registry.Spec.Configuration.PropertiesConfigMapRef; ref != nil && ref.Name != "" {
+		res = append(res, ref.Name)
+	}
+	trustedCa := registry.Spec.Configuration.Security.TrustedCa
+	if trustedCa.ConfigMapName != "" {
+		res = append(res, trustedCa.ConfigMapName)
+	}
+	if trustedCa.InjectClusterBundle {
+		// OpenShift injects the bundle after the ConfigMap is created, and when the cluster proxy configuration changes
+		res = append(res, registry.Name+cf.OcpTrustedCaBundleSuffix)
+	}
+	return res
+}
+
+// Return the names of the Secrets, whose content is used by the control functions
+func getReferencedSecrets(registry *ar.ApicurioRegistry) []string {
+	res := make([]string, 0)
+	if name := registry.Spec.Configuration.Security.TrustedCa.SecretName; name != "" {
+		res = append(res, name)
+	}
+	return res
+}
+
+// Ignore the pod updates that do not affect the health, e.g. changes of the annotations
+func isPodHealthChanged(old *core.Pod, new *core.Pod) bool {
+	if old.Status.Phase != new.Status.Phase ||
+		old.Status.PodIP != new.Status.PodIP ||
+		(old.DeletionTimestamp == nil) != (new.DeletionTimestamp == nil) {
+		return true
+	}
+	if !reflect.DeepEqual(old.Status.Conditions, new.Status.Conditions) {
+		return true
+	}
+	return !reflect.DeepEqual(old.Status.InitContainerStatuses, new.Status.InitContainerStatuses) ||
+		!reflect.DeepEqual(old.Status.ContainerStatuses, new.Status.ContainerStatuses)
+}
+
 // +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistries,verbs=*
 // +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistries/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistries/finalizers,verbs=update
@@ -145,12 +277,20 @@ func (this *ApicurioRegistryReconciler) setupWithManager(mgr cr.Manager) error {
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=*
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
 // +kubebuilder:rbac:groups=apps,resources=deployments;daemonsets;replicasets;statefulsets,verbs=*
-// +kubebuilder:rbac:groups=core,resources=pods;services;endpoints;persistentvolumeclaims;configmaps;secrets;services/finalizers,verbs=*
+// +kubebuilder:rbac:groups=core,resources=pods;services;endpoints;persistentvolumeclaims;configmaps;secrets;services/finalizers;events,verbs=*
 // +kubebuilder:rbac:groups=events,resources=events,verbs=*
 
 // Monitoring
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=*
 
+// Strimzi
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkas;kafkausers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkatopics,verbs=*
+
+// PostgreSQL operators
+// +kubebuilder:rbac:groups=postgresql.cnpg.io,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=get;list;watch
+
 // Cluster Info (k8s vs. OCP)
 // +kubebuilder:rbac:groups=config.openshift.io,resources=clusterversions,verbs=get
 
@@ -211,7 +351,7 @@ func (this *ApicurioRegistryReconciler) createNewLoop(appName c.Name, appNamespa
 	log := this.log.Sugar().With("contextId", loopKey)
 	log.Info("creating a new context")
 
-	ctx := context.NewLoopContext(appName, appNamespace, log.Desugar(), this.clients, this.testing, features)
+	ctx := context.NewLoopContext(appName, appNamespace, log.Desugar(), this.clients, this.testing, features, this.recorder)
 	loopServices := services.NewLoopServices(ctx)
 	result := impl.NewControlLoopImpl(ctx, loopServices)
 
@@ -224,6 +364,9 @@ func (this *ApicurioRegistryReconciler) createNewLoop(appName c.Name, appNamespa
 	//deployment
 	result.AddControlFunction(cf.NewDeploymentCF(ctx, loopServices))
 
+	// pins the storage type during persistence migration, before the deployment modifiers read it
+	result.AddControlFunction(cf.NewPersistenceMigrationCF(ctx, loopServices))
+
 	//deployment modifiers
 	result.AddControlFunction(cf.NewUpgradeCF(ctx))
 	result.AddControlFunction(cf.NewPodTemplateSpecCF(ctx, loopServices))
@@ -233,23 +376,32 @@ func (this *ApicurioRegistryReconciler) createNewLoop(appName c.Name, appNamespa
 	result.AddControlFunction(cf.NewImageCF(ctx, loopServices))
 	result.AddControlFunction(cf.NewImagePullPolicyCF(ctx))
 	result.AddControlFunction(cf.NewImagePullSecretsCF(ctx))
+	result.AddControlFunction(kafkasql.NewKafkasqlTopicCF(ctx, loopServices))
 	result.AddControlFunction(cf.NewReplicasCF(ctx, loopServices))
 
 	//deployment env vars modifiers
-	result.AddControlFunction(cf.NewSqlCF(ctx))
-	result.AddControlFunction(kafkasql.NewKafkasqlCF(ctx))
-	result.AddControlFunction(kafkasql.NewKafkasqlSecurityScramCF(ctx))
-	result.AddControlFunction(kafkasql.NewKafkasqlSecurityTLSCF(ctx))
-	result.AddControlFunction(cf.NewLogLevelCF(ctx))
+	result.AddControlFunction(cf.NewSqlCF(ctx, loopServices))
+	result.AddControlFunction(kafkasql.NewKafkasqlStrimziCF(ctx, loopServices))
+	result.AddControlFunction(kafkasql.NewKafkasqlCF(ctx, loopServices))
+	result.AddControlFunction(kafkasql.NewKafkasqlSecurityScramCF(ctx, loopServices))
+	result.AddControlFunction(kafkasql.NewKafkasqlSecurityTLSCF(ctx, loopServices))
+	result.AddControlFunction(kafkasql.NewKafkasqlSecurityOAuthCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewStorageConnectivityCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewLogLevelCF(ctx, loopServices))
 	result.AddControlFunction(cf.NewProfileCF(ctx))
 	result.AddControlFunction(cf.NewUICF(ctx))
-	result.AddControlFunction(cf.NewKeycloakCF(ctx))
-	result.AddControlFunction(cf.NewCorsCF(ctx))
+	result.AddControlFunction(cf.NewKeycloakCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewOidcCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewAuthorizationCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewCorsCF(ctx, loopServices))
 
 	//env vars from CR
-	result.AddControlFunction(cf.NewEnvCF(ctx))
+	result.AddControlFunction(cf.NewEnvCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewEnvFromCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewPropertiesCF(ctx, loopServices))
+	result.AddControlFunction(cf.NewTrustedCaCF(ctx, loopServices))
 	//env vars applier
-	result.AddControlFunction(cf.NewEnvApplyCF(ctx))
+	result.AddControlFunction(cf.NewEnvApplyCF(ctx, loopServices))
 
 	//depends on deployment
 	if features.SupportsPDBv1beta1 {
@@ -286,7 +438,10 @@ func (this *ApicurioRegistryReconciler) createNewLoop(appName c.Name, appNamespa
 
 	// Other / Dependent on everything :)
 	result.AddControlFunction(cf.NewLabelsCF(ctx, loopServices))
+	result.AddControlFunction(condition.NewProgressingCF(ctx, loopServices))
+	result.AddControlFunction(condition.NewDegradedCF(ctx, loopServices))
 	result.AddControlFunction(condition.NewAppHealthCF(ctx, loopServices))
+	result.AddControlFunction(condition.NewSystemInfoCF(ctx, loopServices))
 
 	return result
 }
@@ -0,0 +1,570 @@
+package controllers
+
+import (
+	go_ctx "context"
+	"errors"
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cr "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ reconcile.Reconciler = &ApicurioRegistryBackupReconciler{}
+
+const (
+	BACKUP_CONDITION_TYPE_SUCCEEDED = "BackupSucceeded"
+
+	BACKUP_LABEL = "apicur.io/backup"
+
+	// Maximum number of entries in status.backups, when the retention is not configured
+	backupStatusHistoryLimit = 20
+
+	// The pre-signed upload URL must remain valid while the export is running, including Job retries
+	backupS3UploadUrlExpiration = 12 * time.Hour
+	BACKUP_S3_UPLOAD_URL_KEY    = "UPLOAD_URL"
+)
+
+// Writes the export to a temporary file first, so a failed export does not leave a partial backup behind.
+// The size of the backup file is reported using the termination message.
+const backupJobScript = "set -eu\n" + registryCurlScript + `mkdir -p "$BACKUP_DIR"
+curl $registry_curl_options -H "Accept: application/zip" \
+  -o "$BACKUP_DIR/$BACKUP_FILE.part" "$REGISTRY_URL/apis/registry/v2/admin/export"
+mv "$BACKUP_DIR/$BACKUP_FILE.part" "$BACKUP_DIR/$BACKUP_FILE"
+if [ "$MAX_BACKUPS" -gt 0 ]; then
+  ls -1 "$BACKUP_DIR" | grep "^$BACKUP_FILE_PREFIX.*\.zip$" | sort -r | tail -n +$((MAX_BACKUPS + 1)) | while read -r f; do
+    rm -f "$BACKUP_DIR/$f"
+  done
+fi
+wc -c < "$BACKUP_DIR/$BACKUP_FILE" | tr -d ' ' > /dev/termination-log
+`
+
+// Uploads the export using a pre-signed URL, so the Job does not need the S3 credentials.
+// The retention is applied by the operator when the Job has finished.
+const backupS3JobScript = "set -eu\n" + registryCurlScript + `insecure=""
+if [ "$S3_INSECURE_SKIP_TLS_VERIFY" = "true" ]; then
+  insecure="--insecure"
+fi
+curl $registry_curl_options -H "Accept: application/zip" \
+  -o "$BACKUP_DIR/$BACKUP_FILE" "$REGISTRY_URL/apis/registry/v2/admin/export"
+curl --fail --silent --show-error $insecure -H "Content-Type: application/zip" \
+  --upload-file "$BACKUP_DIR/$BACKUP_FILE" "$UPLOAD_URL"
+wc -c < "$BACKUP_DIR/$BACKUP_FILE" | tr -d ' ' > /dev/termination-log
+`
+
+type ApicurioRegistryBackupReconciler struct {
+	log     *zap.Logger
+	clients *client.Clients
+	now     func() time.Time
+}
+
+func NewApicurioRegistryBackupReconciler(mgr manager.Manager, rootLog *zap.Logger) (*ApicurioRegistryBackupReconciler, error) {
+
+	clients := client.NewClients(
+		rootLog.Named("clients"),
+		mgr.GetScheme(), mgr.GetConfig(), mgr.GetClient())
+
+	result := &ApicurioRegistryBackupReconciler{
+		log:     rootLog.Named("backup-controller"),
+		clients: clients,
+		now:     time.Now,
+	}
+
+	if err := result.setupWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (this *ApicurioRegistryBackupReconciler) setupWithManager(mgr cr.Manager) error {
+
+	builder := cr.NewControllerManagedBy(mgr)
+
+	builder.For(&ar.ApicurioRegistryBackup{})
+
+	builder.WithEventFilter(predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if _, ok := e.ObjectOld.(*ar.ApicurioRegistryBackup); ok {
+				// Ignore updates to the ApicurioRegistryBackup status, in which case metadata.Generation does not change.
+				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+			}
+			return true
+		},
+	})
+
+	builder.Owns(&batch.Job{})
+
+	return builder.Complete(this)
+}
+
+// Apicurio Registry Backup CR
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistrybackups,verbs=*
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistrybackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistrybackups/finalizers,verbs=update
+
+// Backup and restore Jobs, which own the Secrets with the pre-signed S3 URLs
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=*
+// +kubebuilder:rbac:groups=batch,resources=jobs/finalizers,verbs=update
+
+func (this *ApicurioRegistryBackupReconciler) Reconcile(_ go_ctx.Context, request reconcile.Request) (reconcile.Result, error) {
+
+	namespace := c.Namespace(request.Namespace)
+	log := this.log.Sugar().With("backup", request.Namespace+"/"+request.Name)
+
+	backup, err := this.clients.CRD().GetApicurioRegistryBackup(namespace, c.Name(request.Name))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if backup == nil {
+		// Deleted, owned Jobs are garbage collected
+		return reconcile.Result{}, nil
+	}
+	originalStatus := backup.Status.DeepCopy()
+	now := this.now()
+
+	// Validate the spec, and check that the ApicurioRegistry can be exported.
+	// The ApicurioRegistry is not watched, so it is checked again later.
+	schedule, err := validateBackupSpec(&backup.Spec)
+	recheckAfter := time.Duration(0)
+	if err == nil {
+		if registry, _ := this.clients.CRD().GetApicurioRegistry(namespace, c.Name(backup.Spec.RegistryName)); registry != nil {
+			err = validateRegistryAuthCredentials(registry, &backup.Spec.RegistryAuth)
+			recheckAfter = registryRecheckDelay
+		}
+	}
+	if err != nil {
+		log.Warnw("invalid ApicurioRegistryBackup spec", "error", err)
+		api_meta.SetStatusCondition(&backup.Status.Conditions, meta.Condition{
+			Type:               string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR),
+			Status:             meta.ConditionTrue,
+			Reason:             string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID),
+			Message:            err.Error(),
+			ObservedGeneration: backup.Generation,
+		})
+		backup.Status.NextScheduleTime = nil
+		return this.updateStatus(backup, originalStatus, recheckAfter)
+	}
+	api_meta.RemoveStatusCondition(&backup.Status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR))
+
+	// Check the backup in progress
+	if last := backup.Status.LastBackup; last != nil && last.Result == ar.BACKUP_RESULT_RUNNING {
+		if !this.checkBackupJob(backup, now) {
+			return this.updateStatus(backup, originalStatus, jobPollDelay)
+		}
+	}
+
+	// Start a new backup if it is due.
+	// The run is identified by its scheduled time, which does not change if the status update fails.
+	due := false
+	run := backup.CreationTimestamp.Time
+	if schedule == nil {
+		due = backup.Status.LastBackup == nil
+		backup.Status.NextScheduleTime = nil
+	} else {
+		if backup.Status.LastScheduleTime != nil {
+			run = backup.Status.LastScheduleTime.Time
+		}
+		run = schedule.Next(run)
+		// Missed runs are not made up for, at most one backup is started
+		due = !backup.Spec.Suspend && !now.Before(run)
+	}
+	if due {
+		log.Infow("starting a backup")
+		this.startBackup(log, backup, run, now)
+	}
+
+	requeueAfter := time.Duration(0)
+	if backup.Status.LastBackup != nil && backup.Status.LastBackup.Result == ar.BACKUP_RESULT_RUNNING {
+		requeueAfter = jobPollDelay
+	}
+	if schedule != nil {
+		if backup.Spec.Suspend {
+			backup.Status.NextScheduleTime = nil
+		} else {
+			next := schedule.Next(now)
+			backup.Status.NextScheduleTime = &meta.Time{Time: next}
+			untilNext := next.Sub(now) + time.Second
+			if requeueAfter == 0 || untilNext < requeueAfter {
+				requeueAfter = untilNext
+			}
+		}
+	}
+
+	return this.updateStatus(backup, originalStatus, requeueAfter)
+}
+
+func validateBackupSpec(spec *ar.ApicurioRegistryBackupSpec) (cron.Schedule, error) {
+	if spec.RegistryName == "" {
+		return nil, errors.New("spec.registryName is required")
+	}
+	if (spec.Storage.Pvc == nil) == (spec.Storage.S3 == nil) {
+		return nil, errors.New("exactly one of spec.storage.pvc or spec.storage.s3 must be configured")
+	}
+	if pvc := spec.Storage.Pvc; pvc != nil && pvc.ClaimName == "" {
+		return nil, errors.New("spec.storage.pvc.claimName is required")
+	}
+	if s3 := spec.Storage.S3; s3 != nil {
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.CredentialsSecretName == "" {
+			return nil, errors.New("spec.storage.s3.endpoint, spec.storage.s3.bucket and spec.storage.s3.credentialsSecretName are required")
+		}
+	}
+	if spec.Retention.MaxBackups < 0 {
+		return nil, errors.New("spec.retention.maxBackups must not be negative")
+	}
+	if spec.Schedule == "" {
+		return nil, nil
+	}
+	schedule, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return nil, errors.New("spec.schedule is not a valid cron expression: " + err.Error())
+	}
+	return schedule, nil
+}
+
+func (this *ApicurioRegistryBackupReconciler) updateStatus(backup *ar.ApicurioRegistryBackup, original *ar.ApicurioRegistryBackupStatus,
+	requeueAfter time.Duration) (reconcile.Result, error) {
+
+	if !reflect.DeepEqual(original, &backup.Status) {
+		if _, err := this.clients.CRD().UpdateApicurioRegistryBackupStatus(c.Namespace(backup.Namespace), backup); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (this *ApicurioRegistryBackupReconciler) startBackup(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup, run time.Time, now time.Time) {
+	metaNow := meta.NewTime(now)
+	backup.Status.LastScheduleTime = &metaNow
+	previous := backup.Status.LastBackup
+	entry := &ar.ApicurioRegistryBackupStatusEntry{
+		StartTime: &metaNow,
+		Result:    ar.BACKUP_RESULT_RUNNING,
+	}
+	backup.Status.LastBackup = entry
+
+	registry, err := this.clients.CRD().GetApicurioRegistry(c.Namespace(backup.Namespace), c.Name(backup.Spec.RegistryName))
+	if err == nil && registry == nil {
+		err = errors.New("ApicurioRegistry " + backup.Spec.RegistryName + " not found")
+	}
+	if err != nil {
+		this.finishBackup(log, backup, now, err)
+		return
+	}
+	registryEnv, err := getRegistryJobEnv(this.clients, registry, &backup.Spec.RegistryAuth)
+	if err != nil {
+		this.finishBackup(log, backup, now, err)
+		return
+	}
+	if !isRegistryReady(registry) {
+		log.Warnw("ApicurioRegistry is not ready, the backup might fail", "registry", registry.Name)
+	}
+
+	fileName := backupFilePrefix(backup) + run.UTC().Format("20060102-150405") + ".zip"
+	jobName := getJobName(backup.Name, run.UTC().Format("20060102150405"))
+
+	// Only the Job of the most recent backup is kept, for troubleshooting
+	if previous != nil && previous.JobName != "" && previous.JobName != jobName {
+		if job, err := this.clients.Kube().GetJob(c.Namespace(backup.Namespace), c.Name(previous.JobName)); err == nil {
+			if err := this.clients.Kube().DeleteJob(job); err != nil {
+				log.Warnw("could not delete previous backup Job", "job", previous.JobName, "error", err)
+			}
+		}
+	}
+	if backup.Spec.Storage.S3 != nil {
+		this.createS3BackupJob(log, backup, registryEnv, fileName, jobName, now)
+	} else {
+		this.createBackupJob(log, backup, registryEnv, fileName, jobName, now)
+	}
+}
+
+// finishBackup records the result of the backup in progress, successful if the error is nil
+func (this *ApicurioRegistryBackupReconciler) finishBackup(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup, now time.Time, err error) {
+	entry := backup.Status.LastBackup
+	metaNow := meta.NewTime(now)
+	entry.CompletionTime = &metaNow
+	if err != nil {
+		log.Errorw("backup has failed", "error", err)
+		entry.Result = ar.BACKUP_RESULT_FAILED
+		entry.Message = err.Error()
+		api_meta.SetStatusCondition(&backup.Status.Conditions, meta.Condition{
+			Type:               BACKUP_CONDITION_TYPE_SUCCEEDED,
+			Status:             meta.ConditionFalse,
+			Reason:             ar.BACKUP_RESULT_FAILED,
+			Message:            entry.Message,
+			ObservedGeneration: backup.Generation,
+		})
+	} else {
+		log.Infow("backup has succeeded", "location", entry.Location, "size", entry.Size)
+		entry.Result = ar.BACKUP_RESULT_SUCCEEDED
+		api_meta.SetStatusCondition(&backup.Status.Conditions, meta.Condition{
+			Type:               BACKUP_CONDITION_TYPE_SUCCEEDED,
+			Status:             meta.ConditionTrue,
+			Reason:             ar.BACKUP_RESULT_SUCCEEDED,
+			Message:            "Backup " + entry.Location + " has been created.",
+			ObservedGeneration: backup.Generation,
+		})
+	}
+}
+
+func backupFilePrefix(backup *ar.ApicurioRegistryBackup) string {
+	return backup.Spec.RegistryName + "-"
+}
+
+func backupHistoryLimit(backup *ar.ApicurioRegistryBackup) int {
+	if backup.Spec.Retention.MaxBackups > 0 && int(backup.Spec.Retention.MaxBackups) < backupStatusHistoryLimit {
+		return int(backup.Spec.Retention.MaxBackups)
+	}
+	return backupStatusHistoryLimit
+}
+
+// ===
+// S3
+
+func (this *ApicurioRegistryBackupReconciler) createS3BackupJob(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup, registryEnv []core.EnvVar,
+	fileName string, jobName string, now time.Time) {
+	storage := backup.Spec.Storage.S3
+	entry := backup.Status.LastBackup
+	entry.Location = storage.Prefix + fileName
+
+	s3, err := newS3Client(this.log, this.clients, backup.Namespace, storage.Endpoint, storage.Bucket, storage.Region,
+		storage.CredentialsSecretName, storage.InsecureSkipTlsVerify)
+	if err != nil {
+		this.finishBackup(log, backup, now, err)
+		return
+	}
+
+	labels := map[string]string{
+		BACKUP_LABEL: backup.Name,
+	}
+	job := newBackupJob(jobName, backup.Namespace, labels, backupS3JobScript, append(registryEnv, []core.EnvVar{
+		{Name: "BACKUP_DIR", Value: jobBackupMount},
+		{Name: "BACKUP_FILE", Value: fileName},
+		{Name: "S3_INSECURE_SKIP_TLS_VERIFY", Value: strconv.FormatBool(storage.InsecureSkipTlsVerify)},
+		{Name: "UPLOAD_URL", ValueFrom: &core.EnvVarSource{
+			SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{Name: jobName},
+				Key:                  BACKUP_S3_UPLOAD_URL_KEY,
+			},
+		}},
+	}...), core.VolumeSource{
+		EmptyDir: &core.EmptyDirVolumeSource{},
+	})
+	job, err = getOrCreateJob(this.clients, backup, job)
+	if err != nil {
+		this.finishBackup(log, backup, now, errors.New("could not create backup Job: "+err.Error()))
+		return
+	}
+
+	// The Secret is owned by the Job, so it is deleted together with it
+	namespace := c.Namespace(backup.Namespace)
+	_, err = this.clients.Kube().GetSecret(namespace, c.Name(jobName), &meta.GetOptions{})
+	if api_errors.IsNotFound(err) {
+		_, err = this.clients.Kube().CreateSecret(job, namespace, &core.Secret{
+			ObjectMeta: meta.ObjectMeta{
+				Name:      jobName,
+				Namespace: backup.Namespace,
+				Labels:    labels,
+			},
+			StringData: map[string]string{
+				BACKUP_S3_UPLOAD_URL_KEY: s3.PresignPutObject(entry.Location, backupS3UploadUrlExpiration),
+			},
+		})
+	}
+	if err != nil {
+		this.finishBackup(log, backup, now, errors.New("could not create backup Job Secret: "+err.Error()))
+		if err := this.clients.Kube().DeleteJob(job); err != nil {
+			log.Warnw("could not delete backup Job", "job", jobName, "error", err)
+		}
+		return
+	}
+	this.setBackupRunning(backup, jobName)
+}
+
+// applyS3Retention deletes old backup files, and lists the retained backups in the status
+func (this *ApicurioRegistryBackupReconciler) applyS3Retention(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup) {
+	storage := backup.Spec.Storage.S3
+	entry := backup.Status.LastBackup
+
+	s3, err := newS3Client(this.log, this.clients, backup.Namespace, storage.Endpoint, storage.Bucket, storage.Region,
+		storage.CredentialsSecretName, storage.InsecureSkipTlsVerify)
+	if err == nil {
+		var objects []client.S3Object
+		if objects, err = s3.ListObjects(storage.Prefix + backupFilePrefix(backup)); err == nil {
+			this.deleteOldS3Backups(log, backup, s3, objects)
+			return
+		}
+	}
+	log.Warnw("could not list backups for retention", "error", err)
+	entry.Message = "Could not list backups for retention: " + err.Error()
+	backup.Status.Backups = append([]ar.ApicurioRegistryBackupStatusEntry{*entry}, backup.Status.Backups...)
+	if limit := backupHistoryLimit(backup); len(backup.Status.Backups) > limit {
+		backup.Status.Backups = backup.Status.Backups[:limit]
+	}
+}
+
+func (this *ApicurioRegistryBackupReconciler) deleteOldS3Backups(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup,
+	s3 *client.S3Client, objects []client.S3Object) {
+	entry := backup.Status.LastBackup
+	backups := make([]client.S3Object, 0)
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, ".zip") {
+			backups = append(backups, o)
+		}
+	}
+	// Newest first, the key contains a sortable timestamp
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Key > backups[j].Key
+	})
+	if max := int(backup.Spec.Retention.MaxBackups); max > 0 && len(backups) > max {
+		for _, o := range backups[max:] {
+			log.Infow("deleting old backup", "location", o.Key)
+			if err := s3.DeleteObject(o.Key); err != nil {
+				log.Warnw("could not delete old backup", "location", o.Key, "error", err)
+				entry.Message = "Could not delete old backup " + o.Key + ": " + err.Error()
+			}
+		}
+		backups = backups[:max]
+	}
+	backup.Status.Backups = make([]ar.ApicurioRegistryBackupStatusEntry, 0)
+	for _, o := range backups {
+		if len(backup.Status.Backups) == backupHistoryLimit(backup) {
+			break
+		}
+		if o.Key == entry.Location {
+			backup.Status.Backups = append(backup.Status.Backups, *entry)
+		} else {
+			lastModified := meta.NewTime(o.LastModified)
+			backup.Status.Backups = append(backup.Status.Backups, ar.ApicurioRegistryBackupStatusEntry{
+				Location:       o.Key,
+				CompletionTime: &lastModified,
+				Size:           o.Size,
+				Result:         ar.BACKUP_RESULT_SUCCEEDED,
+			})
+		}
+	}
+}
+
+// ===
+// PersistentVolumeClaim
+
+func (this *ApicurioRegistryBackupReconciler) createBackupJob(log *zap.SugaredLogger, backup *ar.ApicurioRegistryBackup, registryEnv []core.EnvVar,
+	fileName string, jobName string, now time.Time) {
+	storage := backup.Spec.Storage.Pvc
+	entry := backup.Status.LastBackup
+	entry.Location = path.Join("/", storage.Path, fileName)
+
+	if _, err := this.clients.Kube().GetPersistentVolumeClaim(c.Namespace(backup.Namespace), c.Name(storage.ClaimName)); err != nil {
+		this.finishBackup(log, backup, now, errors.New("could not find PersistentVolumeClaim "+storage.ClaimName+": "+err.Error()))
+		return
+	}
+
+	labels := map[string]string{
+		BACKUP_LABEL: backup.Name,
+	}
+	job := newBackupJob(jobName, backup.Namespace, labels, backupJobScript, append(registryEnv, []core.EnvVar{
+		{Name: "BACKUP_DIR", Value: path.Join(jobBackupMount, storage.Path)},
+		{Name: "BACKUP_FILE", Value: fileName},
+		{Name: "BACKUP_FILE_PREFIX", Value: backupFilePrefix(backup)},
+		{Name: "MAX_BACKUPS", Value: strconv.Itoa(int(backup.Spec.Retention.MaxBackups))},
+	}...), core.VolumeSource{
+		PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{ClaimName: storage.ClaimName},
+	})
+
+	if _, err := getOrCreateJob(this.clients, backup, job); err != nil {
+		this.finishBackup(log, backup, now, errors.New("could not create backup Job: "+err.Error()))
+		return
+	}
+	this.setBackupRunning(backup, jobName)
+}
+
+func (this *ApicurioRegistryBackupReconciler) setBackupRunning(backup *ar.ApicurioRegistryBackup, jobName string) {
+	backup.Status.LastBackup.JobName = jobName
+	api_meta.SetStatusCondition(&backup.Status.Conditions, meta.Condition{
+		Type:               BACKUP_CONDITION_TYPE_SUCCEEDED,
+		Status:             meta.ConditionUnknown,
+		Reason:             ar.BACKUP_RESULT_RUNNING,
+		Message:            "Backup Job " + jobName + " is running.",
+		ObservedGeneration: backup.Generation,
+	})
+}
+
+// checkBackupJob updates the backup in progress, and returns true if the Job has finished
+func (this *ApicurioRegistryBackupReconciler) checkBackupJob(backup *ar.ApicurioRegistryBackup, now time.Time) bool {
+	log := this.log.Sugar().With("backup", backup.Namespace+"/"+backup.Name)
+	entry := backup.Status.LastBackup
+	if entry.JobName == "" {
+		this.finishBackup(log, backup, now, errors.New("backup was interrupted"))
+		return true
+	}
+	job, err := this.clients.Kube().GetJob(c.Namespace(backup.Namespace), c.Name(entry.JobName))
+	if err != nil {
+		if api_errors.IsNotFound(err) {
+			this.finishBackup(log, backup, now, errors.New("backup Job "+entry.JobName+" not found"))
+			return true
+		}
+		log.Warnw("could not get backup Job", "job", entry.JobName, "error", err)
+		return false
+	}
+	done, err := getJobResult(job)
+	if !done {
+		return false
+	}
+	if err != nil {
+		this.finishBackup(log, backup, now, err)
+		return true
+	}
+	entry.Size = this.getBackupJobSize(job)
+	this.finishBackup(log, backup, now, nil)
+	if backup.Spec.Storage.S3 != nil {
+		this.applyS3Retention(log, backup)
+		return true
+	}
+	backup.Status.Backups = append([]ar.ApicurioRegistryBackupStatusEntry{*entry}, backup.Status.Backups...)
+	if limit := backupHistoryLimit(backup); len(backup.Status.Backups) > limit {
+		backup.Status.Backups = backup.Status.Backups[:limit]
+	}
+	return true
+}
+
+// getBackupJobSize reads the size of the backup file from the termination message of the Job pod
+func (this *ApicurioRegistryBackupReconciler) getBackupJobSize(job *batch.Job) int64 {
+	pods, err := this.clients.Kube().GetPods(c.Namespace(job.Namespace), meta.ListOptions{
+		LabelSelector: "job-name=" + job.Name,
+	})
+	if err != nil {
+		this.log.Sugar().Warnw("could not list backup Job pods", "job", job.Name, "error", err)
+		return 0
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != core.PodSucceeded {
+			continue
+		}
+		for _, s := range pod.Status.ContainerStatuses {
+			if s.State.Terminated != nil {
+				if size, err := strconv.ParseInt(strings.TrimSpace(s.State.Terminated.Message), 10, 64); err == nil {
+					return size
+				}
+			}
+		}
+	}
+	return 0
+}
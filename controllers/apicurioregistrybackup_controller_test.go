@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	go_ctx "context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const testNamespace = "test"
+
+// setupRegistry stores an ApicurioRegistry together with its Service
+func setupRegistry(fakeServer *fakeApiServer, security ar.ApicurioRegistrySpecConfigurationSecurity) {
+	fakeServer.put("apicurioregistries", "example", &ar.ApicurioRegistry{
+		ObjectMeta: meta.ObjectMeta{Name: "example", Namespace: testNamespace, UID: "registry-uid"},
+		Spec: ar.ApicurioRegistrySpec{
+			Configuration: ar.ApicurioRegistrySpecConfiguration{Security: security},
+		},
+		Status: ar.ApicurioRegistryStatus{
+			ManagedResources: []ar.ApicurioRegistryStatusManagedResource{
+				{Kind: "Service", Name: "example-service", Namespace: testNamespace},
+			},
+			Conditions: []meta.Condition{
+				{Type: string(conditions.CONDITION_TYPE_READY), Status: meta.ConditionTrue},
+			},
+		},
+	})
+	fakeServer.put("services", "example-service", &core.Service{
+		ObjectMeta: meta.ObjectMeta{Name: "example-service", Namespace: testNamespace},
+		Spec: core.ServiceSpec{
+			Ports: []core.ServicePort{{Name: "http", Port: 8080}},
+		},
+	})
+}
+
+func setupBackupTest(t *testing.T, spec ar.ApicurioRegistryBackupSpec, created time.Time) (*fakeApiServer, *ApicurioRegistryBackupReconciler, *time.Time) {
+	fakeServer, clients := newFakeApiServer(t)
+	setupRegistry(fakeServer, ar.ApicurioRegistrySpecConfigurationSecurity{})
+	fakeServer.put("persistentvolumeclaims", "backups", &core.PersistentVolumeClaim{
+		ObjectMeta: meta.ObjectMeta{Name: "backups", Namespace: testNamespace},
+	})
+	fakeServer.put("apicurioregistrybackups", "example-backup", &ar.ApicurioRegistryBackup{
+		ObjectMeta: meta.ObjectMeta{
+			Name:              "example-backup",
+			Namespace:         testNamespace,
+			UID:               "backup-uid",
+			CreationTimestamp: meta.NewTime(created),
+		},
+		Spec: spec,
+	})
+	now := created
+	reconciler := &ApicurioRegistryBackupReconciler{
+		log:     zap.NewNop(),
+		clients: clients,
+		now: func() time.Time {
+			return now
+		},
+	}
+	return fakeServer, reconciler, &now
+}
+
+func reconcileBackup(t *testing.T, fakeServer *fakeApiServer, reconciler *ApicurioRegistryBackupReconciler) (reconcile.Result, *ar.ApicurioRegistryBackup) {
+	t.Helper()
+	result, err := reconciler.Reconcile(go_ctx.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "example-backup"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := &ar.ApicurioRegistryBackup{}
+	fakeServer.get("apicurioregistrybackups", "example-backup", backup)
+	return result, backup
+}
+
+// finishJob marks the Job as finished, and stores the Job pod with the termination message
+func finishJob(t *testing.T, fakeServer *fakeApiServer, name string, conditionType batch.JobConditionType, message string) {
+	t.Helper()
+	job := &batch.Job{}
+	if !fakeServer.get("jobs", name, job) {
+		t.Fatalf("Job %s has not been created", name)
+	}
+	job.Status.Conditions = append(job.Status.Conditions, batch.JobCondition{
+		Type:    conditionType,
+		Status:  core.ConditionTrue,
+		Message: message,
+	})
+	fakeServer.put("jobs", name, job)
+	phase := core.PodSucceeded
+	if conditionType == batch.JobFailed {
+		phase = core.PodFailed
+	}
+	fakeServer.put("pods", name+"-pod", &core.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: name + "-pod", Namespace: testNamespace, Labels: map[string]string{"job-name": name}},
+		Status: core.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []core.ContainerStatus{
+				{Name: "job", State: core.ContainerState{Terminated: &core.ContainerStateTerminated{Message: message}}},
+			},
+		},
+	})
+}
+
+func getEnv(job *batch.Job, name string) *core.EnvVar {
+	for i, e := range job.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == name {
+			return &job.Spec.Template.Spec.Containers[0].Env[i]
+		}
+	}
+	return nil
+}
+
+func TestBackupSchedule(t *testing.T) {
+	created := time.Date(2022, 1, 1, 0, 30, 0, 0, time.UTC)
+	fakeServer, reconciler, now := setupBackupTest(t, ar.ApicurioRegistryBackupSpec{
+		RegistryName: "example",
+		Schedule:     "CRON_TZ=UTC 0 2 * * *",
+		Storage: ar.ApicurioRegistryBackupStorage{
+			Pvc: &ar.ApicurioRegistryBackupStoragePvc{ClaimName: "backups", Path: "example"},
+		},
+	}, created)
+
+	// Not due yet
+	result, backup := reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+	c.AssertEquals(t, time.Date(2022, 1, 1, 2, 0, 0, 0, time.UTC), backup.Status.NextScheduleTime.Time.UTC())
+	c.AssertEquals(t, 90*time.Minute+time.Second, result.RequeueAfter)
+	c.AssertEquals(t, (*ar.ApicurioRegistryBackupStatusEntry)(nil), backup.Status.LastBackup)
+
+	// Due, the Job name is derived from the scheduled time
+	*now = time.Date(2022, 1, 1, 2, 0, 30, 0, time.UTC)
+	result, backup = reconcileBackup(t, fakeServer, reconciler)
+	jobName := "example-backup-20220101020000"
+	job := &batch.Job{}
+	if !fakeServer.get("jobs", jobName, job) {
+		t.Fatalf("Job %s has not been created", jobName)
+	}
+	c.AssertEquals(t, "http://example-service.test.svc:8080", getEnv(job, "REGISTRY_URL").Value)
+	c.AssertEquals(t, "/backup/example", getEnv(job, "BACKUP_DIR").Value)
+	c.AssertEquals(t, "example-20220101-020000.zip", getEnv(job, "BACKUP_FILE").Value)
+	c.AssertEquals(t, (*core.EnvVar)(nil), getEnv(job, "REGISTRY_TOKEN"))
+	c.AssertEquals(t, ar.BACKUP_RESULT_RUNNING, backup.Status.LastBackup.Result)
+	c.AssertEquals(t, jobName, backup.Status.LastBackup.JobName)
+	c.AssertEquals(t, "/example/example-20220101-020000.zip", backup.Status.LastBackup.Location)
+	c.AssertEquals(t, jobPollDelay, result.RequeueAfter)
+
+	// Running, the Job is not created again
+	*now = now.Add(time.Minute)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, 1, fakeServer.count("jobs"))
+	c.AssertEquals(t, ar.BACKUP_RESULT_RUNNING, backup.Status.LastBackup.Result)
+
+	// Succeeded, the size is read from the termination message
+	finishJob(t, fakeServer, jobName, batch.JobComplete, "1234\n")
+	*now = now.Add(time.Minute)
+	result, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.BACKUP_RESULT_SUCCEEDED, backup.Status.LastBackup.Result)
+	c.AssertEquals(t, int64(1234), backup.Status.LastBackup.Size)
+	c.AssertEquals(t, 1, len(backup.Status.Backups))
+	c.AssertEquals(t, true, api_meta.IsStatusConditionTrue(backup.Status.Conditions, BACKUP_CONDITION_TYPE_SUCCEEDED))
+	c.AssertEquals(t, time.Date(2022, 1, 2, 2, 0, 0, 0, time.UTC), backup.Status.NextScheduleTime.Time.UTC())
+	c.AssertEquals(t, 1, fakeServer.count("jobs"))
+
+	// Next run, only the Job of the most recent backup is kept
+	*now = time.Date(2022, 1, 2, 2, 0, 0, 0, time.UTC)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, "example-backup-20220102020000", backup.Status.LastBackup.JobName)
+	c.AssertEquals(t, 1, fakeServer.count("jobs"))
+	finishJob(t, fakeServer, "example-backup-20220102020000", batch.JobComplete, "5678")
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, 2, len(backup.Status.Backups))
+	c.AssertEquals(t, int64(5678), backup.Status.Backups[0].Size)
+
+	// Suspended, no backup is started
+	backup.Spec.Suspend = true
+	fakeServer.put("apicurioregistrybackups", "example-backup", backup)
+	*now = time.Date(2022, 1, 3, 2, 0, 0, 0, time.UTC)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, "example-backup-20220102020000", backup.Status.LastBackup.JobName)
+	c.AssertEquals(t, (*meta.Time)(nil), backup.Status.NextScheduleTime)
+}
+
+func TestBackupJobFailed(t *testing.T) {
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeServer, reconciler, now := setupBackupTest(t, ar.ApicurioRegistryBackupSpec{
+		RegistryName: "example",
+		Storage: ar.ApicurioRegistryBackupStorage{
+			Pvc: &ar.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+		},
+	}, created)
+
+	// A single backup is started immediately
+	_, backup := reconcileBackup(t, fakeServer, reconciler)
+	jobName := backup.Status.LastBackup.JobName
+	c.AssertEquals(t, "example-backup-20220101000000", jobName)
+	c.AssertEquals(t, (*meta.Time)(nil), backup.Status.NextScheduleTime)
+
+	finishJob(t, fakeServer, jobName, batch.JobFailed, "BackoffLimitExceeded")
+	*now = now.Add(time.Minute)
+	result, backup := reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.BACKUP_RESULT_FAILED, backup.Status.LastBackup.Result)
+	c.AssertEquals(t, true, strings.Contains(backup.Status.LastBackup.Message, "BackoffLimitExceeded"))
+	c.AssertEquals(t, 0, len(backup.Status.Backups))
+	condition := api_meta.FindStatusCondition(backup.Status.Conditions, BACKUP_CONDITION_TYPE_SUCCEEDED)
+	c.AssertEquals(t, meta.ConditionFalse, condition.Status)
+	c.AssertEquals(t, time.Duration(0), result.RequeueAfter)
+
+	// The failed backup is not retried
+	*now = now.Add(time.Hour)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, jobName, backup.Status.LastBackup.JobName)
+	c.AssertEquals(t, 1, fakeServer.count("jobs"))
+
+	// The Job is missing
+	backup.Status.LastBackup.Result = ar.BACKUP_RESULT_RUNNING
+	fakeServer.put("apicurioregistrybackups", "example-backup", backup)
+	fakeServer.delete("jobs", jobName)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.BACKUP_RESULT_FAILED, backup.Status.LastBackup.Result)
+	c.AssertEquals(t, "backup Job "+jobName+" not found", backup.Status.LastBackup.Message)
+}
+
+func TestBackupHistoryLimit(t *testing.T) {
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeServer, reconciler, _ := setupBackupTest(t, ar.ApicurioRegistryBackupSpec{
+		RegistryName: "example",
+		Storage: ar.ApicurioRegistryBackupStorage{
+			Pvc: &ar.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+		},
+		Retention: ar.ApicurioRegistryBackupRetention{MaxBackups: 2},
+	}, created)
+
+	backup := &ar.ApicurioRegistryBackup{}
+	fakeServer.get("apicurioregistrybackups", "example-backup", backup)
+	backup.Status.Backups = []ar.ApicurioRegistryBackupStatusEntry{
+		{Location: "/example-2.zip", Result: ar.BACKUP_RESULT_SUCCEEDED},
+		{Location: "/example-1.zip", Result: ar.BACKUP_RESULT_SUCCEEDED},
+	}
+	fakeServer.put("apicurioregistrybackups", "example-backup", backup)
+
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	job := &batch.Job{}
+	fakeServer.get("jobs", backup.Status.LastBackup.JobName, job)
+	// The Job deletes the old backup files
+	c.AssertEquals(t, "2", getEnv(job, "MAX_BACKUPS").Value)
+	c.AssertEquals(t, "example-", getEnv(job, "BACKUP_FILE_PREFIX").Value)
+
+	finishJob(t, fakeServer, job.Name, batch.JobComplete, "1")
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, 2, len(backup.Status.Backups))
+	c.AssertEquals(t, "/example-20220101-000000.zip", backup.Status.Backups[0].Location)
+	c.AssertEquals(t, "/example-2.zip", backup.Status.Backups[1].Location)
+}
+
+func TestBackupS3Retention(t *testing.T) {
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/bucket/"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	s3, err := client.NewS3Client(zap.NewNop(), server.URL, "bucket", "", "access", "secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &ApicurioRegistryBackupReconciler{log: zap.NewNop()}
+	backup := &ar.ApicurioRegistryBackup{
+		Spec: ar.ApicurioRegistryBackupSpec{
+			RegistryName: "example",
+			Retention:    ar.ApicurioRegistryBackupRetention{MaxBackups: 2},
+		},
+		Status: ar.ApicurioRegistryBackupStatus{
+			LastBackup: &ar.ApicurioRegistryBackupStatusEntry{
+				Location: "prefix/example-20220103-000000.zip",
+				Size:     3,
+				Result:   ar.BACKUP_RESULT_SUCCEEDED,
+				JobName:  "example-backup-20220103000000",
+			},
+		},
+	}
+	reconciler.deleteOldS3Backups(zap.NewNop().Sugar(), backup, s3, []client.S3Object{
+		{Key: "prefix/example-20220101-000000.zip", Size: 1},
+		{Key: "prefix/example-20220103-000000.zip", Size: 3},
+		{Key: "prefix/example-20220102-000000.zip", Size: 2},
+		{Key: "prefix/example-notes.txt", Size: 1},
+	})
+
+	sort.Strings(deleted)
+	c.AssertEquals(t, []string{"prefix/example-20220101-000000.zip"}, deleted)
+	c.AssertEquals(t, 2, len(backup.Status.Backups))
+	// The entry of the most recent backup is kept as is
+	c.AssertEquals(t, *backup.Status.LastBackup, backup.Status.Backups[0])
+	c.AssertEquals(t, "prefix/example-20220102-000000.zip", backup.Status.Backups[1].Location)
+	c.AssertEquals(t, int64(2), backup.Status.Backups[1].Size)
+}
+
+func TestBackupRegistryAuth(t *testing.T) {
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeServer, reconciler, _ := setupBackupTest(t, ar.ApicurioRegistryBackupSpec{
+		RegistryName: "example",
+		Storage: ar.ApicurioRegistryBackupStorage{
+			Pvc: &ar.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+		},
+	}, created)
+	setupRegistry(fakeServer, ar.ApicurioRegistrySpecConfigurationSecurity{
+		Keycloak: ar.ApicurioRegistrySpecConfigurationSecurityKeycloak{
+			Url:   "https://keycloak.example.com/auth/",
+			Realm: "registry",
+		},
+	})
+
+	// The credentials are required
+	result, backup := reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, true, api_meta.IsStatusConditionTrue(backup.Status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR)))
+	c.AssertEquals(t, registryRecheckDelay, result.RequeueAfter)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+
+	// The credentials Secret must contain the expected keys
+	fakeServer.put("secrets", "credentials", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "credentials", Namespace: testNamespace},
+		Data:       map[string][]byte{"clientId": []byte("backup")},
+	})
+	backup.Spec.RegistryAuth.SecretName = "credentials"
+	fakeServer.put("apicurioregistrybackups", "example-backup", backup)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	c.AssertEquals(t, false, api_meta.IsStatusConditionTrue(backup.Status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR)))
+	c.AssertEquals(t, ar.BACKUP_RESULT_FAILED, backup.Status.LastBackup.Result)
+	c.AssertEquals(t, true, strings.Contains(backup.Status.LastBackup.Message, "clientSecret"))
+
+	// The client credentials are passed to the Job, which gets the token from Keycloak
+	fakeServer.put("secrets", "credentials", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "credentials", Namespace: testNamespace},
+		Data:       map[string][]byte{"clientId": []byte("backup"), "clientSecret": []byte("secret")},
+	})
+	backup.Status.LastBackup = nil
+	fakeServer.put("apicurioregistrybackups", "example-backup", backup)
+	_, backup = reconcileBackup(t, fakeServer, reconciler)
+	job := &batch.Job{}
+	if !fakeServer.get("jobs", backup.Status.LastBackup.JobName, job) {
+		t.Fatalf("Job has not been created: %s", backup.Status.LastBackup.Message)
+	}
+	c.AssertEquals(t, "https://keycloak.example.com/auth/realms/registry/protocol/openid-connect/token",
+		getEnv(job, "REGISTRY_TOKEN_ENDPOINT").Value)
+	clientId := getEnv(job, "REGISTRY_CLIENT_ID")
+	c.AssertEquals(t, "", clientId.Value)
+	c.AssertEquals(t, "credentials", clientId.ValueFrom.SecretKeyRef.Name)
+	c.AssertEquals(t, "clientId", clientId.ValueFrom.SecretKeyRef.Key)
+	c.AssertEquals(t, "clientSecret", getEnv(job, "REGISTRY_CLIENT_SECRET").ValueFrom.SecretKeyRef.Key)
+	c.AssertEquals(t, (*core.EnvVar)(nil), getEnv(job, "REGISTRY_TOKEN"))
+}
+
+func TestRegistryJobEnvToken(t *testing.T) {
+	fakeServer, clients := newFakeApiServer(t)
+	setupRegistry(fakeServer, ar.ApicurioRegistrySpecConfigurationSecurity{})
+	fakeServer.put("secrets", "credentials", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "credentials", Namespace: testNamespace},
+		Data:       map[string][]byte{"token": []byte("token"), "clientId": []byte("ignored")},
+	})
+	registry := &ar.ApicurioRegistry{}
+	fakeServer.get("apicurioregistries", "example", registry)
+
+	// The token is preferred, the token endpoint is not needed
+	env, err := getRegistryJobEnv(clients, registry, &ar.ApicurioRegistryBackupRegistryAuth{SecretName: "credentials"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AssertEquals(t, 3, len(env))
+	c.AssertEquals(t, "REGISTRY_TOKEN", env[2].Name)
+	c.AssertEquals(t, "token", env[2].ValueFrom.SecretKeyRef.Key)
+
+	// The token endpoint can not be derived without Keycloak or OIDC
+	fakeServer.put("secrets", "credentials", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "credentials", Namespace: testNamespace},
+		Data:       map[string][]byte{"clientId": []byte("backup"), "clientSecret": []byte("secret")},
+	})
+	_, err = getRegistryJobEnv(clients, registry, &ar.ApicurioRegistryBackupRegistryAuth{SecretName: "credentials"})
+	c.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), "tokenEndpoint"))
+	env, err = getRegistryJobEnv(clients, registry, &ar.ApicurioRegistryBackupRegistryAuth{
+		SecretName:    "credentials",
+		TokenEndpoint: "https://idp.example.com/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AssertEquals(t, core.EnvVar{Name: "REGISTRY_TOKEN_ENDPOINT", Value: "https://idp.example.com/token"}, env[len(env)-1])
+
+	// The token endpoint is discovered by the Job
+	registry.Spec.Configuration.Security.Oidc.ServerUrl = "https://idp.example.com/realms/registry"
+	env, err = getRegistryJobEnv(clients, registry, &ar.ApicurioRegistryBackupRegistryAuth{SecretName: "credentials"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AssertEquals(t, core.EnvVar{Name: "REGISTRY_OIDC_SERVER_URL", Value: "https://idp.example.com/realms/registry"}, env[len(env)-1])
+}
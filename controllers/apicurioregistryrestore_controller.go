@@ -0,0 +1,511 @@
+package controllers
+
+import (
+	go_ctx "context"
+	"errors"
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"strconv"
+	"time"
+
+	cr "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ reconcile.Reconciler = &ApicurioRegistryRestoreReconciler{}
+
+const (
+	RESTORE_CONDITION_TYPE_SUCCEEDED = "RestoreSucceeded"
+
+	RESTORE_LABEL = "apicur.io/restore"
+
+	RESTORE_DEFAULT_KEY = "backup.zip"
+
+	// The pre-signed download URL must remain valid while the import is running, including Job retries
+	restoreS3DownloadUrlExpiration = 12 * time.Hour
+	RESTORE_S3_DOWNLOAD_URL_KEY    = "DOWNLOAD_URL"
+)
+
+// Downloads the backup file first, if it is not mounted.
+// Streams the backup file, so it does not have to fit into memory.
+// The download does not use the registry curl options, so the Authorization header is not sent to the download URL.
+const restoreJobScript = "set -eu\n" + registryCurlScript + `if [ -n "${DOWNLOAD_URL:-}" ]; then
+  insecure=""
+  if [ "${DOWNLOAD_INSECURE_SKIP_TLS_VERIFY:-false}" = "true" ]; then
+    insecure="--insecure"
+  fi
+  curl --fail --silent --show-error --location $insecure -o "$BACKUP_FILE" "$DOWNLOAD_URL"
+fi
+curl $registry_curl_options -X POST -H "Content-Type: application/zip" \
+  --upload-file "$BACKUP_FILE" "$REGISTRY_URL/apis/registry/v2/admin/import"
+`
+
+type ApicurioRegistryRestoreReconciler struct {
+	log     *zap.Logger
+	clients *client.Clients
+	now     func() time.Time
+}
+
+func NewApicurioRegistryRestoreReconciler(mgr manager.Manager, rootLog *zap.Logger) (*ApicurioRegistryRestoreReconciler, error) {
+
+	clients := client.NewClients(
+		rootLog.Named("clients"),
+		mgr.GetScheme(), mgr.GetConfig(), mgr.GetClient())
+
+	result := &ApicurioRegistryRestoreReconciler{
+		log:     rootLog.Named("restore-controller"),
+		clients: clients,
+		now:     time.Now,
+	}
+
+	if err := result.setupWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (this *ApicurioRegistryRestoreReconciler) setupWithManager(mgr cr.Manager) error {
+
+	builder := cr.NewControllerManagedBy(mgr)
+
+	builder.For(&ar.ApicurioRegistryRestore{})
+
+	builder.WithEventFilter(predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if _, ok := e.ObjectOld.(*ar.ApicurioRegistryRestore); ok {
+				// Ignore updates to the ApicurioRegistryRestore status, in which case metadata.Generation does not change.
+				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+			}
+			return true
+		},
+	})
+
+	builder.Owns(&batch.Job{})
+
+	return builder.Complete(this)
+}
+
+// Apicurio Registry Restore CR
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistryrestores,verbs=*
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistryrestores/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=registry.apicur.io,resources=apicurioregistryrestores/finalizers,verbs=update
+
+func (this *ApicurioRegistryRestoreReconciler) Reconcile(_ go_ctx.Context, request reconcile.Request) (reconcile.Result, error) {
+
+	namespace := c.Namespace(request.Namespace)
+	log := this.log.Sugar().With("restore", request.Namespace+"/"+request.Name)
+
+	restore, err := this.clients.CRD().GetApicurioRegistryRestore(namespace, c.Name(request.Name))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if restore == nil {
+		return reconcile.Result{}, nil
+	}
+	status := &restore.Status
+	if status.Phase == ar.RESTORE_PHASE_SUCCEEDED || status.Phase == ar.RESTORE_PHASE_FAILED {
+		// The restore is executed only once
+		return reconcile.Result{}, nil
+	}
+	originalStatus := status.DeepCopy()
+	now := this.now()
+	if status.Phase == "" {
+		status.Phase = ar.RESTORE_PHASE_PENDING
+		metaNow := meta.NewTime(now)
+		status.StartTime = &metaNow
+	}
+
+	// Validate the spec
+	if err := validateRestoreSpec(&restore.Spec); err != nil {
+		log.Warnw("invalid ApicurioRegistryRestore spec", "error", err)
+		api_meta.SetStatusCondition(&status.Conditions, meta.Condition{
+			Type:               string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR),
+			Status:             meta.ConditionTrue,
+			Reason:             string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID),
+			Message:            err.Error(),
+			ObservedGeneration: restore.Generation,
+		})
+		status.Message = err.Error()
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+	api_meta.RemoveStatusCondition(&status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR))
+
+	// Check the import in progress.
+	// The Job is not created again if it is missing, because the import might have already been executed.
+	if status.Phase == ar.RESTORE_PHASE_IMPORTING {
+		if status.JobName == "" {
+			this.finishRestore(log, restore, errors.New("restore was interrupted"))
+			return this.updateStatus(restore, originalStatus, 0)
+		}
+		job, err := this.clients.Kube().GetJob(namespace, c.Name(status.JobName))
+		if err != nil {
+			if api_errors.IsNotFound(err) {
+				this.finishRestore(log, restore, errors.New("restore Job "+status.JobName+" not found"))
+				return this.updateStatus(restore, originalStatus, 0)
+			}
+			return reconcile.Result{}, err
+		}
+		done, err := getJobResult(job)
+		if !done {
+			return this.updateStatus(restore, originalStatus, jobPollDelay)
+		}
+		this.finishRestore(log, restore, err)
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+
+	// Wait for the registry to be ready
+	registry, err := this.clients.CRD().GetApicurioRegistry(namespace, c.Name(restore.Spec.RegistryName))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if registry != nil {
+		// The ApicurioRegistry is not watched, so it is checked again later
+		if err := validateRegistryAuthCredentials(registry, &restore.Spec.RegistryAuth); err != nil {
+			log.Warnw("invalid ApicurioRegistryRestore spec", "error", err)
+			api_meta.SetStatusCondition(&status.Conditions, meta.Condition{
+				Type:               string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR),
+				Status:             meta.ConditionTrue,
+				Reason:             string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID),
+				Message:            err.Error(),
+				ObservedGeneration: restore.Generation,
+			})
+			status.Message = err.Error()
+			return this.updateStatus(restore, originalStatus, registryRecheckDelay)
+		}
+	}
+	if registry == nil || !isRegistryReady(registry) {
+		status.Phase = ar.RESTORE_PHASE_WAITING_FOR_REGISTRY
+		if registry == nil {
+			status.Message = "ApicurioRegistry " + restore.Spec.RegistryName + " not found."
+		} else {
+			status.Message = "Waiting for ApicurioRegistry " + registry.Name + " to become ready."
+		}
+		this.setProgressCondition(restore)
+		return this.updateStatus(restore, originalStatus, jobPollDelay)
+	}
+	if _, err := getRegistryServiceUrl(this.clients, registry); err != nil {
+		status.Phase = ar.RESTORE_PHASE_WAITING_FOR_REGISTRY
+		status.Message = err.Error()
+		this.setProgressCondition(restore)
+		return this.updateStatus(restore, originalStatus, jobPollDelay)
+	}
+
+	// Import using a Job, so the reconciliation is not blocked
+	registryEnv, err := getRegistryJobEnv(this.clients, registry, &restore.Spec.RegistryAuth)
+	if err != nil {
+		this.finishRestore(log, restore, err)
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+	source, err := this.resolveSource(restore)
+	if err != nil {
+		this.finishRestore(log, restore, err)
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+	// The restore is executed only once
+	jobName := getJobName(restore.Name, "import")
+	job, err := this.newRestoreJob(restore, source, jobName, registryEnv)
+	if err != nil {
+		this.finishRestore(log, restore, err)
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+	// The phase is persisted before the Job is created, so the import is not executed again after a restart
+	status.Phase = ar.RESTORE_PHASE_IMPORTING
+	status.Message = "Importing data using Job " + jobName + "."
+	status.JobName = jobName
+	this.setProgressCondition(restore)
+	if restore, err = this.clients.CRD().UpdateApicurioRegistryRestoreStatus(namespace, restore); err != nil {
+		return reconcile.Result{}, err
+	}
+	originalStatus = restore.Status.DeepCopy()
+	log.Infow("importing data", "registry", registry.Name, "job", jobName)
+	if err := this.createRestoreJob(restore, source, job); err != nil {
+		this.finishRestore(log, restore, err)
+		return this.updateStatus(restore, originalStatus, 0)
+	}
+	return reconcile.Result{RequeueAfter: jobPollDelay}, nil
+}
+
+func validateRestoreSpec(spec *ar.ApicurioRegistryRestoreSpec) error {
+	if spec.RegistryName == "" {
+		return errors.New("spec.registryName is required")
+	}
+	source := spec.Source
+	count := 0
+	for _, set := range []bool{source.Pvc != nil, source.Secret != nil, source.ConfigMap != nil, source.S3 != nil,
+		source.Url != "", source.Backup != nil} {
+		if set {
+			count++
+		}
+	}
+	if count != 1 {
+		return errors.New("exactly one of spec.source.pvc, spec.source.secret, spec.source.configMap, spec.source.s3, " +
+			"spec.source.url or spec.source.backup must be configured")
+	}
+	if source.Pvc != nil && (source.Pvc.ClaimName == "" || source.Pvc.Path == "") {
+		return errors.New("spec.source.pvc.claimName and spec.source.pvc.path are required")
+	}
+	if source.Secret != nil && source.Secret.Name == "" {
+		return errors.New("spec.source.secret.name is required")
+	}
+	if source.ConfigMap != nil && source.ConfigMap.Name == "" {
+		return errors.New("spec.source.configMap.name is required")
+	}
+	if s3 := source.S3; s3 != nil && (s3.Endpoint == "" || s3.Bucket == "" || s3.Key == "" || s3.CredentialsSecretName == "") {
+		return errors.New("spec.source.s3.endpoint, spec.source.s3.bucket, spec.source.s3.key and spec.source.s3.credentialsSecretName are required")
+	}
+	if source.Backup != nil && source.Backup.Name == "" {
+		return errors.New("spec.source.backup.name is required")
+	}
+	return nil
+}
+
+func (this *ApicurioRegistryRestoreReconciler) updateStatus(restore *ar.ApicurioRegistryRestore, original *ar.ApicurioRegistryRestoreStatus,
+	requeueAfter time.Duration) (reconcile.Result, error) {
+
+	if !reflect.DeepEqual(original, &restore.Status) {
+		if _, err := this.clients.CRD().UpdateApicurioRegistryRestoreStatus(c.Namespace(restore.Namespace), restore); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (this *ApicurioRegistryRestoreReconciler) setProgressCondition(restore *ar.ApicurioRegistryRestore) {
+	api_meta.SetStatusCondition(&restore.Status.Conditions, meta.Condition{
+		Type:               RESTORE_CONDITION_TYPE_SUCCEEDED,
+		Status:             meta.ConditionUnknown,
+		Reason:             restore.Status.Phase,
+		Message:            restore.Status.Message,
+		ObservedGeneration: restore.Generation,
+	})
+}
+
+// finishRestore records the result of the restore, successful if the error is nil
+func (this *ApicurioRegistryRestoreReconciler) finishRestore(log *zap.SugaredLogger, restore *ar.ApicurioRegistryRestore, err error) {
+	status := &restore.Status
+	metaNow := meta.NewTime(this.now())
+	status.CompletionTime = &metaNow
+	if err != nil {
+		log.Errorw("restore has failed", "error", err)
+		status.Phase = ar.RESTORE_PHASE_FAILED
+		status.Message = err.Error()
+		api_meta.SetStatusCondition(&status.Conditions, meta.Condition{
+			Type:               RESTORE_CONDITION_TYPE_SUCCEEDED,
+			Status:             meta.ConditionFalse,
+			Reason:             ar.RESTORE_PHASE_FAILED,
+			Message:            status.Message,
+			ObservedGeneration: restore.Generation,
+		})
+	} else {
+		log.Infow("restore has succeeded")
+		status.Phase = ar.RESTORE_PHASE_SUCCEEDED
+		status.Message = "Data has been imported into ApicurioRegistry " + restore.Spec.RegistryName + "."
+		api_meta.SetStatusCondition(&status.Conditions, meta.Condition{
+			Type:               RESTORE_CONDITION_TYPE_SUCCEEDED,
+			Status:             meta.ConditionTrue,
+			Reason:             ar.RESTORE_PHASE_SUCCEEDED,
+			Message:            status.Message,
+			ObservedGeneration: restore.Generation,
+		})
+	}
+}
+
+// resolveSource replaces a reference to an ApicurioRegistryBackup with the location of the backup file
+func (this *ApicurioRegistryRestoreReconciler) resolveSource(restore *ar.ApicurioRegistryRestore) (*ar.ApicurioRegistryRestoreSource, error) {
+	ref := restore.Spec.Source.Backup
+	if ref == nil {
+		return &restore.Spec.Source, nil
+	}
+	backup, err := this.clients.CRD().GetApicurioRegistryBackup(c.Namespace(restore.Namespace), c.Name(ref.Name))
+	if err == nil && backup == nil {
+		err = errors.New("ApicurioRegistryBackup " + ref.Name + " not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	location := ref.Location
+	if location == "" {
+		if last := backup.Status.LastBackup; last != nil && last.Result == ar.BACKUP_RESULT_SUCCEEDED {
+			location = last.Location
+		} else if len(backup.Status.Backups) > 0 {
+			location = backup.Status.Backups[0].Location
+		} else {
+			return nil, errors.New("ApicurioRegistryBackup " + ref.Name + " does not have any successful backup")
+		}
+	}
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		return &ar.ApicurioRegistryRestoreSource{
+			S3: &ar.ApicurioRegistryRestoreSourceS3{
+				Endpoint:              s3.Endpoint,
+				Bucket:                s3.Bucket,
+				Key:                   location,
+				Region:                s3.Region,
+				CredentialsSecretName: s3.CredentialsSecretName,
+				InsecureSkipTlsVerify: s3.InsecureSkipTlsVerify,
+			},
+		}, nil
+	}
+	if pvc := backup.Spec.Storage.Pvc; pvc != nil {
+		return &ar.ApicurioRegistryRestoreSource{
+			Pvc: &ar.ApicurioRegistryRestoreSourcePvc{
+				ClaimName: pvc.ClaimName,
+				Path:      location,
+			},
+		}, nil
+	}
+	return nil, errors.New("ApicurioRegistryBackup " + ref.Name + " does not have a storage configured")
+}
+
+func keyOrDefault(ref *ar.ApicurioRegistryRestoreSourceKeyRef) string {
+	if ref.Key == "" {
+		return RESTORE_DEFAULT_KEY
+	}
+	return ref.Key
+}
+
+// newRestoreJob creates the import Job, and checks that the backup file exists if it is stored in a Kubernetes resource
+func (this *ApicurioRegistryRestoreReconciler) newRestoreJob(restore *ar.ApicurioRegistryRestore, source *ar.ApicurioRegistryRestoreSource,
+	jobName string, registryEnv []core.EnvVar) (*batch.Job, error) {
+
+	namespace := c.Namespace(restore.Namespace)
+	labels := map[string]string{
+		RESTORE_LABEL: restore.Name,
+	}
+	downloadFile := path.Join(jobBackupMount, RESTORE_DEFAULT_KEY)
+	var env []core.EnvVar
+	var volume core.VolumeSource
+	switch {
+	case source.Pvc != nil:
+		if _, err := this.clients.Kube().GetPersistentVolumeClaim(namespace, c.Name(source.Pvc.ClaimName)); err != nil {
+			return nil, errors.New("could not find PersistentVolumeClaim " + source.Pvc.ClaimName + ": " + err.Error())
+		}
+		env = []core.EnvVar{
+			{Name: "BACKUP_FILE", Value: path.Join(jobBackupMount, source.Pvc.Path)},
+		}
+		volume = core.VolumeSource{
+			PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{ClaimName: source.Pvc.ClaimName, ReadOnly: true},
+		}
+	case source.Secret != nil:
+		secret, err := this.clients.Kube().GetSecret(namespace, c.Name(source.Secret.Name), &meta.GetOptions{})
+		if err != nil {
+			return nil, errors.New("could not read Secret " + source.Secret.Name + ": " + err.Error())
+		}
+		key := keyOrDefault(source.Secret)
+		if _, exists := secret.Data[key]; !exists {
+			return nil, errors.New("Secret " + source.Secret.Name + " does not contain key " + key)
+		}
+		env = []core.EnvVar{
+			{Name: "BACKUP_FILE", Value: downloadFile},
+		}
+		volume = core.VolumeSource{
+			Secret: &core.SecretVolumeSource{
+				SecretName: source.Secret.Name,
+				Items:      []core.KeyToPath{{Key: key, Path: RESTORE_DEFAULT_KEY}},
+			},
+		}
+	case source.ConfigMap != nil:
+		configMap, err := this.clients.Kube().GetConfigMap(namespace, c.Name(source.ConfigMap.Name))
+		if err != nil {
+			return nil, errors.New("could not read ConfigMap " + source.ConfigMap.Name + ": " + err.Error())
+		}
+		key := keyOrDefault(source.ConfigMap)
+		_, binaryExists := configMap.BinaryData[key]
+		_, exists := configMap.Data[key]
+		if !binaryExists && !exists {
+			return nil, errors.New("ConfigMap " + source.ConfigMap.Name + " does not contain key " + key)
+		}
+		env = []core.EnvVar{
+			{Name: "BACKUP_FILE", Value: downloadFile},
+		}
+		volume = core.VolumeSource{
+			ConfigMap: &core.ConfigMapVolumeSource{
+				LocalObjectReference: core.LocalObjectReference{Name: source.ConfigMap.Name},
+				Items:                []core.KeyToPath{{Key: key, Path: RESTORE_DEFAULT_KEY}},
+			},
+		}
+	case source.S3 != nil:
+		// Checks the credentials, the download URL is pre-signed when the Job is created
+		s3 := source.S3
+		if _, err := newS3Client(this.log, this.clients, restore.Namespace, s3.Endpoint, s3.Bucket, s3.Region,
+			s3.CredentialsSecretName, s3.InsecureSkipTlsVerify); err != nil {
+			return nil, err
+		}
+		env = []core.EnvVar{
+			{Name: "BACKUP_FILE", Value: downloadFile},
+			{Name: "DOWNLOAD_INSECURE_SKIP_TLS_VERIFY", Value: strconv.FormatBool(s3.InsecureSkipTlsVerify)},
+			{Name: "DOWNLOAD_URL", ValueFrom: &core.EnvVarSource{
+				SecretKeyRef: &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{Name: jobName},
+					Key:                  RESTORE_S3_DOWNLOAD_URL_KEY,
+				},
+			}},
+		}
+		volume = core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		}
+	case source.Url != "":
+		env = []core.EnvVar{
+			{Name: "BACKUP_FILE", Value: downloadFile},
+			{Name: "DOWNLOAD_URL", Value: source.Url},
+		}
+		volume = core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		}
+	default:
+		return nil, errors.New("no supported source is configured")
+	}
+	return newBackupJob(jobName, restore.Namespace, labels, restoreJobScript, append(registryEnv, env...), volume), nil
+}
+
+// createRestoreJob creates the import Job, together with the Secret that contains the pre-signed S3 download URL
+func (this *ApicurioRegistryRestoreReconciler) createRestoreJob(restore *ar.ApicurioRegistryRestore, source *ar.ApicurioRegistryRestoreSource, job *batch.Job) error {
+	job, err := getOrCreateJob(this.clients, restore, job)
+	if err != nil {
+		return errors.New("could not create restore Job: " + err.Error())
+	}
+	s3 := source.S3
+	if s3 == nil {
+		return nil
+	}
+	// The Secret is owned by the Job, so it is deleted together with it
+	namespace := c.Namespace(restore.Namespace)
+	_, err = this.clients.Kube().GetSecret(namespace, c.Name(job.Name), &meta.GetOptions{})
+	if api_errors.IsNotFound(err) {
+		var s3Client *client.S3Client
+		s3Client, err = newS3Client(this.log, this.clients, restore.Namespace, s3.Endpoint, s3.Bucket, s3.Region,
+			s3.CredentialsSecretName, s3.InsecureSkipTlsVerify)
+		if err == nil {
+			_, err = this.clients.Kube().CreateSecret(job, namespace, &core.Secret{
+				ObjectMeta: meta.ObjectMeta{
+					Name:      job.Name,
+					Namespace: restore.Namespace,
+					Labels:    job.Labels,
+				},
+				StringData: map[string]string{
+					RESTORE_S3_DOWNLOAD_URL_KEY: s3Client.PresignGetObject(s3.Key, restoreS3DownloadUrlExpiration),
+				},
+			})
+		}
+	}
+	if err != nil {
+		if err := this.clients.Kube().DeleteJob(job); err != nil {
+			this.log.Sugar().Warnw("could not delete restore Job", "job", job.Name, "error", err)
+		}
+		return errors.New("could not create restore Job Secret: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	go_ctx "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func setupRestoreTest(t *testing.T, spec ar.ApicurioRegistryRestoreSpec) (*fakeApiServer, *ApicurioRegistryRestoreReconciler) {
+	fakeServer, clients := newFakeApiServer(t)
+	setupRegistry(fakeServer, ar.ApicurioRegistrySpecConfigurationSecurity{})
+	fakeServer.put("apicurioregistryrestores", "example-restore", &ar.ApicurioRegistryRestore{
+		ObjectMeta: meta.ObjectMeta{Name: "example-restore", Namespace: testNamespace, UID: "restore-uid"},
+		Spec:       spec,
+	})
+	reconciler := &ApicurioRegistryRestoreReconciler{
+		log:     zap.NewNop(),
+		clients: clients,
+		now: func() time.Time {
+			return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		},
+	}
+	return fakeServer, reconciler
+}
+
+func reconcileRestore(t *testing.T, fakeServer *fakeApiServer, reconciler *ApicurioRegistryRestoreReconciler) (reconcile.Result, *ar.ApicurioRegistryRestore) {
+	t.Helper()
+	result, err := reconciler.Reconcile(go_ctx.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "example-restore"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restore := &ar.ApicurioRegistryRestore{}
+	fakeServer.get("apicurioregistryrestores", "example-restore", restore)
+	return result, restore
+}
+
+func TestRestoreSecretSource(t *testing.T) {
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source: ar.ApicurioRegistryRestoreSource{
+			Secret: &ar.ApicurioRegistryRestoreSourceKeyRef{Name: "backup", Key: "export.zip"},
+		},
+	})
+	fakeServer.put("secrets", "backup", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "backup", Namespace: testNamespace},
+		Data:       map[string][]byte{"export.zip": []byte("zip")},
+	})
+
+	// The phase is persisted before the Job is created
+	result, restore := reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, []string{
+		"PUT apicurioregistryrestores/example-restore",
+		"POST jobs/",
+	}, fakeServer.takeRequests())
+	c.AssertEquals(t, ar.RESTORE_PHASE_IMPORTING, restore.Status.Phase)
+	c.AssertEquals(t, "example-restore-import", restore.Status.JobName)
+	c.AssertEquals(t, jobPollDelay, result.RequeueAfter)
+	job := &batch.Job{}
+	if !fakeServer.get("jobs", "example-restore-import", job) {
+		t.Fatal("restore Job has not been created")
+	}
+	c.AssertEquals(t, "/backup/backup.zip", getEnv(job, "BACKUP_FILE").Value)
+	c.AssertEquals(t, (*core.EnvVar)(nil), getEnv(job, "DOWNLOAD_URL"))
+	secret := job.Spec.Template.Spec.Volumes[0].Secret
+	c.AssertEquals(t, "backup", secret.SecretName)
+	c.AssertEquals(t, []core.KeyToPath{{Key: "export.zip", Path: "backup.zip"}}, secret.Items)
+
+	// Running, e.g. after a restart, the Job is not created again
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, 0, len(fakeServer.takeRequests()))
+	c.AssertEquals(t, ar.RESTORE_PHASE_IMPORTING, restore.Status.Phase)
+
+	// Succeeded
+	finishJob(t, fakeServer, "example-restore-import", batch.JobComplete, "")
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.RESTORE_PHASE_SUCCEEDED, restore.Status.Phase)
+	c.AssertEquals(t, true, api_meta.IsStatusConditionTrue(restore.Status.Conditions, RESTORE_CONDITION_TYPE_SUCCEEDED))
+
+	// The restore is executed only once
+	fakeServer.delete("jobs", "example-restore-import")
+	fakeServer.takeRequests()
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, 0, len(fakeServer.takeRequests()))
+	c.AssertEquals(t, ar.RESTORE_PHASE_SUCCEEDED, restore.Status.Phase)
+}
+
+func TestRestoreSourceNotFound(t *testing.T) {
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source: ar.ApicurioRegistryRestoreSource{
+			ConfigMap: &ar.ApicurioRegistryRestoreSourceKeyRef{Name: "backup"},
+		},
+	})
+	fakeServer.put("configmaps", "backup", &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{Name: "backup", Namespace: testNamespace},
+		BinaryData: map[string][]byte{"other.zip": []byte("zip")},
+	})
+
+	_, restore := reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.RESTORE_PHASE_FAILED, restore.Status.Phase)
+	c.AssertEquals(t, "ConfigMap backup does not contain key backup.zip", restore.Status.Message)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+}
+
+func TestRestoreUrlSource(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source:       ar.ApicurioRegistryRestoreSource{Url: server.URL + "/backup.zip"},
+	})
+
+	// The file is downloaded by the Job, not by the operator
+	_, restore := reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.RESTORE_PHASE_IMPORTING, restore.Status.Phase)
+	c.AssertEquals(t, int32(0), atomic.LoadInt32(&requests))
+	job := &batch.Job{}
+	fakeServer.get("jobs", restore.Status.JobName, job)
+	c.AssertEquals(t, server.URL+"/backup.zip", getEnv(job, "DOWNLOAD_URL").Value)
+	c.AssertEquals(t, "/backup/backup.zip", getEnv(job, "BACKUP_FILE").Value)
+	c.AssertEquals(t, true, job.Spec.Template.Spec.Volumes[0].EmptyDir != nil)
+}
+
+func TestRestoreS3Source(t *testing.T) {
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source: ar.ApicurioRegistryRestoreSource{
+			Backup: &ar.ApicurioRegistryRestoreSourceBackup{Name: "example-backup"},
+		},
+	})
+	fakeServer.put("apicurioregistrybackups", "example-backup", &ar.ApicurioRegistryBackup{
+		ObjectMeta: meta.ObjectMeta{Name: "example-backup", Namespace: testNamespace},
+		Spec: ar.ApicurioRegistryBackupSpec{
+			RegistryName: "example",
+			Storage: ar.ApicurioRegistryBackupStorage{
+				S3: &ar.ApicurioRegistryBackupStorageS3{
+					Endpoint:              "http://minio.test.svc:9000",
+					Bucket:                "backups",
+					CredentialsSecretName: "minio",
+				},
+			},
+		},
+		Status: ar.ApicurioRegistryBackupStatus{
+			LastBackup: &ar.ApicurioRegistryBackupStatusEntry{Location: "example-20220101-000000.zip", Result: ar.BACKUP_RESULT_SUCCEEDED},
+		},
+	})
+
+	// The S3 credentials are required
+	_, restore := reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.RESTORE_PHASE_FAILED, restore.Status.Phase)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+
+	fakeServer.put("secrets", "minio", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "minio", Namespace: testNamespace},
+		Data: map[string][]byte{
+			BACKUP_S3_ACCESS_KEY_ID_KEY:     []byte("access"),
+			BACKUP_S3_SECRET_ACCESS_KEY_KEY: []byte("secret"),
+		},
+	})
+	restore.Status = ar.ApicurioRegistryRestoreStatus{}
+	fakeServer.put("apicurioregistryrestores", "example-restore", restore)
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	if restore.Status.Phase != ar.RESTORE_PHASE_IMPORTING {
+		t.Fatal(restore.Status.Message)
+	}
+	job := &batch.Job{}
+	fakeServer.get("jobs", restore.Status.JobName, job)
+	c.AssertEquals(t, restore.Status.JobName, getEnv(job, "DOWNLOAD_URL").ValueFrom.SecretKeyRef.Name)
+	c.AssertEquals(t, "false", getEnv(job, "DOWNLOAD_INSECURE_SKIP_TLS_VERIFY").Value)
+
+	// The Job gets a pre-signed URL, not the credentials
+	secret := &core.Secret{}
+	if !fakeServer.get("secrets", restore.Status.JobName, secret) {
+		t.Fatal("restore Job Secret has not been created")
+	}
+	c.AssertEquals(t, restore.Status.JobName, secret.OwnerReferences[0].Name)
+	url := secret.StringData[RESTORE_S3_DOWNLOAD_URL_KEY]
+	c.AssertEquals(t, true, strings.HasPrefix(url, "http://minio.test.svc:9000/backups/example-20220101-000000.zip?"))
+	c.AssertEquals(t, true, strings.Contains(url, "X-Amz-Signature="))
+}
+
+func TestRestoreInterrupted(t *testing.T) {
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source:       ar.ApicurioRegistryRestoreSource{Url: "http://example.com/backup.zip"},
+	})
+	restore := &ar.ApicurioRegistryRestore{}
+	fakeServer.get("apicurioregistryrestores", "example-restore", restore)
+	restore.Status.Phase = ar.RESTORE_PHASE_IMPORTING
+	restore.Status.JobName = "example-restore-import"
+	fakeServer.put("apicurioregistryrestores", "example-restore", restore)
+
+	// The import might have been executed, so the Job is not created again
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, ar.RESTORE_PHASE_FAILED, restore.Status.Phase)
+	c.AssertEquals(t, "restore Job example-restore-import not found", restore.Status.Message)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+}
+
+func TestRestoreRegistryAuth(t *testing.T) {
+	fakeServer, reconciler := setupRestoreTest(t, ar.ApicurioRegistryRestoreSpec{
+		RegistryName: "example",
+		Source:       ar.ApicurioRegistryRestoreSource{Url: "http://example.com/backup.zip"},
+	})
+	setupRegistry(fakeServer, ar.ApicurioRegistrySpecConfigurationSecurity{
+		Oidc: ar.ApicurioRegistrySpecConfigurationSecurityOidc{ServerUrl: "https://idp.example.com/realms/registry"},
+	})
+
+	// The credentials are required
+	result, restore := reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, true, api_meta.IsStatusConditionTrue(restore.Status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR)))
+	c.AssertEquals(t, registryRecheckDelay, result.RequeueAfter)
+	c.AssertEquals(t, 0, fakeServer.count("jobs"))
+
+	// The token is passed to the Job
+	fakeServer.put("secrets", "credentials", &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "credentials", Namespace: testNamespace},
+		Data:       map[string][]byte{"token": []byte("token")},
+	})
+	restore.Spec.RegistryAuth.SecretName = "credentials"
+	fakeServer.put("apicurioregistryrestores", "example-restore", restore)
+	_, restore = reconcileRestore(t, fakeServer, reconciler)
+	c.AssertEquals(t, false, api_meta.IsStatusConditionTrue(restore.Status.Conditions, string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR)))
+	c.AssertEquals(t, ar.RESTORE_PHASE_IMPORTING, restore.Status.Phase)
+	job := &batch.Job{}
+	fakeServer.get("jobs", restore.Status.JobName, job)
+	c.AssertEquals(t, "token", getEnv(job, "REGISTRY_TOKEN").ValueFrom.SecretKeyRef.Key)
+}
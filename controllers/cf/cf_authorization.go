@@ -0,0 +1,201 @@
+package cf
+
+import (
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+)
+
+var _ loop.ControlFunction = &AuthorizationCF{}
+
+const (
+	ENV_ROLE_BASED_AUTHZ_ENABLED                    = "ROLE_BASED_AUTHZ_ENABLED"
+	ENV_ROLE_BASED_AUTHZ_SOURCE                     = "ROLE_BASED_AUTHZ_SOURCE"
+	ENV_REGISTRY_AUTH_ROLES_ADMIN                   = "REGISTRY_AUTH_ROLES_ADMIN"
+	ENV_REGISTRY_AUTH_ROLES_DEVELOPER               = "REGISTRY_AUTH_ROLES_DEVELOPER"
+	ENV_REGISTRY_AUTH_ROLES_READONLY                = "REGISTRY_AUTH_ROLES_READONLY"
+	ENV_REGISTRY_AUTH_OBAC_ENABLED                  = "REGISTRY_AUTH_OBAC_ENABLED"
+	ENV_REGISTRY_AUTH_ANONYMOUS_READ_ACCESS_ENABLED = "REGISTRY_AUTH_ANONYMOUS_READ_ACCESS_ENABLED"
+	ENV_REGISTRY_AUTH_AUTHENTICATED_READS_ENABLED   = "REGISTRY_AUTH_AUTHENTICATED_READS_ENABLED"
+	ENV_CLIENT_CREDENTIALS_BASIC_AUTH_ENABLED       = "CLIENT_CREDENTIALS_BASIC_AUTH_ENABLED"
+
+	AUTHZ_ROLE_SOURCE_TOKEN       = "token"
+	AUTHZ_ROLE_SOURCE_APPLICATION = "application"
+
+	authorizationOptionPath = "spec.configuration.security.authorization"
+)
+
+// This CF translates the authorization options to the env. variables.
+// Authentication is configured by KeycloakCF or OidcCF, so it must be executed after them.
+type AuthorizationCF struct {
+	ctx              context.LoopContext
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+	targetEnv        map[string]string
+	invalidOptions   []string
+	invalidDetails   []string
+	existingEnv      map[string]string
+	staleEnv         []string
+}
+
+func NewAuthorizationCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	return &AuthorizationCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+		targetEnv:        make(map[string]string),
+		existingEnv:      make(map[string]string),
+	}
+}
+
+func (this *AuthorizationCF) Describe() string {
+	return "AuthorizationCF"
+}
+
+func (this *AuthorizationCF) Sense() {
+	this.targetEnv = make(map[string]string)
+	this.invalidOptions = nil
+	this.invalidDetails = nil
+	this.existingEnv = make(map[string]string)
+	this.staleEnv = nil
+
+	// Observation #1
+	// Read the config values
+	var config ar.ApicurioRegistrySpecConfigurationSecurityAuthorization
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		config = specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Security.Authorization
+	}
+	authEnabled := false
+	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_AUTH_ENABLED); exists {
+		authEnabled = entry.GetValue().Value == "true"
+	}
+
+	// Observation #2
+	// Validate the config values, invalid options are skipped
+	if config != (ar.ApicurioRegistrySpecConfigurationSecurityAuthorization{}) {
+		if !authEnabled {
+			this.addInvalid(authorizationOptionPath, "authentication is not enabled, configure keycloak or oidc")
+		} else {
+			this.addRoleBasedEnv(config.RoleBased)
+			if config.OwnerOnly {
+				this.targetEnv[ENV_REGISTRY_AUTH_OBAC_ENABLED] = "true"
+			}
+			if config.AnonymousReadAccess {
+				this.targetEnv[ENV_REGISTRY_AUTH_ANONYMOUS_READ_ACCESS_ENABLED] = "true"
+			}
+			if config.AuthenticatedReadAccess {
+				if !config.RoleBased.Enabled {
+					this.addInvalid(authorizationOptionPath+".authenticatedReadAccess", "requires role-based authorization")
+				} else {
+					this.targetEnv[ENV_REGISTRY_AUTH_AUTHENTICATED_READS_ENABLED] = "true"
+				}
+			}
+			if config.BasicClientCredentials {
+				this.targetEnv[ENV_CLIENT_CREDENTIALS_BASIC_AUTH_ENABLED] = "true"
+			}
+		}
+	}
+
+	// Observation #3
+	// Read the env values
+	for name := range this.targetEnv {
+		if entry, exists := this.svcEnvCache.Get(name); exists && entry.GetValue().ValueFrom == nil {
+			this.existingEnv[name] = entry.GetValue().Value
+		}
+	}
+
+	// Observation #4
+	// Are there env values set by this CF that are no longer needed?
+	for _, entry := range this.svcEnvCache.GetByOwner(this.Describe()) {
+		if _, exists := this.targetEnv[entry.GetName()]; !exists {
+			this.staleEnv = append(this.staleEnv, entry.GetName())
+		}
+	}
+}
+
+func (this *AuthorizationCF) addRoleBasedEnv(config ar.ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased) {
+	optionPath := authorizationOptionPath + ".roleBased"
+	rolesConfigured := config.AdminRole != "" || config.DeveloperRole != "" || config.ReadOnlyRole != ""
+	if !config.Enabled {
+		if config.RoleSource != "" || rolesConfigured {
+			this.addInvalid(optionPath+".enabled", "role-based authorization must be enabled to configure the role source and names")
+		}
+		return
+	}
+	roleSource := config.RoleSource
+	if roleSource == "" {
+		roleSource = AUTHZ_ROLE_SOURCE_TOKEN
+	}
+	if roleSource != AUTHZ_ROLE_SOURCE_TOKEN && roleSource != AUTHZ_ROLE_SOURCE_APPLICATION {
+		this.addInvalid(optionPath+".roleSource", "supported values are "+AUTHZ_ROLE_SOURCE_TOKEN+" and "+AUTHZ_ROLE_SOURCE_APPLICATION)
+		return
+	}
+	this.targetEnv[ENV_ROLE_BASED_AUTHZ_ENABLED] = "true"
+	this.targetEnv[ENV_ROLE_BASED_AUTHZ_SOURCE] = roleSource
+	if rolesConfigured && roleSource != AUTHZ_ROLE_SOURCE_TOKEN {
+		this.addInvalid(optionPath, "role names can be configured only for the "+AUTHZ_ROLE_SOURCE_TOKEN+" role source")
+		return
+	}
+	if config.AdminRole != "" {
+		this.targetEnv[ENV_REGISTRY_AUTH_ROLES_ADMIN] = config.AdminRole
+	}
+	if config.DeveloperRole != "" {
+		this.targetEnv[ENV_REGISTRY_AUTH_ROLES_DEVELOPER] = config.DeveloperRole
+	}
+	if config.ReadOnlyRole != "" {
+		this.targetEnv[ENV_REGISTRY_AUTH_ROLES_READONLY] = config.ReadOnlyRole
+	}
+}
+
+func (this *AuthorizationCF) addInvalid(optionPath string, details string) {
+	this.invalidOptions = append(this.invalidOptions, optionPath)
+	this.invalidDetails = append(this.invalidDetails, details)
+}
+
+func (this *AuthorizationCF) Compare() bool {
+	// Condition #1
+	// The required env vars are not present OR they differ
+	// Condition #2
+	// Stale env vars have to be removed
+	// Condition #3
+	// Report invalid values once per loop
+	return !isEnvEqual(this.targetEnv, this.existingEnv) ||
+		len(this.staleEnv) > 0 ||
+		(len(this.invalidOptions) > 0 && this.ctx.GetAttempts() == 0)
+}
+
+func (this *AuthorizationCF) Respond() {
+	// Response #1
+	// Just set the value(s)!
+	for name, value := range this.targetEnv {
+		if existing, exists := this.existingEnv[name]; !exists || existing != value {
+			if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(name, value).SetOwner(this.Describe()).Build()); err != nil {
+				this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+				this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), authorizationOptionPath)
+			}
+		}
+	}
+
+	// Response #2
+	// Remove the stale value(s)
+	for _, name := range this.staleEnv {
+		this.svcEnvCache.DeleteByName(name)
+	}
+
+	// Response #3
+	// Report the first invalid value
+	if len(this.invalidOptions) > 0 && this.ctx.GetAttempts() == 0 {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(this.invalidDetails[0], this.invalidOptions[0])
+	}
+}
+
+func (this *AuthorizationCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
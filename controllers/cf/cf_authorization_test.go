@@ -0,0 +1,79 @@
+package cf
+
+import (
+	"strings"
+	"testing"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
+	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+)
+
+func TestAuthorization(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewKeycloakCF(ctx, services))
+	loop.AddControlFunction(NewOidcCF(ctx, services))
+	loop.AddControlFunction(NewAuthorizationCF(ctx, services))
+
+	spec := &ar.ApicurioRegistry{
+		Spec: ar.ApicurioRegistrySpec{
+			Configuration: ar.ApicurioRegistrySpecConfiguration{
+				Security: ar.ApicurioRegistrySpecConfigurationSecurity{
+					Oidc: ar.ApicurioRegistrySpecConfigurationSecurityOidc{
+						ServerUrl: "https://login.example.com/realms/registry",
+					},
+					Authorization: ar.ApicurioRegistrySpecConfigurationSecurityAuthorization{
+						RoleBased: ar.ApicurioRegistrySpecConfigurationSecurityAuthorizationRoleBased{
+							Enabled:   true,
+							AdminRole: "registry-admin",
+						},
+						AnonymousReadAccess:     true,
+						AuthenticatedReadAccess: true,
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+
+	assertEnv := func(name string, expected string) {
+		entry, exists := ctx.GetEnvCache().Get(name)
+		c.AssertEquals(t, expected != "", exists)
+		if exists {
+			c.AssertEquals(t, expected, entry.GetValue().Value)
+		}
+	}
+	assertEnv(ENV_ROLE_BASED_AUTHZ_ENABLED, "true")
+	assertEnv(ENV_ROLE_BASED_AUTHZ_SOURCE, AUTHZ_ROLE_SOURCE_TOKEN)
+	assertEnv(ENV_REGISTRY_AUTH_ROLES_ADMIN, "registry-admin")
+	assertEnv(ENV_REGISTRY_AUTH_ROLES_DEVELOPER, "")
+	assertEnv(ENV_REGISTRY_AUTH_ANONYMOUS_READ_ACCESS_ENABLED, "true")
+	assertEnv(ENV_REGISTRY_AUTH_AUTHENTICATED_READS_ENABLED, "true")
+	assertEnv(ENV_REGISTRY_AUTH_OBAC_ENABLED, "")
+
+	// Role names are not supported with the application role source
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Security.Authorization.RoleBased.RoleSource = AUTHZ_ROLE_SOURCE_APPLICATION
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.security.authorization.roleBased"))
+	assertEnv(ENV_ROLE_BASED_AUTHZ_SOURCE, AUTHZ_ROLE_SOURCE_APPLICATION)
+	assertEnv(ENV_REGISTRY_AUTH_ROLES_ADMIN, "")
+
+	// Authorization requires authentication
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Security.Oidc = ar.ApicurioRegistrySpecConfigurationSecurityOidc{}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	condition = services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "authentication is not enabled"))
+	assertEnv(ENV_ROLE_BASED_AUTHZ_ENABLED, "")
+	assertEnv(ENV_REGISTRY_AUTH_ANONYMOUS_READ_ACCESS_ENABLED, "")
+}
@@ -4,6 +4,7 @@ import (
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
@@ -17,6 +18,7 @@ const ENV_CORS = "CORS_ALLOWED_ORIGINS"
 type CorsCF struct {
 	ctx              context.LoopContext
 	log              *zap.SugaredLogger
+	services         services.LoopServices
 	svcResourceCache resources.ResourceCache
 	targetCors       string
 	existingCors     string
@@ -24,9 +26,10 @@ type CorsCF struct {
 }
 
 // This CF makes sure the CORS_ALLOWED_ORIGINS env. variable is set properly
-func NewCorsCF(ctx context.LoopContext) loop.ControlFunction {
+func NewCorsCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	res := &CorsCF{
 		ctx:              ctx,
+		services:         services,
 		svcResourceCache: ctx.GetResourceCache(),
 	}
 	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
@@ -46,24 +49,9 @@ func (this *CorsCF) Sense() {
 		if host != "" {
 			this.targetCors = "http://" + host + "," + "https://" + host
 		}
-		keycloak := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Security.Keycloak.Url
-		if keycloak != "" {
-			if keycloakUrl, err := url.Parse(keycloak); err == nil {
-				if keycloakHost := keycloakUrl.Hostname(); keycloakHost != "" {
-					if this.targetCors != "" {
-						this.targetCors = this.targetCors + ","
-					}
-					this.targetCors = this.targetCors + keycloakUrl.Scheme + "://" + keycloakHost
-				} else {
-					this.log.With("keycloakUrl", keycloakUrl).
-						Infof("could not include Keycloak URL in %s, failed to get host. "+
-							"Make sure the URL is a valid URL with both a scheme and a host", ENV_CORS)
-				}
-			} else {
-				this.log.With("keycloakUrl", keycloakUrl, "error", err).
-					Infof("could not include Keycloak URL in %s, failed to parse URL", ENV_CORS)
-			}
-		}
+		security := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Security
+		this.addOrigin("Keycloak", security.Keycloak.Url)
+		this.addOrigin("OIDC server", security.Oidc.ServerUrl)
 
 		envList := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Env
 		for _, e := range envList {
@@ -79,6 +67,28 @@ func (this *CorsCF) Sense() {
 	}
 }
 
+// Add the origin of the URL, e.g. of the Keycloak server, to the target CORS value
+func (this *CorsCF) addOrigin(name string, rawUrl string) {
+	if rawUrl == "" {
+		return
+	}
+	if parsedUrl, err := url.Parse(rawUrl); err == nil {
+		if host := parsedUrl.Hostname(); host != "" {
+			if this.targetCors != "" {
+				this.targetCors = this.targetCors + ","
+			}
+			this.targetCors = this.targetCors + parsedUrl.Scheme + "://" + host
+		} else {
+			this.log.With("url", parsedUrl).
+				Infof("could not include %s URL in %s, failed to get host. "+
+					"Make sure the URL is a valid URL with both a scheme and a host", name, ENV_CORS)
+		}
+	} else {
+		this.log.With("url", rawUrl, "error", err).
+			Infof("could not include %s URL in %s, failed to parse URL", name, ENV_CORS)
+	}
+}
+
 func (this *CorsCF) Compare() bool {
 
 	if this.overriddenCors != "" {
@@ -91,16 +101,23 @@ func (this *CorsCF) Compare() bool {
 func (this *CorsCF) Respond() {
 
 	if this.overriddenCors != "" {
-		this.ctx.GetEnvCache().Set(env.NewSimpleEnvCacheEntryBuilder(ENV_CORS, this.overriddenCors).Build())
+		this.setEnv(this.overriddenCors, "spec.configuration.env")
 	} else {
 		if this.targetCors != "" {
-			this.ctx.GetEnvCache().Set(env.NewSimpleEnvCacheEntryBuilder(ENV_CORS, this.targetCors).Build())
+			this.setEnv(this.targetCors, "spec.deployment.host")
 		} else {
 			this.ctx.GetEnvCache().DeleteByName(ENV_CORS)
 		}
 	}
 }
 
+func (this *CorsCF) setEnv(value string, optionPath string) {
+	if err := this.ctx.GetEnvCache().Set(env.NewSimpleEnvCacheEntryBuilder(ENV_CORS, value).SetOwner(this.Describe()).Build()); err != nil {
+		this.log.Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), optionPath)
+	}
+}
+
 func (this *CorsCF) Cleanup() bool {
 	// No cleanup
 	return true
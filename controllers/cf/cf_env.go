@@ -1,14 +1,19 @@
 package cf
 
 import (
+	"reflect"
+	"strconv"
+	"strings"
+
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
-	"reflect"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var _ loop.ControlFunction = &EnvCF{}
@@ -18,19 +23,24 @@ type EnvCF struct {
 	log              *zap.SugaredLogger
 	svcResourceCache resources.ResourceCache
 	svcEnvCache      env.EnvCache
+	services         services.LoopServices
 	// To know which were deleted, we need to compare with previous ones
 	previousTargetEnv []corev1.EnvVar
 	targetEnv         []corev1.EnvVar
 	remove            map[string]corev1.EnvVar
 	update            bool
+	// Entries that are not added to the cache, reported as a configuration error
+	invalidPath    string
+	invalidDetails string
 }
 
 // NewEnvCF creates a new instance of `Env` control function.
 // This control function is responsible for reading custom environment variables from the spec,
 // and saving them into the environment cache.
-func NewEnvCF(ctx context.LoopContext) loop.ControlFunction {
+func NewEnvCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	res := &EnvCF{
 		ctx:               ctx,
+		services:          services,
 		svcResourceCache:  ctx.GetResourceCache(),
 		svcEnvCache:       ctx.GetEnvCache(),
 		previousTargetEnv: make([]corev1.EnvVar, 0),
@@ -47,9 +57,12 @@ func (this *EnvCF) Describe() string {
 }
 
 func (this *EnvCF) Sense() {
-	this.log.Debugw("env cache before", "value", this.svcEnvCache.GetSorted())
+	sorted, _ := this.svcEnvCache.GetSorted()
+	this.log.Debugw("env cache before", "value", sorted)
 	this.update = false
 	this.remove = make(map[string]corev1.EnvVar)
+	this.invalidPath = ""
+	this.invalidDetails = ""
 
 	this.targetEnv = make([]corev1.EnvVar, 0)
 
@@ -57,7 +70,23 @@ func (this *EnvCF) Sense() {
 	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
 		envConfig := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Env
 
-		for _, v := range envConfig {
+		names := make(map[string]bool, len(envConfig))
+		for i, v := range envConfig {
+			// Skip invalid entries, and report the first one
+			details := ""
+			if errs := validation.IsEnvVarName(v.Name); len(errs) > 0 {
+				details = "'" + v.Name + "' is not a valid environment variable name: " + strings.Join(errs, ", ")
+			} else if names[v.Name] {
+				details = "environment variable '" + v.Name + "' is defined more than once"
+			}
+			if details != "" {
+				if this.invalidPath == "" {
+					this.invalidPath = "spec.configuration.env[" + strconv.Itoa(i) + "].name"
+					this.invalidDetails = details
+				}
+				continue
+			}
+			names[v.Name] = true
 			this.targetEnv = append(this.targetEnv, v)
 			if _, e := this.svcEnvCache.Get(v.Name); !e {
 				// Add to cache
@@ -66,7 +95,7 @@ func (this *EnvCF) Sense() {
 		}
 
 		this.remove = make(map[string]corev1.EnvVar, 0)
-		for _, v := range this.svcEnvCache.GetSorted() { // TODO Iterate directly?
+		for _, v := range sorted { // TODO Iterate directly?
 			cached, _ := this.svcEnvCache.Get(v.Name)
 			// Iterate over cached
 			if !found(envConfig, v.Name) && cached.GetPriority() == env.PRIORITY_SPEC && !cached.IsLocked() {
@@ -92,7 +121,8 @@ func (this *EnvCF) Sense() {
 
 func (this *EnvCF) Compare() bool {
 	this.log.Debugw("conditions", "this.update", this.update, "len(this.remove)", len(this.remove))
-	return this.update || len(this.remove) > 0
+	// Report the invalid entries once per loop
+	return this.update || len(this.remove) > 0 || (this.ctx.GetAttempts() == 0 && this.invalidPath != "")
 }
 
 func (this *EnvCF) Respond() {
@@ -113,12 +143,24 @@ func (this *EnvCF) Respond() {
 			// Maintain ordering
 			entryBuilder.SetDependency(prev)
 		}
-		this.svcEnvCache.Set(entryBuilder.SetPriority(env.PRIORITY_SPEC).Build())
+		if err := this.svcEnvCache.Set(entryBuilder.SetPriority(env.PRIORITY_SPEC).Build()); err != nil {
+			// Already validated
+			this.log.Errorw("could not add environment variable", "error", err)
+			continue
+		}
 		prev = v.Name
 	}
 
 	this.previousTargetEnv = this.targetEnv
-	this.log.Debugw("env cache after", "value", this.svcEnvCache.GetSorted())
+
+	// Response #3
+	// Report the invalid entries
+	if this.invalidPath != "" {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(this.invalidDetails, this.invalidPath)
+	}
+
+	sorted, _ := this.svcEnvCache.GetSorted()
+	this.log.Debugw("env cache after", "value", sorted)
 }
 
 func (this *EnvCF) Cleanup() bool {
@@ -1,23 +1,35 @@
 package cf
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 var _ loop.ControlFunction = &EnvApplyCF{}
 
+// Deployment annotation that keeps track of the CFs that manage the env. variables,
+// so they can be removed by the owner even after the operator is restarted.
+const ENV_OWNERS_ANNOTATION = "apicur.io/env-owners"
+
 type EnvApplyCF struct {
 	ctx                context.LoopContext
 	log                *zap.SugaredLogger
 	svcResourceCache   resources.ResourceCache
 	svcEnvCache        env.EnvCache
+	services           services.LoopServices
 	deploymentExists   bool
 	deploymentEntry    resources.ResourceCacheEntry
 	deploymentName     string
@@ -25,12 +37,15 @@ type EnvApplyCF struct {
 	lastDeploymentName string
 	deploymentUID      types.UID
 	lastDeploymentUID  types.UID
+	sortedEnv          []core.EnvVar
+	sortErr            error
 }
 
 // Is responsible for managing environment variables from the env cache
-func NewEnvApplyCF(ctx context.LoopContext) loop.ControlFunction {
+func NewEnvApplyCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	res := &EnvApplyCF{
 		ctx:                ctx,
+		services:           services,
 		svcResourceCache:   ctx.GetResourceCache(),
 		svcEnvCache:        ctx.GetEnvCache(),
 		deploymentExists:   false,
@@ -60,13 +75,20 @@ func (this *EnvApplyCF) Sense() {
 		// keeping the original ordering.
 		// The operator overwrites user defined ones only when necessary.
 		deployment := this.deploymentEntry.GetValue().(*apps.Deployment)
+		owners := make(map[string]string, 0)
+		if val, exists := deployment.Annotations[ENV_OWNERS_ANNOTATION]; exists {
+			if err := json.Unmarshal([]byte(val), &owners); err != nil {
+				this.log.Warnw("could not parse the env. variable owners annotation", "error", err)
+			}
+		}
 
 		for i, c := range deployment.Spec.Template.Spec.Containers {
 			if c.Name == factory.REGISTRY_CONTAINER_NAME {
 				prevName := "" // To maintain ordering in case of interpolation
 				// Copy variables in the cache
 				deleted := make(map[string]bool, 0)
-				for _, v := range this.svcEnvCache.GetSorted() {
+				sorted, _ := this.svcEnvCache.GetSorted()
+				for _, v := range sorted {
 					deleted[v.Name] = true // deletes spec stuff as well
 				}
 				for _, e := range deployment.Spec.Template.Spec.Containers[i].Env {
@@ -79,11 +101,14 @@ func (this *EnvApplyCF) Sense() {
 					}
 
 					// Add to the cache
-					entryBuilder := env.NewEnvCacheEntryBuilder(&e).SetPriority(env.PRIORITY_DEPLOYMENT)
+					entryBuilder := env.NewEnvCacheEntryBuilder(&e).SetPriority(env.PRIORITY_DEPLOYMENT).
+						SetOwner(owners[e.Name])
 					if prevName != "" {
 						entryBuilder.SetDependency(prevName)
 					}
-					this.svcEnvCache.Set(entryBuilder.Build())
+					if err := this.svcEnvCache.Set(entryBuilder.Build()); err != nil {
+						this.log.Errorw("could not add environment variable from the deployment", "error", err)
+					}
 					prevName = e.Name
 				}
 				// Remove things from the cache that are not in the spec
@@ -101,6 +126,10 @@ func (this *EnvApplyCF) Sense() {
 	// Observation #2
 	// Was the env cache updated?
 	this.envCacheUpdated = this.svcEnvCache.IsChanged()
+
+	// Observation #3
+	// Can the env vars be sorted?
+	this.sortedEnv, this.sortErr = this.svcEnvCache.GetSorted()
 }
 
 func (this *EnvApplyCF) Compare() bool {
@@ -108,23 +137,54 @@ func (this *EnvApplyCF) Compare() bool {
 	// We have something to update
 	// Condition #2
 	// There is a deployment
-	return (this.envCacheUpdated || this.deploymentName != this.lastDeploymentName) && this.deploymentExists
+	// Condition #3
+	// The env vars can be sorted, otherwise report the error once per loop
+	return ((this.envCacheUpdated || this.deploymentName != this.lastDeploymentName) && this.deploymentExists && this.sortErr == nil) ||
+		(this.sortErr != nil && this.ctx.GetAttempts() == 0)
 }
 
 func (this *EnvApplyCF) Respond() {
 	// Response #1
-	// Write the sorted env vars
+	// Report the error, the deployment is not updated until it is fixed
+	if this.sortErr != nil {
+		this.log.Errorw("could not sort environment variables", "error", this.sortErr)
+		optionPath := "spec.configuration.env"
+		if cycleErr, ok := this.sortErr.(*env.DependencyCycleError); ok {
+			optionPath = this.getSpecEnvPaths(cycleErr.Names)
+		}
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(this.sortErr.Error(), optionPath)
+		this.services.GetConditionManager().GetReadyCondition().TransitionError()
+		return
+	}
+
+	// Response #2
+	// Write the sorted env vars, and their owners
+	owners := make(map[string]string, 0)
+	for _, e := range this.sortedEnv {
+		if entry, exists := this.svcEnvCache.Get(e.Name); exists && entry.GetOwner() != "" {
+			owners[e.Name] = entry.GetOwner()
+		}
+	}
 	this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
 		deployment := value.(*apps.Deployment).DeepCopy()
 		for i, c := range deployment.Spec.Template.Spec.Containers {
 			if c.Name == factory.REGISTRY_CONTAINER_NAME {
-				deployment.Spec.Template.Spec.Containers[i].Env = this.svcEnvCache.GetSorted()
+				deployment.Spec.Template.Spec.Containers[i].Env = this.sortedEnv
 			}
 		} // TODO report a problem if not found?
+		if len(owners) > 0 {
+			if deployment.Annotations == nil {
+				deployment.Annotations = make(map[string]string)
+			}
+			data, _ := json.Marshal(owners) // Keys are sorted
+			deployment.Annotations[ENV_OWNERS_ANNOTATION] = string(data)
+		} else {
+			delete(deployment.Annotations, ENV_OWNERS_ANNOTATION)
+		}
 		return deployment
 	})
 
-	// Response #2
+	// Response #3
 	// Do not clear the cache, but reset the change mark
 	this.svcEnvCache.ProcessAndAdvanceToNextPeriod()
 
@@ -135,3 +195,21 @@ func (this *EnvApplyCF) Cleanup() bool {
 	// No cleanup
 	return true
 }
+
+// Return the paths of the spec.configuration.env entries with the given names
+func (this *EnvApplyCF) getSpecEnvPaths(names []string) string {
+	paths := make([]string, 0)
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		for i, e := range specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Env {
+			for _, name := range names {
+				if e.Name == name {
+					paths = append(paths, "spec.configuration.env["+strconv.Itoa(i)+"]")
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return "spec.configuration.env"
+	}
+	return strings.Join(paths, ", ")
+}
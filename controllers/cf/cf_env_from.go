@@ -0,0 +1,178 @@
+package cf
+
+import (
+	"reflect"
+	"sort"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ loop.ControlFunction = &EnvFromCF{}
+
+// This CF applies the `spec.configuration.envFrom` sources to the registry container.
+// Kubernetes gives the `env` entries (written by EnvApplyCF) precedence over the `envFrom` sources,
+// so the operator-managed variables are never overridden. Shadowed keys are reported instead.
+type EnvFromCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+
+	deploymentExists bool
+	deploymentEntry  resources.ResourceCacheEntry
+	targetEnvFrom    []core.EnvFromSource
+	existingEnvFrom  []core.EnvFromSource
+	conflicts        []string
+}
+
+func NewEnvFromCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &EnvFromCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *EnvFromCF) Describe() string {
+	return "EnvFromCF"
+}
+
+func (this *EnvFromCF) Sense() {
+	this.targetEnvFrom = nil
+	this.existingEnvFrom = nil
+	this.conflicts = nil
+
+	// Observation #1
+	// Read the config values
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		this.targetEnvFrom = specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.EnvFrom
+	}
+
+	// Observation #2
+	// Read the existing value
+	this.deploymentEntry, this.deploymentExists = this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	if this.deploymentExists {
+		deployment := this.deploymentEntry.GetValue().(*apps.Deployment)
+		for _, c := range deployment.Spec.Template.Spec.Containers {
+			if c.Name == factory.REGISTRY_CONTAINER_NAME {
+				this.existingEnvFrom = c.EnvFrom
+			}
+		}
+	}
+
+	// Observation #3
+	// Find the keys shadowed by the operator-managed env. variables, once per loop.
+	// The other env. CFs have already been executed.
+	if this.ctx.GetAttempts() == 0 {
+		for _, source := range this.targetEnvFrom {
+			keys := this.readKeys(source)
+			this.conflicts = append(this.conflicts, findEnvFromConflicts(this.svcEnvCache, source.Prefix, keys)...)
+		}
+		sort.Strings(this.conflicts)
+	}
+}
+
+func (this *EnvFromCF) readKeys(source core.EnvFromSource) []string {
+	keys := make([]string, 0)
+	namespace := this.ctx.GetAppNamespace()
+	if source.ConfigMapRef != nil {
+		configMap, err := this.ctx.GetClients().Kube().GetConfigMap(namespace, common.Name(source.ConfigMapRef.Name))
+		if err != nil {
+			if !api_errors.IsNotFound(err) {
+				this.log.Warnw("could not read ConfigMap", "name", source.ConfigMapRef.Name, "error", err)
+			}
+			return keys
+		}
+		for k := range configMap.Data {
+			keys = append(keys, k)
+		}
+		for k := range configMap.BinaryData {
+			keys = append(keys, k)
+		}
+	}
+	if source.SecretRef != nil {
+		secret, err := this.ctx.GetClients().Kube().GetSecret(namespace, common.Name(source.SecretRef.Name), &meta.GetOptions{})
+		if err != nil {
+			if !api_errors.IsNotFound(err) {
+				this.log.Warnw("could not read Secret", "name", source.SecretRef.Name, "error", err)
+			}
+			return keys
+		}
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (this *EnvFromCF) Compare() bool {
+	// Condition #1
+	// The sources have changed
+	// Condition #2
+	// Report the conflicts once per loop
+	return (this.deploymentExists && !isEnvFromEqual(this.targetEnvFrom, this.existingEnvFrom)) ||
+		len(this.conflicts) > 0
+}
+
+func (this *EnvFromCF) Respond() {
+	// Response #1
+	// Patch the deployment
+	if this.deploymentExists && !isEnvFromEqual(this.targetEnvFrom, this.existingEnvFrom) {
+		this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+			deployment := value.(*apps.Deployment).DeepCopy()
+			for i, c := range deployment.Spec.Template.Spec.Containers {
+				if c.Name == factory.REGISTRY_CONTAINER_NAME {
+					deployment.Spec.Template.Spec.Containers[i].EnvFrom = this.targetEnvFrom
+				}
+			}
+			return deployment
+		})
+	}
+
+	// Response #2
+	// Report the conflicts
+	if len(this.conflicts) > 0 {
+		this.services.GetConditionManager().GetConfigurationWarningCondition().TransitionEnvFromConflict(this.conflicts)
+	}
+}
+
+func (this *EnvFromCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Return the names of env. variables from an envFrom source with the given prefix and keys,
+// that are overridden by an operator-managed env. variable
+func findEnvFromConflicts(envCache env.EnvCache, prefix string, keys []string) []string {
+	res := make([]string, 0)
+	for _, k := range keys {
+		if entry, exists := envCache.Get(prefix + k); exists && isOperatorManagedEnv(entry) {
+			res = append(res, prefix+k)
+		}
+	}
+	return res
+}
+
+func isOperatorManagedEnv(entry env.EnvCacheEntry) bool {
+	return entry.GetPriority() == env.PRIORITY_OPERATOR || entry.GetOwner() != ""
+}
+
+func isEnvFromEqual(a []core.EnvFromSource, b []core.EnvFromSource) bool {
+	return (len(a) == 0 && len(b) == 0) || reflect.DeepEqual(a, b)
+}
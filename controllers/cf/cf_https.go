@@ -297,7 +297,9 @@ func (this *HttpsCF) Respond() {
 		for k, v := range this.targetJavaOptions {
 			this.javaOptions[k] = v
 		}
-		env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, true)
+		if err := env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, true); err != nil {
+			this.log.Errorw("could not add java options", "error", err)
+		}
 		this.log.Debugw("added java options", "this.javaOptions", this.javaOptions)
 	}
 	if !this.httpsEnabled && this.javaOptionsExists {
@@ -309,7 +311,9 @@ func (this *HttpsCF) Respond() {
 			}
 		}
 		if changed {
-			env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, false)
+			if err := env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, false); err != nil {
+				this.log.Errorw("could not remove java options", "error", err)
+			}
 			this.log.Debugw("removed java options", "this.javaOptions", this.javaOptions)
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 )
@@ -19,10 +20,13 @@ const (
 
 	DEFAULT_REGISTRY_KEYCLOAK_API_CLIENT_ID = "registry-client-api"
 	DEFAULT_REGISTRY_KEYCLOAK_UI_CLIENT_ID  = "registry-client-ui"
+
+	keycloakOptionPath = "spec.configuration.security.keycloak"
 )
 
 type KeycloakCF struct {
 	ctx              context.LoopContext
+	services         services.LoopServices
 	svcResourceCache resources.ResourceCache
 	specEntry        resources.ResourceCacheEntry
 	svcEnvCache      env.EnvCache
@@ -41,11 +45,14 @@ type KeycloakCF struct {
 
 	applyDefaultApi bool
 	applyDefaultUi  bool
+
+	staleEnv bool
 }
 
-func NewKeycloakCF(ctx context.LoopContext) loop.ControlFunction {
+func NewKeycloakCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &KeycloakCF{
 		ctx:              ctx,
+		services:         services,
 		svcResourceCache: ctx.GetResourceCache(),
 		svcEnvCache:      ctx.GetEnvCache(),
 	}
@@ -75,6 +82,11 @@ func (this *KeycloakCF) Sense() {
 
 	// Observation #3
 	// Read the env values
+	this.envAuthEnabled = ""
+	this.envKeycloakUrl = ""
+	this.envKeycloakRealm = ""
+	this.envKeycloakApiClientId = ""
+	this.envKeycloakUiClientId = ""
 	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_AUTH_ENABLED); exists {
 		this.envAuthEnabled = val.GetValue().Value
 	}
@@ -101,27 +113,40 @@ func (this *KeycloakCF) Sense() {
 		this.applyDefaultUi = true
 		this.keycloakUiClientId = DEFAULT_REGISTRY_KEYCLOAK_UI_CLIENT_ID
 	}
+
+	// Observation #5
+	// Are there env values set by this CF that are no longer needed?
+	this.staleEnv = !this.valid && len(this.svcEnvCache.GetByOwner(this.Describe())) > 0
 }
 
 func (this *KeycloakCF) Compare() bool {
 	// Condition #1
-	return this.valid && (this.envAuthEnabled != "true" ||
+	// Condition #2
+	// Stale env vars have to be removed
+	return (this.valid && (this.envAuthEnabled != "true" ||
 		this.keycloakUrl != this.envKeycloakUrl ||
 		this.keycloakRealm != this.envKeycloakRealm ||
 		this.keycloakApiClientId != this.envKeycloakApiClientId ||
-		this.keycloakUiClientId != this.envKeycloakUiClientId)
+		this.keycloakUiClientId != this.envKeycloakUiClientId)) || this.staleEnv
 }
 
 func (this *KeycloakCF) Respond() {
 	// Response #1
+	// Remove the stale value(s), e.g. when Keycloak is removed from the spec
+	if this.staleEnv {
+		this.svcEnvCache.DeleteByOwner(this.Describe())
+		return
+	}
+
+	// Response #2
 	// Just set the value(s)!
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_AUTH_ENABLED, "true").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KEYCLOAK_URL, this.keycloakUrl).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KEYCLOAK_REALM, this.keycloakRealm).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KEYCLOAK_API_CLIENT_ID, this.keycloakApiClientId).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KEYCLOAK_UI_CLIENT_ID, this.keycloakUiClientId).Build())
+	this.setEnv(ENV_REGISTRY_AUTH_ENABLED, "true", keycloakOptionPath)
+	this.setEnv(ENV_REGISTRY_KEYCLOAK_URL, this.keycloakUrl, keycloakOptionPath+".url")
+	this.setEnv(ENV_REGISTRY_KEYCLOAK_REALM, this.keycloakRealm, keycloakOptionPath+".realm")
+	this.setEnv(ENV_REGISTRY_KEYCLOAK_API_CLIENT_ID, this.keycloakApiClientId, keycloakOptionPath+".apiClientId")
+	this.setEnv(ENV_REGISTRY_KEYCLOAK_UI_CLIENT_ID, this.keycloakUiClientId, keycloakOptionPath+".uiClientId")
 
-	// Response #1
+	// Response #3
 	// Update defaults
 	if this.applyDefaultApi {
 		this.specEntry.ApplyPatch(func(value interface{}) interface{} {
@@ -139,6 +164,13 @@ func (this *KeycloakCF) Respond() {
 	}
 }
 
+func (this *KeycloakCF) setEnv(name string, value string, optionPath string) {
+	if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(name, value).SetOwner(this.Describe()).Build()); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), optionPath)
+	}
+}
+
 func (this *KeycloakCF) Cleanup() bool {
 	// No cleanup
 	return true
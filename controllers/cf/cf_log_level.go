@@ -4,6 +4,7 @@ import (
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 )
@@ -15,6 +16,7 @@ const ENV_REGISTRY_LOG_LEVEL2 = "REGISTRY_LOG_LEVEL"
 
 type LogLevelCF struct {
 	ctx              context.LoopContext
+	services         services.LoopServices
 	svcResourceCache resources.ResourceCache
 	svcEnvCache      env.EnvCache
 	valid            bool
@@ -22,11 +24,14 @@ type LogLevelCF struct {
 	registryLogLevel string
 	envLogLevel      string
 	envLogLevel2     string
+	staleLogLevel    bool
+	staleLogLevel2   bool
 }
 
-func NewLogLevelCF(ctx context.LoopContext) loop.ControlFunction {
+func NewLogLevelCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &LogLevelCF{
 		ctx:              ctx,
+		services:         services,
 		svcResourceCache: ctx.GetResourceCache(),
 		svcEnvCache:      ctx.GetEnvCache(),
 		valid:            true,
@@ -50,33 +55,55 @@ func (this *LogLevelCF) Sense() {
 	// Observation #2
 	// Read the env values
 	this.envLogLevel = ""
+	this.staleLogLevel = false
 	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_LOG_LEVEL); exists {
 		this.envLogLevel = val.GetValue().Value
+		this.staleLogLevel = this.logLevel == "" && val.GetOwner() == this.Describe()
 	}
 	this.envLogLevel2 = ""
+	this.staleLogLevel2 = false
 	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_LOG_LEVEL2); exists {
 		this.envLogLevel2 = val.GetValue().Value
+		this.staleLogLevel2 = this.registryLogLevel == "" && val.GetOwner() == this.Describe()
 	}
 
 	// TODO log level validation?
-
-	// We won't actively delete old env values if not used
 }
 
 func (this *LogLevelCF) Compare() bool {
 	// Condition #1
 	// Has the value changed
-	return this.logLevel != this.envLogLevel || this.registryLogLevel != this.envLogLevel2
+	// Condition #2
+	// The value has been removed from the spec
+	return (this.logLevel != "" && this.logLevel != this.envLogLevel) ||
+		(this.registryLogLevel != "" && this.registryLogLevel != this.envLogLevel2) ||
+		this.staleLogLevel || this.staleLogLevel2
 }
 
 func (this *LogLevelCF) Respond() {
 	// Response #1
 	// Just set the value(s)!
 	if this.logLevel != "" {
-		this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_LOG_LEVEL, this.logLevel).Build())
+		this.setEnv(ENV_REGISTRY_LOG_LEVEL, this.logLevel, "spec.configuration.logLevel")
 	}
 	if this.registryLogLevel != "" {
-		this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_LOG_LEVEL2, this.registryLogLevel).Build())
+		this.setEnv(ENV_REGISTRY_LOG_LEVEL2, this.registryLogLevel, "spec.configuration.registryLogLevel")
+	}
+
+	// Response #2
+	// Remove the stale value(s)
+	if this.staleLogLevel {
+		this.svcEnvCache.DeleteByName(ENV_REGISTRY_LOG_LEVEL)
+	}
+	if this.staleLogLevel2 {
+		this.svcEnvCache.DeleteByName(ENV_REGISTRY_LOG_LEVEL2)
+	}
+}
+
+func (this *LogLevelCF) setEnv(name string, value string, optionPath string) {
+	if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(name, value).SetOwner(this.Describe()).Build()); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), optionPath)
 	}
 }
 
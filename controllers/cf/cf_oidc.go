@@ -0,0 +1,209 @@
+package cf
+
+import (
+	"net/url"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	core "k8s.io/api/core/v1"
+)
+
+var _ loop.ControlFunction = &OidcCF{}
+
+const (
+	ENV_QUARKUS_OIDC_AUTH_SERVER_URL    = "QUARKUS_OIDC_AUTH_SERVER_URL"
+	ENV_QUARKUS_OIDC_CLIENT_ID          = "QUARKUS_OIDC_CLIENT_ID"
+	ENV_QUARKUS_OIDC_CREDENTIALS_SECRET = "QUARKUS_OIDC_CREDENTIALS_SECRET"
+	ENV_QUARKUS_OIDC_TOKEN_AUDIENCE     = "QUARKUS_OIDC_TOKEN_AUDIENCE"
+	// Used by the UI, and the Keycloak specific configuration
+	ENV_REGISTRY_AUTH_URL_CONFIGURED = "REGISTRY_AUTH_URL_CONFIGURED"
+	ENV_REGISTRY_UI_AUTH_TYPE        = "REGISTRY_UI_AUTH_TYPE"
+	ENV_REGISTRY_OIDC_UI_CLIENT_ID   = "REGISTRY_OIDC_UI_CLIENT_ID"
+
+	REGISTRY_UI_AUTH_TYPE_OIDC = "oidc"
+	OIDC_CLIENT_SECRET_KEY     = "clientSecret"
+
+	oidcOptionPath = "spec.configuration.security.oidc"
+)
+
+// This CF configures a generic OIDC provider, an alternative to KeycloakCF
+type OidcCF struct {
+	ctx                      context.LoopContext
+	services                 services.LoopServices
+	svcResourceCache         resources.ResourceCache
+	svcEnvCache              env.EnvCache
+	valid                    bool
+	requiredOption           string
+	invalidDetails           string
+	targetEnv                map[string]string
+	targetClientSecretName   string
+	existingEnv              map[string]string
+	existingClientSecretName string
+	staleEnv                 []string
+}
+
+func NewOidcCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	return &OidcCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+		valid:            false,
+		targetEnv:        make(map[string]string),
+		existingEnv:      make(map[string]string),
+	}
+}
+
+func (this *OidcCF) Describe() string {
+	return "OidcCF"
+}
+
+func (this *OidcCF) Sense() {
+	this.requiredOption = ""
+	this.invalidDetails = ""
+	this.targetEnv = make(map[string]string)
+	this.targetClientSecretName = ""
+	this.existingEnv = make(map[string]string)
+	this.existingClientSecretName = ""
+	this.staleEnv = nil
+
+	// Observation #1
+	// Read the config values
+	var config ar.ApicurioRegistrySpecConfigurationSecurityOidc
+	var keycloak ar.ApicurioRegistrySpecConfigurationSecurityKeycloak
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		spec := specEntry.GetValue().(*ar.ApicurioRegistry).Spec
+		config = spec.Configuration.Security.Oidc
+		keycloak = spec.Configuration.Security.Keycloak
+	}
+
+	// Observation #2
+	// Validate the config values
+	configured := config != (ar.ApicurioRegistrySpecConfigurationSecurityOidc{})
+	if configured {
+		if config.ServerUrl == "" {
+			this.requiredOption = oidcOptionPath + ".serverUrl"
+		} else if details := validateOidcServerUrl(config.ServerUrl); details != "" {
+			this.invalidDetails = details
+		} else if keycloak.Url != "" || keycloak.Realm != "" {
+			this.invalidDetails = "only one of keycloak or oidc can be configured"
+		}
+	}
+	this.valid = configured && this.requiredOption == "" && this.invalidDetails == ""
+	if this.valid {
+		this.targetEnv[ENV_REGISTRY_AUTH_ENABLED] = "true"
+		this.targetEnv[ENV_QUARKUS_OIDC_AUTH_SERVER_URL] = config.ServerUrl
+		this.targetEnv[ENV_REGISTRY_AUTH_URL_CONFIGURED] = config.ServerUrl
+		this.targetEnv[ENV_REGISTRY_UI_AUTH_TYPE] = REGISTRY_UI_AUTH_TYPE_OIDC
+		if config.ApiClientId != "" {
+			this.targetEnv[ENV_QUARKUS_OIDC_CLIENT_ID] = config.ApiClientId
+		}
+		if config.UiClientId != "" {
+			this.targetEnv[ENV_REGISTRY_OIDC_UI_CLIENT_ID] = config.UiClientId
+		}
+		if config.TokenAudience != "" {
+			this.targetEnv[ENV_QUARKUS_OIDC_TOKEN_AUDIENCE] = config.TokenAudience
+		}
+		this.targetClientSecretName = config.ClientSecretName
+	}
+
+	// Observation #3
+	// Read the env values
+	for name := range this.targetEnv {
+		if entry, exists := this.svcEnvCache.Get(name); exists && entry.GetValue().ValueFrom == nil {
+			this.existingEnv[name] = entry.GetValue().Value
+		}
+	}
+	if entry, exists := this.svcEnvCache.Get(ENV_QUARKUS_OIDC_CREDENTIALS_SECRET); exists &&
+		entry.GetValue().ValueFrom != nil && entry.GetValue().ValueFrom.SecretKeyRef != nil {
+		this.existingClientSecretName = entry.GetValue().ValueFrom.SecretKeyRef.Name
+	}
+
+	// Observation #4
+	// Are there env values set by this CF that are no longer needed?
+	for _, entry := range this.svcEnvCache.GetByOwner(this.Describe()) {
+		name := entry.GetName()
+		if _, exists := this.targetEnv[name]; !exists &&
+			(name != ENV_QUARKUS_OIDC_CREDENTIALS_SECRET || this.targetClientSecretName == "") {
+			this.staleEnv = append(this.staleEnv, name)
+		}
+	}
+}
+
+func (this *OidcCF) Compare() bool {
+	// Condition #1
+	// Config values are valid
+	// Condition #2
+	// The required env vars are not present OR they differ
+	// Condition #3
+	// Stale env vars have to be removed
+	// Condition #4
+	// Report invalid configuration once per loop
+	return (this.valid && (!isEnvEqual(this.targetEnv, this.existingEnv) ||
+		this.targetClientSecretName != this.existingClientSecretName)) ||
+		len(this.staleEnv) > 0 ||
+		((this.requiredOption != "" || this.invalidDetails != "") && this.ctx.GetAttempts() == 0)
+}
+
+func (this *OidcCF) Respond() {
+	// Response #1
+	// Just set the value(s)!
+	for name, value := range this.targetEnv {
+		if existing, exists := this.existingEnv[name]; !exists || existing != value {
+			if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(name, value).SetOwner(this.Describe()).Build()); err != nil {
+				this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+				this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), oidcOptionPath)
+			}
+		}
+	}
+	if this.targetClientSecretName != "" && this.targetClientSecretName != this.existingClientSecretName {
+		if err := this.svcEnvCache.Set(env.NewEnvCacheEntryBuilder(&core.EnvVar{
+			Name: ENV_QUARKUS_OIDC_CREDENTIALS_SECRET,
+			ValueFrom: &core.EnvVarSource{
+				SecretKeyRef: &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{
+						Name: this.targetClientSecretName,
+					},
+					Key: OIDC_CLIENT_SECRET_KEY,
+				},
+			},
+		}).SetOwner(this.Describe()).Build()); err != nil {
+			this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), oidcOptionPath+".clientSecretName")
+		}
+	}
+
+	// Response #2
+	// Remove the stale value(s)
+	for _, name := range this.staleEnv {
+		this.svcEnvCache.DeleteByName(name)
+	}
+
+	// Response #3
+	// Report the missing or invalid value
+	if this.ctx.GetAttempts() == 0 {
+		if this.requiredOption != "" {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionRequired(this.requiredOption)
+		}
+		if this.invalidDetails != "" {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(this.invalidDetails, oidcOptionPath)
+		}
+	}
+}
+
+func (this *OidcCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+func validateOidcServerUrl(serverUrl string) string {
+	parsed, err := url.Parse(serverUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "serverUrl must be a valid http or https URL"
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package cf
+
+import (
+	"strings"
+	"testing"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
+	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+)
+
+func TestOidc(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewKeycloakCF(ctx, services))
+	loop.AddControlFunction(NewOidcCF(ctx, services))
+	loop.AddControlFunction(NewCorsCF(ctx, services))
+
+	spec := &ar.ApicurioRegistry{
+		Spec: ar.ApicurioRegistrySpec{
+			Deployment: ar.ApicurioRegistrySpecDeployment{
+				Host: "registry.example.com",
+			},
+			Configuration: ar.ApicurioRegistrySpecConfiguration{
+				Security: ar.ApicurioRegistrySpecConfigurationSecurity{
+					Oidc: ar.ApicurioRegistrySpecConfigurationSecurityOidc{
+						ServerUrl:        "https://login.example.com:8443/tenant/v2.0",
+						ApiClientId:      "registry-api",
+						ClientSecretName: "registry-api-client",
+						TokenAudience:    "registry",
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+
+	assertEnv := func(name string, expected string) {
+		entry, exists := ctx.GetEnvCache().Get(name)
+		c.AssertEquals(t, expected != "", exists)
+		if exists {
+			c.AssertEquals(t, expected, entry.GetValue().Value)
+		}
+	}
+	assertEnv(ENV_REGISTRY_AUTH_ENABLED, "true")
+	assertEnv(ENV_QUARKUS_OIDC_AUTH_SERVER_URL, "https://login.example.com:8443/tenant/v2.0")
+	assertEnv(ENV_QUARKUS_OIDC_CLIENT_ID, "registry-api")
+	assertEnv(ENV_QUARKUS_OIDC_TOKEN_AUDIENCE, "registry")
+	assertEnv(ENV_REGISTRY_UI_AUTH_TYPE, "oidc")
+	assertEnv(ENV_REGISTRY_OIDC_UI_CLIENT_ID, "")
+	assertEnv(ENV_CORS, "http://registry.example.com,https://registry.example.com,https://login.example.com")
+	entry, _ := ctx.GetEnvCache().Get(ENV_QUARKUS_OIDC_CREDENTIALS_SECRET)
+	c.AssertEquals(t, "registry-api-client", entry.GetValue().ValueFrom.SecretKeyRef.Name)
+
+	// Keycloak and OIDC can not be configured together
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Security.Keycloak = ar.ApicurioRegistrySpecConfigurationSecurityKeycloak{
+		Url:   "https://keycloak.example.com/auth",
+		Realm: "registry",
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.security.oidc"))
+	assertEnv(ENV_REGISTRY_AUTH_ENABLED, "true")
+	assertEnv(ENV_REGISTRY_KEYCLOAK_URL, "https://keycloak.example.com/auth")
+	assertEnv(ENV_QUARKUS_OIDC_AUTH_SERVER_URL, "")
+	assertEnv(ENV_QUARKUS_OIDC_CREDENTIALS_SECRET, "")
+}
@@ -0,0 +1,502 @@
+package cf
+
+import (
+	"fmt"
+	"reflect"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/cf/kafkasql"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ loop.ControlFunction = &PersistenceMigrationCF{}
+
+const (
+	MIGRATION_PHASE_EXPORTING   = string(conditions.PERSISTENCE_MIGRATION_REASON_EXPORTING)
+	MIGRATION_PHASE_ROLLING_OUT = string(conditions.PERSISTENCE_MIGRATION_REASON_ROLLING_OUT)
+	MIGRATION_PHASE_IMPORTING   = string(conditions.PERSISTENCE_MIGRATION_REASON_IMPORTING)
+	MIGRATION_PHASE_CLEANING_UP = string(conditions.PERSISTENCE_MIGRATION_REASON_CLEANING_UP)
+	MIGRATION_PHASE_COMPLETED   = string(conditions.PERSISTENCE_MIGRATION_REASON_COMPLETED)
+	MIGRATION_PHASE_FAILED      = string(conditions.PERSISTENCE_MIGRATION_REASON_FAILED)
+
+	// Seconds to wait before checking the progress of the export, rollout, or import again
+	migrationPollDelay = 10
+
+	ENV_REGISTRY_STORAGE_READ_ONLY = "REGISTRY_STORAGE_READ_ONLY"
+)
+
+// Owners of the env. variables that configure each storage type, see the Describe method of the CFs
+var migrationEnvOwners = map[string][]string{
+	"sql": {"SqlCF"},
+	kafkasql.PERSISTENCE_ID: {
+		"KafkasqlCF", "KafkasqlSecurityTLSCF", "KafkasqlSecurityScramCF", "KafkasqlSecurityOAuthCF",
+	},
+}
+
+// This CF orchestrates the migration of data when the storage type is changed:
+//  1. Make the existing deployment read-only, and export the data using an ApicurioRegistryBackup.
+//     The mem storage can not be made read-only, so the changes made during the export are lost,
+//  2. roll out the new storage,
+//  3. import the data using an ApicurioRegistryRestore,
+//  4. remove the configuration of the previous storage, and complete the migration.
+//
+// The storage type used by the deployment is tracked in `status.persistence`.
+// Until the data is exported, the cached spec is pinned to that storage type,
+// so the CFs that configure the storage keep the existing deployment unchanged.
+// Afterwards, the env. variables of the previous storage are adopted by this CF until the data is imported,
+// because the CFs that manage them remove them when the storage type changes, see env.EnvCache.DeleteByOwner.
+// If the migration fails, the spec is pinned to the previous storage again.
+type PersistenceMigrationCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+
+	specEntry         resources.ResourceCacheEntry
+	statusEntry       resources.ResourceCacheEntry
+	targetPersistence string
+	existing          *ar.ApicurioRegistryStatusPersistence
+	target            *ar.ApicurioRegistryStatusPersistence
+	pinPersistence    string
+	createBackup      bool
+	createRestore     bool
+	createFailed      bool
+	storageInvalid    bool
+	waiting           bool
+	targetReadOnly    bool
+	existingReadOnly  bool
+	retainEnv         []env.EnvCacheEntry
+	staleEnv          []string
+}
+
+func NewPersistenceMigrationCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &PersistenceMigrationCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *PersistenceMigrationCF) Describe() string {
+	return "PersistenceMigrationCF"
+}
+
+func (this *PersistenceMigrationCF) Sense() {
+	this.target = nil
+	this.pinPersistence = ""
+	this.createBackup = false
+	this.createRestore = false
+	this.storageInvalid = false
+	this.waiting = false
+	this.targetReadOnly = false
+	this.existingReadOnly = false
+	this.retainEnv = nil
+	this.staleEnv = nil
+	if this.ctx.GetAttempts() == 0 {
+		this.createFailed = false
+	}
+
+	// Observation #1
+	// Get the spec and the existing status
+	var specExists, statusExists bool
+	this.specEntry, specExists = this.svcResourceCache.Get(resources.RC_KEY_SPEC)
+	this.statusEntry, statusExists = this.svcResourceCache.Get(resources.RC_KEY_STATUS)
+	if !specExists || !statusExists {
+		return
+	}
+	spec := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	// The spec is reloaded before each loop, and it might be pinned during the following attempts
+	if this.ctx.GetAttempts() == 0 {
+		this.targetPersistence = normalizePersistence(spec.Spec.Configuration.Persistence)
+	}
+	if this.targetPersistence != "mem" && this.targetPersistence != "sql" && this.targetPersistence != kafkasql.PERSISTENCE_ID {
+		// Reported by ImageCF
+		return
+	}
+	this.existing = this.statusEntry.GetValue().(*ar.ApicurioRegistryStatus).Persistence
+	if this.existing != nil {
+		this.target = this.existing.DeepCopy()
+	} else {
+		this.target = &ar.ApicurioRegistryStatusPersistence{}
+	}
+
+	// Observation #2
+	// Is there a deployment with the existing storage?
+	deploymentEntry, deploymentExists := this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	deploymentExists = deploymentExists && deploymentEntry.GetName() != resources.RC_NOT_CREATED_NAME_EMPTY
+
+	// Observation #3
+	// Determine the next migration step
+	migration := this.target.Migration
+	if migration != nil && isMigrationInProgress(migration.Phase) {
+		this.senseMigration(deploymentEntry, deploymentExists)
+	} else if this.target.Type == "" {
+		// Nothing has been deployed yet, or the operator has been upgraded
+		this.target.Type = this.targetPersistence
+	} else if this.target.Type != this.targetPersistence {
+		this.senseStart(spec, deploymentEntry, deploymentExists)
+	}
+
+	// Observation #4
+	// Keep the existing storage, until the data has been exported.
+	// The status might change during the loop, so the spec is also unpinned if needed.
+	this.pinPersistence = this.target.Type
+
+	// Observation #5
+	// Which env. variables have to be set or removed?
+	this.senseEnv()
+}
+
+func (this *PersistenceMigrationCF) senseStart(spec *ar.ApicurioRegistry, deploymentEntry resources.ResourceCacheEntry, deploymentExists bool) {
+	config := spec.Spec.Configuration.PersistenceMigration
+	if !config.Enabled || !deploymentExists {
+		this.target.Type = this.targetPersistence
+		return
+	}
+	if last := this.target.Migration; last != nil && last.Phase == MIGRATION_PHASE_FAILED &&
+		last.From == this.target.Type && last.To == this.targetPersistence {
+		// Do not retry automatically, the storage type has to be changed back and forth
+		return
+	}
+	if (config.Storage.Pvc == nil) == (config.Storage.S3 == nil) {
+		this.storageInvalid = true
+		return
+	}
+	now := meta.Now()
+	this.target.Migration = &ar.ApicurioRegistryStatusPersistenceMigration{
+		Phase:      MIGRATION_PHASE_EXPORTING,
+		From:       this.target.Type,
+		To:         this.targetPersistence,
+		BackupName: fmt.Sprintf("%s-migration-%d", this.ctx.GetAppName().Str(), now.Unix()),
+		StartTime:  &now,
+	}
+	if !isReadOnlyRequired(this.target.Migration) {
+		this.target.Migration.Message = "The " + this.target.Type + " storage can not be made read-only, " +
+			"changes made after the data has been exported are not migrated."
+	}
+	this.createBackup = !this.createFailed && this.isReadyForExport(deploymentEntry, deploymentExists)
+	this.waiting = true
+}
+
+func (this *PersistenceMigrationCF) senseMigration(deploymentEntry resources.ResourceCacheEntry, deploymentExists bool) {
+	migration := this.target.Migration
+	if migration.To != this.targetPersistence {
+		this.failMigration("the storage type has been changed to " + this.targetPersistence + " during the migration")
+		return
+	}
+	namespace := this.ctx.GetAppNamespace()
+	switch migration.Phase {
+	case MIGRATION_PHASE_EXPORTING:
+		backup, err := this.ctx.GetClients().CRD().GetApicurioRegistryBackup(namespace, common.Name(migration.BackupName))
+		if err != nil {
+			this.log.Warnw("could not get ApicurioRegistryBackup", "name", migration.BackupName, "error", err)
+			this.waiting = true
+			return
+		}
+		if backup == nil {
+			this.createBackup = !this.createFailed && this.isReadyForExport(deploymentEntry, deploymentExists)
+			this.waiting = true
+			return
+		}
+		if cond := api_meta.FindStatusCondition(backup.Status.Conditions,
+			string(conditions.CONDITION_TYPE_CONFIGURATION_ERROR)); cond != nil && cond.Status == meta.ConditionTrue {
+			this.failMigration("could not export the data: " + cond.Message)
+			return
+		}
+		last := backup.Status.LastBackup
+		switch {
+		case last == nil || last.Result == ar.BACKUP_RESULT_RUNNING:
+			this.waiting = true
+		case last.Result == ar.BACKUP_RESULT_FAILED:
+			this.failMigration("could not export the data: " + last.Message)
+		case last.Result == ar.BACKUP_RESULT_SUCCEEDED:
+			migration.Phase = MIGRATION_PHASE_ROLLING_OUT
+			this.target.Type = migration.To
+			this.waiting = true
+		}
+	case MIGRATION_PHASE_ROLLING_OUT:
+		// The Deployment is patched at the end of the loop, wait for the next one
+		if this.ctx.GetAttempts() == 0 && deploymentExists && !deploymentEntry.HasChanged() &&
+			isDeploymentRolledOut(deploymentEntry.GetValue().(*apps.Deployment)) {
+			migration.Phase = MIGRATION_PHASE_IMPORTING
+			migration.RestoreName = migration.BackupName
+			this.createRestore = !this.createFailed
+		}
+		this.waiting = true
+	case MIGRATION_PHASE_IMPORTING:
+		restore, err := this.ctx.GetClients().CRD().GetApicurioRegistryRestore(namespace, common.Name(migration.RestoreName))
+		if err != nil {
+			this.log.Warnw("could not get ApicurioRegistryRestore", "name", migration.RestoreName, "error", err)
+			this.waiting = true
+			return
+		}
+		switch {
+		case restore == nil:
+			this.createRestore = !this.createFailed
+			this.waiting = true
+		case restore.Status.Phase == ar.RESTORE_PHASE_FAILED:
+			this.failMigration("could not import the data: " + restore.Status.Message)
+		case restore.Status.Phase == ar.RESTORE_PHASE_SUCCEEDED:
+			migration.Phase = MIGRATION_PHASE_CLEANING_UP
+		default:
+			this.waiting = true
+		}
+	case MIGRATION_PHASE_CLEANING_UP:
+		// The env. variables of the previous storage are removed when entering this phase,
+		// wait until the deployment without them has been rolled out
+		if this.ctx.GetAttempts() == 0 && len(this.svcEnvCache.GetByOwner(this.Describe())) == 0 &&
+			deploymentExists && !deploymentEntry.HasChanged() &&
+			isDeploymentRolledOut(deploymentEntry.GetValue().(*apps.Deployment)) {
+			now := meta.Now()
+			migration.Phase = MIGRATION_PHASE_COMPLETED
+			migration.CompletionTime = &now
+			return
+		}
+		this.waiting = true
+	}
+}
+
+// The deployment has to be rolled out in the read-only mode before the data is exported,
+// otherwise the changes made during the export would be lost
+func (this *PersistenceMigrationCF) isReadyForExport(deploymentEntry resources.ResourceCacheEntry, deploymentExists bool) bool {
+	if !isReadOnlyRequired(this.target.Migration) {
+		return true
+	}
+	// The Deployment is patched at the end of the loop, wait for the next one
+	if this.ctx.GetAttempts() != 0 || !deploymentExists || deploymentEntry.HasChanged() {
+		return false
+	}
+	deployment := deploymentEntry.GetValue().(*apps.Deployment)
+	container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME)
+	if container == nil {
+		return false
+	}
+	for _, e := range container.Env {
+		if e.Name == ENV_REGISTRY_STORAGE_READ_ONLY && e.Value == "true" {
+			return isDeploymentRolledOut(deployment)
+		}
+	}
+	return false
+}
+
+// Rolls back to the previous storage, its configuration is kept until the data has been imported
+func (this *PersistenceMigrationCF) failMigration(message string) {
+	now := meta.Now()
+	this.target.Type = this.target.Migration.From
+	this.target.Migration.Phase = MIGRATION_PHASE_FAILED
+	this.target.Migration.Message = message
+	this.target.Migration.CompletionTime = &now
+}
+
+func (this *PersistenceMigrationCF) senseEnv() {
+	migration := this.target.Migration
+	inProgress := migration != nil && isMigrationInProgress(migration.Phase)
+	this.targetReadOnly = inProgress && migration.Phase == MIGRATION_PHASE_EXPORTING && isReadOnlyRequired(migration)
+	// The spec is not pinned to the previous storage after the data has been exported
+	retain := inProgress && this.target.Type != migration.From &&
+		(migration.Phase == MIGRATION_PHASE_ROLLING_OUT || migration.Phase == MIGRATION_PHASE_IMPORTING)
+	for _, entry := range this.svcEnvCache.GetByOwner(this.Describe()) {
+		if entry.GetName() == ENV_REGISTRY_STORAGE_READ_ONLY {
+			this.existingReadOnly = true
+		} else if !retain {
+			this.staleEnv = append(this.staleEnv, entry.GetName())
+		}
+	}
+	if retain {
+		for _, owner := range migrationEnvOwners[migration.From] {
+			this.retainEnv = append(this.retainEnv, this.svcEnvCache.GetByOwner(owner)...)
+		}
+	}
+}
+
+func (this *PersistenceMigrationCF) Compare() bool {
+	if this.target == nil {
+		return false
+	}
+	spec := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	// Condition #1
+	// Report the migration or configuration error once per loop
+	// Condition #2
+	// The spec has to be pinned to the existing storage
+	// Condition #3
+	// Resources have to be created
+	// Condition #4
+	// Status has to be updated
+	// Condition #5
+	// Env. variables have to be set or removed
+	return (this.ctx.GetAttempts() == 0 && (this.target.Migration != nil || this.storageInvalid || this.waiting)) ||
+		(this.pinPersistence != "" && normalizePersistence(spec.Spec.Configuration.Persistence) != this.pinPersistence) ||
+		this.createBackup || this.createRestore ||
+		!reflect.DeepEqual(this.existing, this.target) ||
+		this.targetReadOnly != this.existingReadOnly || len(this.retainEnv) > 0 || len(this.staleEnv) > 0
+}
+
+func (this *PersistenceMigrationCF) Respond() {
+	// Response #1
+	// Pin the existing storage type. The cache entry is replaced, so the change is never patched into the resource.
+	spec := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	if this.pinPersistence != "" && normalizePersistence(spec.Spec.Configuration.Persistence) != this.pinPersistence {
+		pinned := spec.DeepCopy()
+		pinned.Spec.Configuration.Persistence = this.pinPersistence
+		this.svcResourceCache.Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(this.specEntry.GetName(), pinned))
+	}
+
+	// Response #2
+	// Make the deployment read-only during the export, and keep the configuration of the previous storage
+	if this.targetReadOnly && !this.existingReadOnly {
+		if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_STORAGE_READ_ONLY, "true").
+			SetOwner(this.Describe()).Build()); err != nil {
+			this.log.Errorw("could not set env. variable", "name", ENV_REGISTRY_STORAGE_READ_ONLY, "error", err)
+		}
+	}
+	if !this.targetReadOnly && this.existingReadOnly {
+		this.svcEnvCache.DeleteByName(ENV_REGISTRY_STORAGE_READ_ONLY)
+	}
+	for _, entry := range this.retainEnv {
+		builder := env.NewEnvCacheEntryBuilder(entry.GetValue()).
+			SetPriority(entry.GetPriority()).
+			SetOwner(this.Describe())
+		for _, dependency := range entry.GetDependencies() {
+			builder.SetDependency(dependency)
+		}
+		if entry.IsLocked() {
+			builder.Lock()
+		}
+		if err := this.svcEnvCache.Set(builder.Build()); err != nil {
+			this.log.Errorw("could not set env. variable", "name", entry.GetName(), "error", err)
+		}
+	}
+	for _, name := range this.staleEnv {
+		this.svcEnvCache.DeleteByName(name)
+	}
+
+	// Response #3
+	// Create the backup or restore resources
+	migration := this.target.Migration
+	if this.createBackup {
+		backup := &ar.ApicurioRegistryBackup{
+			ObjectMeta: meta.ObjectMeta{
+				Name:      migration.BackupName,
+				Namespace: this.ctx.GetAppNamespace().Str(),
+				Labels:    this.services.GetKubeFactory().GetLabels(),
+			},
+			Spec: ar.ApicurioRegistryBackupSpec{
+				RegistryName: this.ctx.GetAppName().Str(),
+				Storage:      *spec.Spec.Configuration.PersistenceMigration.Storage.DeepCopy(),
+				RegistryAuth: spec.Spec.Configuration.PersistenceMigration.RegistryAuth,
+			},
+		}
+		if _, err := this.ctx.GetClients().CRD().CreateApicurioRegistryBackup(spec, this.ctx.GetAppNamespace(), backup); err != nil {
+			this.log.Warnw("could not create ApicurioRegistryBackup", "name", migration.BackupName, "error", err)
+			this.createFailed = true
+		}
+	}
+	if this.createRestore {
+		restore := &ar.ApicurioRegistryRestore{
+			ObjectMeta: meta.ObjectMeta{
+				Name:      migration.RestoreName,
+				Namespace: this.ctx.GetAppNamespace().Str(),
+				Labels:    this.services.GetKubeFactory().GetLabels(),
+			},
+			Spec: ar.ApicurioRegistryRestoreSpec{
+				RegistryName: this.ctx.GetAppName().Str(),
+				RegistryAuth: spec.Spec.Configuration.PersistenceMigration.RegistryAuth,
+				Source: ar.ApicurioRegistryRestoreSource{
+					Backup: &ar.ApicurioRegistryRestoreSourceBackup{
+						Name: migration.BackupName,
+					},
+				},
+			},
+		}
+		if _, err := this.ctx.GetClients().CRD().CreateApicurioRegistryRestore(spec, this.ctx.GetAppNamespace(), restore); err != nil {
+			this.log.Warnw("could not create ApicurioRegistryRestore", "name", migration.RestoreName, "error", err)
+			this.createFailed = true
+		}
+	}
+
+	// Response #4
+	// Update the status
+	if !reflect.DeepEqual(this.existing, this.target) {
+		target := this.target.DeepCopy()
+		this.statusEntry.ApplyPatch(func(value interface{}) interface{} {
+			status := value.(*ar.ApicurioRegistryStatus).DeepCopy()
+			status.Persistence = target
+			return status
+		})
+	}
+
+	// Response #5
+	// Report the progress
+	if this.storageInvalid {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid("exactly one of pvc or s3 must be configured", "spec.configuration.persistenceMigration.storage")
+		this.services.GetConditionManager().GetReadyCondition().TransitionError()
+	}
+	if migration != nil {
+		condition := this.services.GetConditionManager().GetPersistenceMigrationCondition()
+		switch migration.Phase {
+		case MIGRATION_PHASE_EXPORTING:
+			condition.TransitionExporting(migration.From, migration.To)
+		case MIGRATION_PHASE_ROLLING_OUT:
+			condition.TransitionRollingOut(migration.To)
+		case MIGRATION_PHASE_IMPORTING:
+			condition.TransitionImporting(migration.To)
+		case MIGRATION_PHASE_CLEANING_UP:
+			condition.TransitionCleaningUp(migration.From)
+		case MIGRATION_PHASE_COMPLETED:
+			condition.TransitionCompleted(migration.From, migration.To)
+		case MIGRATION_PHASE_FAILED:
+			condition.TransitionFailed(migration.Message)
+		}
+	}
+	if this.waiting || this.createFailed {
+		this.ctx.SetRequeueDelaySec(migrationPollDelay)
+	}
+}
+
+func (this *PersistenceMigrationCF) Cleanup() bool {
+	// The ApicurioRegistryBackup and ApicurioRegistryRestore resources are owned by the ApicurioRegistry
+	return true
+}
+
+func normalizePersistence(persistence string) string {
+	if persistence == "" {
+		return "mem"
+	}
+	return persistence
+}
+
+func isMigrationInProgress(phase string) bool {
+	return phase != "" && phase != MIGRATION_PHASE_COMPLETED && phase != MIGRATION_PHASE_FAILED
+}
+
+// The mem storage does not support the read-only mode, and it would be emptied by the rollout
+func isReadOnlyRequired(migration *ar.ApicurioRegistryStatusPersistenceMigration) bool {
+	return migration.From != "mem"
+}
+
+func isDeploymentRolledOut(deployment *apps.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
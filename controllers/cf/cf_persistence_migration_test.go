@@ -0,0 +1,426 @@
+package cf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
+	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+func setupPersistenceMigrationTest(spec v1.ApicurioRegistrySpec, status v1.ApicurioRegistryStatus) (*context.LoopContextMock, loop.ControlLoop) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	controlLoop := loop_impl.NewControlLoopImpl(ctx, services)
+	controlLoop.AddControlFunction(NewPersistenceMigrationCF(ctx, services))
+
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
+		ObjectMeta: meta.ObjectMeta{Name: ctx.GetAppName().Str()},
+		Spec:       spec,
+	}))
+	ctx.GetResourceCache().Set(resources.RC_KEY_STATUS, resources.NewResourceCacheEntry(ctx.GetAppName(), &status))
+	ctx.GetResourceCache().Set(resources.RC_KEY_DEPLOYMENT, resources.NewResourceCacheEntry("deployment", &apps.Deployment{}))
+	return ctx, controlLoop
+}
+
+func getPersistence(ctx *context.LoopContextMock) (string, *v1.ApicurioRegistryStatusPersistence) {
+	specEntry, _ := ctx.GetResourceCache().Get(resources.RC_KEY_SPEC)
+	statusEntry, _ := ctx.GetResourceCache().Get(resources.RC_KEY_STATUS)
+	return specEntry.GetValue().(*v1.ApicurioRegistry).Spec.Configuration.Persistence,
+		statusEntry.GetValue().(*v1.ApicurioRegistryStatus).Persistence
+}
+
+func TestPersistenceMigrationDisabled(t *testing.T) {
+	ctx, controlLoop := setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{Persistence: "sql"},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{Type: "mem"},
+	})
+	controlLoop.Run()
+	persistence, status := getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, "sql", status.Type)
+	c.AssertEquals(t, (*v1.ApicurioRegistryStatusPersistenceMigration)(nil), status.Migration)
+}
+
+func TestPersistenceMigrationPinned(t *testing.T) {
+	// Storage is not configured
+	ctx, controlLoop := setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence:          "kafkasql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{Enabled: true},
+		},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{Type: "sql"},
+	})
+	controlLoop.Run()
+	persistence, status := getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, "sql", status.Type)
+
+	// Failed migrations are not retried
+	ctx, controlLoop = setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence: "kafkasql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{
+				Enabled: true,
+				Storage: v1.ApicurioRegistryBackupStorage{
+					Pvc: &v1.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+				},
+			},
+		},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{
+			Type:      "sql",
+			Migration: &v1.ApicurioRegistryStatusPersistenceMigration{Phase: MIGRATION_PHASE_FAILED, From: "sql", To: "kafkasql"},
+		},
+	})
+	controlLoop.Run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, MIGRATION_PHASE_FAILED, status.Migration.Phase)
+}
+
+func TestPersistenceMigrationCleanup(t *testing.T) {
+	ctx, controlLoop := setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence:          "kafkasql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{Enabled: true},
+		},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{
+			Type:      "kafkasql",
+			Migration: &v1.ApicurioRegistryStatusPersistenceMigration{Phase: MIGRATION_PHASE_CLEANING_UP, From: "sql", To: "kafkasql"},
+		},
+	})
+	setRolledOutDeployment(ctx)
+	ctx.GetEnvCache().Set(env.NewSimpleEnvCacheEntryBuilder("REGISTRY_DATASOURCE_URL", "jdbc:postgresql://db").
+		SetOwner("PersistenceMigrationCF").Build())
+
+	// The configuration of the previous storage is removed first
+	controlLoop.Run()
+	_, status := getPersistence(ctx)
+	c.AssertEquals(t, MIGRATION_PHASE_CLEANING_UP, status.Migration.Phase)
+	_, exists := ctx.GetEnvCache().Get("REGISTRY_DATASOURCE_URL")
+	c.AssertEquals(t, false, exists)
+
+	// Completed after the deployment has been rolled out
+	ctx.GetEnvCache().ProcessAndAdvanceToNextPeriod()
+	controlLoop.Run()
+	_, status = getPersistence(ctx)
+	c.AssertEquals(t, MIGRATION_PHASE_COMPLETED, status.Migration.Phase)
+	c.AssertEquals(t, true, status.Migration.CompletionTime != nil)
+}
+
+func TestPersistenceMigrationRollback(t *testing.T) {
+	t.Setenv(factory.ENV_REGISTRY_VERSION, "2.x")
+	t.Setenv(factory.ENV_OPERATOR_NAME, "apicurio-registry-operator")
+
+	ctx, controlLoop := setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence:          "kafkasql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{Enabled: true},
+		},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{
+			Type: "kafkasql",
+			Migration: &v1.ApicurioRegistryStatusPersistenceMigration{
+				Phase: MIGRATION_PHASE_IMPORTING, From: "sql", To: "kafkasql", BackupName: "migration", RestoreName: "migration",
+			},
+		},
+	})
+	fakeServer := setupFakeMigrationServer(t, ctx)
+	fakeServer.create(t, "apicurioregistryrestores", "migration", &v1.ApicurioRegistryRestore{
+		ObjectMeta: meta.ObjectMeta{Name: "migration"},
+		Status:     v1.ApicurioRegistryRestoreStatus{Phase: v1.RESTORE_PHASE_FAILED, Message: "import failed"},
+	})
+	// Adopted from SqlCF during the rollout
+	ctx.GetEnvCache().Set(env.NewSimpleEnvCacheEntryBuilder("REGISTRY_DATASOURCE_URL", "jdbc:postgresql://db").
+		SetOwner("PersistenceMigrationCF").Build())
+
+	// The spec is pinned to the previous storage again, and its configuration is released to SqlCF
+	controlLoop.Run()
+	persistence, status := getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, "sql", status.Type)
+	c.AssertEquals(t, MIGRATION_PHASE_FAILED, status.Migration.Phase)
+	c.AssertEquals(t, "could not import the data: import failed", status.Migration.Message)
+	c.AssertEquals(t, 0, len(ctx.GetEnvCache().GetByOwner("PersistenceMigrationCF")))
+}
+
+// Keeps the control loop running long enough for the migration CF to take several steps in one loop
+type idleCF struct{}
+
+func (this *idleCF) Describe() string { return "idleCF" }
+func (this *idleCF) Sense()           {}
+func (this *idleCF) Compare() bool    { return false }
+func (this *idleCF) Respond()         {}
+func (this *idleCF) Cleanup() bool    { return true }
+
+// Serves the ApicurioRegistryBackup and ApicurioRegistryRestore resources
+type fakeMigrationServer struct {
+	lock    sync.Mutex
+	objects map[string]map[string]json.RawMessage
+}
+
+func (this *fakeMigrationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	// /apis/registry.apicur.io/v1/namespaces/<namespace>/<resource>[/<name>]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) < 6 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resource := parts[5]
+	if this.objects[resource] == nil {
+		this.objects[resource] = make(map[string]json.RawMessage)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 7:
+		if object, exists := this.objects[resource][parts[6]]; exists {
+			w.Write(object)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(&meta.Status{
+			TypeMeta: meta.TypeMeta{Kind: "Status", APIVersion: "v1"},
+			Status:   meta.StatusFailure,
+			Reason:   meta.StatusReasonNotFound,
+			Code:     http.StatusNotFound,
+		})
+	case r.Method == http.MethodPost && len(parts) == 6:
+		object := &meta.PartialObjectMetadata{}
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || json.Unmarshal(body, object) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		this.objects[resource][object.Name] = body
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func setupFakeMigrationServer(t *testing.T, ctx *context.LoopContextMock) *fakeMigrationServer {
+	fakeServer := &fakeMigrationServer{objects: make(map[string]map[string]json.RawMessage)}
+	server := httptest.NewServer(fakeServer)
+	t.Cleanup(server.Close)
+	ctx.SetClients(client.NewClients(zap.NewNop(), runtime.NewScheme(), &rest.Config{Host: server.URL}, nil))
+	return fakeServer
+}
+
+func (this *fakeMigrationServer) create(t *testing.T, resource string, name string, value interface{}) {
+	t.Helper()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	object, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if this.objects[resource] == nil {
+		this.objects[resource] = make(map[string]json.RawMessage)
+	}
+	this.objects[resource][name] = object
+}
+
+func (this *fakeMigrationServer) exists(resource string, name string) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	_, exists := this.objects[resource][name]
+	return exists
+}
+
+func setRolledOutDeployment(ctx *context.LoopContextMock, env ...core.EnvVar) {
+	var replicas int32 = 1
+	ctx.GetResourceCache().Set(resources.RC_KEY_DEPLOYMENT, resources.NewResourceCacheEntry("deployment", &apps.Deployment{
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Template: core.PodTemplateSpec{
+				Spec: core.PodSpec{
+					Containers: []core.Container{{Name: factory.REGISTRY_CONTAINER_NAME, Env: env}},
+				},
+			},
+		},
+		Status: apps.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}))
+}
+
+func (this *fakeMigrationServer) update(t *testing.T, resource string, name string, value interface{}, modify func()) {
+	t.Helper()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	object, exists := this.objects[resource][name]
+	if !exists {
+		t.Fatalf("%s %s has not been created", resource, name)
+	}
+	if err := json.Unmarshal(object, value); err != nil {
+		t.Fatal(err)
+	}
+	modify()
+	object, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	this.objects[resource][name] = object
+}
+
+func TestPersistenceMigration(t *testing.T) {
+	t.Setenv(factory.ENV_REGISTRY_VERSION, "2.x")
+	t.Setenv(factory.ENV_OPERATOR_NAME, "apicurio-registry-operator")
+
+	spec := v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence: "kafkasql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{
+				Enabled: true,
+				Storage: v1.ApicurioRegistryBackupStorage{
+					Pvc: &v1.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+				},
+				RegistryAuth: v1.ApicurioRegistryBackupRegistryAuth{SecretName: "registry-credentials"},
+			},
+		},
+	}
+	ctx, controlLoop := setupPersistenceMigrationTest(spec, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{Type: "sql"},
+	})
+	controlLoop.AddControlFunction(&idleCF{})
+	fakeServer := setupFakeMigrationServer(t, ctx)
+	setRolledOutDeployment(ctx)
+	envCache := ctx.GetEnvCache()
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("REGISTRY_DATASOURCE_URL", "jdbc:postgresql://db").SetOwner("SqlCF").Build())
+
+	run := func() {
+		t.Helper()
+		// The spec is reloaded before each loop
+		ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
+			ObjectMeta: meta.ObjectMeta{Name: ctx.GetAppName().Str()},
+			Spec:       spec,
+		}))
+		controlLoop.Run()
+		envCache.ProcessAndAdvanceToNextPeriod()
+	}
+	getOwner := func(name string) string {
+		t.Helper()
+		if entry, exists := envCache.Get(name); exists {
+			return entry.GetOwner()
+		}
+		return ""
+	}
+
+	// Exporting, the existing storage is kept and made read-only
+	run()
+	persistence, status := getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, "sql", status.Type)
+	c.AssertEquals(t, MIGRATION_PHASE_EXPORTING, status.Migration.Phase)
+	c.AssertEquals(t, "sql", status.Migration.From)
+	c.AssertEquals(t, "kafkasql", status.Migration.To)
+	c.AssertEquals(t, "PersistenceMigrationCF", getOwner(ENV_REGISTRY_STORAGE_READ_ONLY))
+	c.AssertEquals(t, false, fakeServer.exists("apicurioregistrybackups", status.Migration.BackupName))
+
+	// The data is exported after the read-only deployment has been rolled out, the spec is pinned again
+	setRolledOutDeployment(ctx, core.EnvVar{Name: ENV_REGISTRY_STORAGE_READ_ONLY, Value: "true"})
+	run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "sql", persistence)
+	c.AssertEquals(t, MIGRATION_PHASE_EXPORTING, status.Migration.Phase)
+	requeue, _ := ctx.GetAndResetRequeue()
+	c.AssertEquals(t, true, requeue)
+
+	// RollingOut, the new storage is configured, and the configuration of the previous storage is kept
+	backup := &v1.ApicurioRegistryBackup{}
+	fakeServer.update(t, "apicurioregistrybackups", status.Migration.BackupName, backup, func() {
+		c.AssertEquals(t, spec.Configuration.PersistenceMigration.Storage, backup.Spec.Storage)
+		c.AssertEquals(t, spec.Configuration.PersistenceMigration.RegistryAuth, backup.Spec.RegistryAuth)
+		backup.Status.LastBackup = &v1.ApicurioRegistryBackupStatusEntry{Result: v1.BACKUP_RESULT_SUCCEEDED}
+	})
+	run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "kafkasql", persistence)
+	c.AssertEquals(t, "kafkasql", status.Type)
+	c.AssertEquals(t, MIGRATION_PHASE_ROLLING_OUT, status.Migration.Phase)
+	c.AssertEquals(t, "", getOwner(ENV_REGISTRY_STORAGE_READ_ONLY))
+	c.AssertEquals(t, "PersistenceMigrationCF", getOwner("REGISTRY_DATASOURCE_URL"))
+	c.AssertEquals(t, 0, len(envCache.GetByOwner("SqlCF")))
+
+	// Importing, after the deployment has been rolled out
+	setRolledOutDeployment(ctx)
+	run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "kafkasql", persistence)
+	c.AssertEquals(t, MIGRATION_PHASE_IMPORTING, status.Migration.Phase)
+	c.AssertEquals(t, status.Migration.BackupName, status.Migration.RestoreName)
+	c.AssertEquals(t, "PersistenceMigrationCF", getOwner("REGISTRY_DATASOURCE_URL"))
+
+	// CleaningUp, the configuration of the previous storage is removed
+	restore := &v1.ApicurioRegistryRestore{}
+	fakeServer.update(t, "apicurioregistryrestores", status.Migration.RestoreName, restore, func() {
+		c.AssertEquals(t, status.Migration.BackupName, restore.Spec.Source.Backup.Name)
+		c.AssertEquals(t, spec.Configuration.PersistenceMigration.RegistryAuth, restore.Spec.RegistryAuth)
+		restore.Status.Phase = v1.RESTORE_PHASE_SUCCEEDED
+	})
+	run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "kafkasql", persistence)
+	c.AssertEquals(t, MIGRATION_PHASE_CLEANING_UP, status.Migration.Phase)
+	c.AssertEquals(t, "", getOwner("REGISTRY_DATASOURCE_URL"))
+
+	// Completed
+	run()
+	persistence, status = getPersistence(ctx)
+	c.AssertEquals(t, "kafkasql", persistence)
+	c.AssertEquals(t, "kafkasql", status.Type)
+	c.AssertEquals(t, MIGRATION_PHASE_COMPLETED, status.Migration.Phase)
+	c.AssertEquals(t, true, status.Migration.CompletionTime != nil)
+}
+
+func TestPersistenceMigrationFromMem(t *testing.T) {
+	t.Setenv(factory.ENV_REGISTRY_VERSION, "2.x")
+	t.Setenv(factory.ENV_OPERATOR_NAME, "apicurio-registry-operator")
+
+	ctx, controlLoop := setupPersistenceMigrationTest(v1.ApicurioRegistrySpec{
+		Configuration: v1.ApicurioRegistrySpecConfiguration{
+			Persistence: "sql",
+			PersistenceMigration: v1.ApicurioRegistrySpecConfigurationPersistenceMigration{
+				Enabled: true,
+				Storage: v1.ApicurioRegistryBackupStorage{
+					Pvc: &v1.ApicurioRegistryBackupStoragePvc{ClaimName: "backups"},
+				},
+			},
+		},
+	}, v1.ApicurioRegistryStatus{
+		Persistence: &v1.ApicurioRegistryStatusPersistence{Type: "mem"},
+	})
+	fakeServer := setupFakeMigrationServer(t, ctx)
+
+	// The mem storage can not be made read-only, the data is exported immediately
+	controlLoop.Run()
+	_, status := getPersistence(ctx)
+	c.AssertEquals(t, MIGRATION_PHASE_EXPORTING, status.Migration.Phase)
+	c.AssertEquals(t, true, fakeServer.exists("apicurioregistrybackups", status.Migration.BackupName))
+	c.AssertEquals(t, true, strings.Contains(status.Migration.Message, "not migrated"))
+	_, exists := ctx.GetEnvCache().Get(ENV_REGISTRY_STORAGE_READ_ONLY)
+	c.AssertEquals(t, false, exists)
+}
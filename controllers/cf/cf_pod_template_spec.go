@@ -180,6 +180,12 @@ func SanitizeBasePodSpec(log *zap.SugaredLogger, base *ar.ApicurioRegistryPodTem
 		}
 		baseContainer.Env = currentContainer.Env
 
+		// spec.containers[name = "registry"].envFrom
+		if len(baseContainer.EnvFrom) != 0 {
+			return nil, newReservedFieldError("spec.containers[name = \"registry\"].envFrom")
+		}
+		baseContainer.EnvFrom = currentContainer.EnvFrom
+
 		// spec.containers[name = "registry"].image
 		if baseContainer.Image != "" {
 			return nil, newReservedFieldError("spec.containers[name = \"registry\"].image")
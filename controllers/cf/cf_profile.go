@@ -46,7 +46,9 @@ func (this *ProfileCF) Compare() bool {
 func (this *ProfileCF) Respond() {
 	// Response #1
 	// Just set the value(s)!
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_QUARKUS_PROFILE, "prod").Build())
+	if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_QUARKUS_PROFILE, "prod").SetOwner(this.Describe()).Build()); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+	}
 
 }
 
@@ -0,0 +1,267 @@
+package cf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+var _ loop.ControlFunction = &PropertiesCF{}
+
+const (
+	PropertiesVolumeName = "registry-properties"
+	PropertiesKey        = "application.properties"
+	// Quarkus reads this file with a higher precedence than the application.properties packaged in the application
+	PropertiesMountPath = "/deployments/config/" + PropertiesKey
+	// Changes to the pod template annotation roll the pods when the content of the ConfigMap changes
+	PropertiesHashAnnotation = "apicur.io/properties-hash"
+)
+
+// This CF mounts the `application.properties` file from the ConfigMap referenced in `spec.configuration.propertiesConfigMapRef`.
+type PropertiesCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+
+	deploymentExists bool
+	deploymentEntry  resources.ResourceCacheEntry
+
+	targetConfigMapName string
+	configMapValid      bool
+	targetHash          string
+	propertyKeys        []string
+
+	existingConfigMapName string
+	mountExists           bool
+	existingHash          string
+
+	overridden []string
+}
+
+func NewPropertiesCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &PropertiesCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *PropertiesCF) Describe() string {
+	return "PropertiesCF"
+}
+
+func (this *PropertiesCF) Sense() {
+	this.targetConfigMapName = ""
+	this.configMapValid = false
+	this.targetHash = ""
+	this.propertyKeys = nil
+	this.existingConfigMapName = ""
+	this.mountExists = false
+	this.existingHash = ""
+	this.overridden = nil
+
+	// Observation #1
+	// Read the config values
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		if ref := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.PropertiesConfigMapRef; ref != nil {
+			this.targetConfigMapName = ref.Name
+		}
+	}
+
+	// Observation #2
+	// Read the ConfigMap, it must contain the properties file
+	if this.targetConfigMapName != "" {
+		configMap, err := this.ctx.GetClients().Kube().GetConfigMap(this.ctx.GetAppNamespace(), common.Name(this.targetConfigMapName))
+		if err != nil {
+			this.log.Warnw("could not read the properties ConfigMap", "name", this.targetConfigMapName, "error", err)
+		} else if data, exists := configMap.Data[PropertiesKey]; exists {
+			this.configMapValid = true
+			hash := sha256.Sum256([]byte(data))
+			this.targetHash = hex.EncodeToString(hash[:])
+			this.propertyKeys = parsePropertyKeys(data)
+		}
+	}
+
+	// Observation #3
+	// Read the existing volume, mount, and hash
+	this.deploymentEntry, this.deploymentExists = this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	if this.deploymentExists {
+		deployment := this.deploymentEntry.GetValue().(*apps.Deployment)
+		for _, v := range deployment.Spec.Template.Spec.Volumes {
+			if v.Name == PropertiesVolumeName && v.ConfigMap != nil {
+				this.existingConfigMapName = v.ConfigMap.Name
+			}
+		}
+		if container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME); container != nil {
+			for _, m := range container.VolumeMounts {
+				if m.Name == PropertiesVolumeName {
+					this.mountExists = true
+				}
+			}
+		}
+		this.existingHash = deployment.Spec.Template.Annotations[PropertiesHashAnnotation]
+	}
+
+	// Observation #4
+	// Find the properties overridden by the operator-managed env. variables, once per loop.
+	// The other env. CFs have already been executed.
+	if this.ctx.GetAttempts() == 0 && this.configMapValid {
+		this.overridden = findOverriddenProperties(this.svcEnvCache, this.propertyKeys)
+	}
+}
+
+func (this *PropertiesCF) Compare() bool {
+	// Condition #1
+	// The properties file has to be mounted or updated
+	// Condition #2
+	// The properties file has to be removed
+	// Condition #3
+	// Report problems once per loop
+	return (this.deploymentExists && this.configMapValid && (this.existingConfigMapName != this.targetConfigMapName ||
+		!this.mountExists || this.existingHash != this.targetHash)) ||
+		(this.deploymentExists && this.targetConfigMapName == "" && (this.existingConfigMapName != "" ||
+			this.mountExists || this.existingHash != "")) ||
+		(this.ctx.GetAttempts() == 0 && ((this.targetConfigMapName != "" && !this.configMapValid) || len(this.overridden) > 0))
+}
+
+func (this *PropertiesCF) Respond() {
+	volume := &core.Volume{
+		Name: PropertiesVolumeName,
+		VolumeSource: core.VolumeSource{
+			ConfigMap: &core.ConfigMapVolumeSource{
+				LocalObjectReference: core.LocalObjectReference{
+					Name: this.targetConfigMapName,
+				},
+				Items: []core.KeyToPath{
+					{
+						Key:  PropertiesKey,
+						Path: PropertiesKey,
+					},
+				},
+			},
+		},
+	}
+	// Mount only the file, so the other content of the directory is kept
+	mount := &core.VolumeMount{
+		Name:      PropertiesVolumeName,
+		ReadOnly:  true,
+		MountPath: PropertiesMountPath,
+		SubPath:   PropertiesKey,
+	}
+
+	// Response #1
+	// Mount the properties file, the hash annotation rolls the pods when the content changes
+	if this.deploymentExists && this.configMapValid {
+		this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+			deployment := value.(*apps.Deployment).DeepCopy()
+			common.SetVolumeInDeployment(deployment, volume)
+			if container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME); container != nil {
+				common.AddVolumeMountToContainer(container, mount)
+			}
+			if deployment.Spec.Template.Annotations == nil {
+				deployment.Spec.Template.Annotations = make(map[string]string)
+			}
+			deployment.Spec.Template.Annotations[PropertiesHashAnnotation] = this.targetHash
+			return deployment
+		})
+	}
+
+	// Response #2
+	// Remove the properties file
+	if this.deploymentExists && this.targetConfigMapName == "" {
+		this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+			deployment := value.(*apps.Deployment).DeepCopy()
+			common.RemoveVolumeFromDeployment(deployment, volume)
+			if container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME); container != nil {
+				common.RemoveVolumeMountFromContainer(container, mount)
+			}
+			delete(deployment.Spec.Template.Annotations, PropertiesHashAnnotation)
+			return deployment
+		})
+	}
+
+	// Response #3
+	// Report problems
+	if this.targetConfigMapName != "" && !this.configMapValid {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(
+			"ConfigMap "+this.targetConfigMapName+" with the "+PropertiesKey+" key not found",
+			"spec.configuration.propertiesConfigMapRef")
+		this.ctx.SetRequeueDelaySec(10)
+	}
+	if len(this.overridden) > 0 {
+		this.services.GetConditionManager().GetConfigurationWarningCondition().TransitionPropertyOverridden(this.overridden)
+	}
+}
+
+func (this *PropertiesCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Return the keys of a Java properties file
+func parsePropertyKeys(data string) []string {
+	res := make([]string, 0)
+	continuation := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		wasContinuation := continuation
+		continuation = strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\")
+		if wasContinuation || line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key := line
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' {
+				key = line[:i]
+				break
+			}
+		}
+		res = append(res, key)
+	}
+	return res
+}
+
+// Return the properties that are overridden by an operator-managed env. variable,
+// using the Quarkus env. variable naming convention
+func findOverriddenProperties(envCache env.EnvCache, keys []string) []string {
+	res := make([]string, 0)
+	for _, k := range keys {
+		if entry, exists := envCache.Get(propertyToEnvName(k)); exists && isOperatorManagedEnv(entry) {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func propertyToEnvName(property string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, property)
+}
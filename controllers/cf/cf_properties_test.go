@@ -0,0 +1,39 @@
+package cf
+
+import (
+	"testing"
+
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"go.uber.org/zap"
+)
+
+func TestPropertiesOverridden(t *testing.T) {
+	keys := parsePropertyKeys(`
+# Comment
+! Comment
+registry.datasource.url=jdbc:postgresql://db:5432/registry
+quarkus.log.level: DEBUG
+registry.rules.global.validity FULL
+registry.ccompat.legacy-id-mode.enabled = true \
+  registry.not.a.key=value
+key\=with\:escapes=value
+`)
+	c.AssertEquals(t, []string{
+		"registry.datasource.url",
+		"quarkus.log.level",
+		"registry.rules.global.validity",
+		"registry.ccompat.legacy-id-mode.enabled",
+		"key\\=with\\:escapes",
+	}, keys)
+
+	c.AssertEquals(t, "REGISTRY_CCOMPAT_LEGACY_ID_MODE_ENABLED", propertyToEnvName("registry.ccompat.legacy-id-mode.enabled"))
+	c.AssertEquals(t, "_DEV_QUARKUS_HTTP_PORT", propertyToEnvName("%dev.quarkus.http.port"))
+
+	envCache := env.NewEnvCache(zap.NewNop())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_URL, "jdbc:postgresql://other:5432/registry").
+		SetOwner("SqlCF").Build())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("QUARKUS_LOG_LEVEL", "INFO").
+		SetPriority(env.PRIORITY_SPEC).Build())
+	c.AssertEquals(t, []string{"registry.datasource.url"}, findOverriddenProperties(envCache, keys))
+}
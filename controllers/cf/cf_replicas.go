@@ -14,6 +14,7 @@ var _ loop.ControlFunction = &ReplicasCF{}
 
 type ReplicasCF struct {
 	ctx              context.LoopContext
+	services         services.LoopServices
 	svcResourceCache resources.ResourceCache
 	svcStatus        *status.Status
 	deploymentEntry  resources.ResourceCacheEntry
@@ -28,6 +29,7 @@ type ReplicasCF struct {
 func NewReplicasCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &ReplicasCF{
 		ctx:              ctx,
+		services:         services,
 		svcResourceCache: ctx.GetResourceCache(),
 		svcStatus:        services.GetStatus(),
 		deploymentEntry:  nil,
@@ -65,6 +67,14 @@ func (this *ReplicasCF) Sense() {
 		this.targetReplicas = 1
 	}
 
+	// Observation #4
+	// Do not start the deployment until the storage is ready (see KafkasqlTopicCF),
+	// but do not stop a running deployment
+	if this.services.GetConditionManager().GetStorageReadyCondition().IsBlockingDeployment() &&
+		this.deploymentExists && (this.deploymentEntry.GetName() == resources.RC_NOT_CREATED_NAME_EMPTY || this.existingReplicas == 0) {
+		this.targetReplicas = 0
+	}
+
 	// Update state
 	this.svcStatus.SetConfigInt32P(status.CFG_STA_REPLICA_COUNT, &this.existingReplicas)
 }
@@ -1,11 +1,27 @@
 package cf
 
 import (
+	"crypto/x509"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ loop.ControlFunction = &SqlCF{}
@@ -14,23 +30,95 @@ const ENV_REGISTRY_DATASOURCE_URL = "REGISTRY_DATASOURCE_URL"
 const ENV_REGISTRY_DATASOURCE_USERNAME = "REGISTRY_DATASOURCE_USERNAME"
 const ENV_REGISTRY_DATASOURCE_PASSWORD = "REGISTRY_DATASOURCE_PASSWORD"
 
+// Connection pool configuration, the size is configured using the Apicurio Registry properties
+const ENV_REGISTRY_DATASOURCE_JDBC_INITIAL_SIZE = "REGISTRY_DATASOURCE_JDBC_INITIAL_SIZE"
+const ENV_REGISTRY_DATASOURCE_JDBC_MIN_SIZE = "REGISTRY_DATASOURCE_JDBC_MIN_SIZE"
+const ENV_REGISTRY_DATASOURCE_JDBC_MAX_SIZE = "REGISTRY_DATASOURCE_JDBC_MAX_SIZE"
+const ENV_QUARKUS_DATASOURCE_JDBC_ACQUISITION_TIMEOUT = "QUARKUS_DATASOURCE_JDBC_ACQUISITION_TIMEOUT"
+const ENV_QUARKUS_DATASOURCE_JDBC_LEAK_DETECTION_INTERVAL = "QUARKUS_DATASOURCE_JDBC_LEAK_DETECTION_INTERVAL"
+
+const (
+	SqlTlsCaVolumeName     = "registry-sql-tls-ca"
+	SqlTlsCaMountPath      = "/etc/" + SqlTlsCaVolumeName
+	SqlTlsClientVolumeName = "registry-sql-tls-client"
+	SqlTlsClientMountPath  = "/etc/" + SqlTlsClientVolumeName
+
+	SqlTlsCaKey          = "ca.crt"
+	SqlTlsCertificateKey = "tls.crt"
+	// The PostgreSQL JDBC driver requires the key in the PKCS #8 DER format,
+	// cert-manager stores it under this key when the DER additional output format is enabled
+	SqlTlsPrivateKeyKey = "key.der"
+
+	sqlJdbcUrlPrefix        = "jdbc:postgresql:"
+	sqlDataSourceOptionPath = "spec.configuration.sql.dataSource"
+	sqlTlsOptionPath        = "spec.configuration.sql.dataSource.tls"
+	sqlDefaultSslMode       = "require"
+	sqlDefaultSslModeTls    = "verify-full"
+
+	sqlPoolOptionPath           = "spec.configuration.sql.pool"
+	sqlJdbcPropertiesOptionPath = "spec.configuration.sql.jdbcProperties"
+	// Defaults of Apicurio Registry
+	sqlDefaultPoolMinSize = 20
+	sqlDefaultPoolMaxSize = 100
+)
+
+var sqlJdbcPropertyRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
+
+// JDBC properties configured using the dedicated options
+var sqlReservedJdbcProperties = []string{"user", "password"}
+
+// Modes supported by the PostgreSQL JDBC driver
+var sqlSslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// JDBC URL parameters managed by the TLS configuration, they are removed from the configured URL
+var sqlTlsUrlParameters = []string{"ssl", "sslmode", "sslrootcert", "sslcert", "sslkey", "sslpassword", "sslfactory"}
+
 type SqlCF struct {
 	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
 	svcResourceCache resources.ResourceCache
 	svcEnvCache      env.EnvCache
 	persistence      string
 	url              string
 	user             string
 	password         string
+	userRef          *core.SecretKeySelector
+	passwordRef      *core.SecretKeySelector
 	valid            bool
 	envUrl           string
 	envUser          string
 	envPassword      string
+	envUserRef       *core.SecretKeySelector
+	envPasswordRef   *core.SecretKeySelector
+	staleEnv         bool
+
+	// The last resolved connection is kept while waiting for the Postgres operator
+	postgresResolved   bool
+	postgresConnection sqlPostgresConnection
+	postgresWaiting    string
+
+	tls                  ar.ApicurioRegistrySpecConfigurationDataSourceTls
+	requiredOption       string
+	invalidOptions       []string
+	invalidDetails       []string
+	targetPoolEnv        map[string]string
+	existingPoolEnv      map[string]string
+	stalePoolEnv         []string
+	deploymentExists     bool
+	deploymentEntry      resources.ResourceCacheEntry
+	targetCaSecretName   string
+	targetClientSecret   string
+	existingCaSecretName string
+	existingClientSecret string
+	// Details of the invalid client certificate Secret, read once per loop
+	clientSecretDetails string
 }
 
-func NewSqlCF(ctx context.LoopContext) loop.ControlFunction {
-	return &SqlCF{
+func NewSqlCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &SqlCF{
 		ctx:              ctx,
+		services:         services,
 		svcResourceCache: ctx.GetResourceCache(),
 		svcEnvCache:      ctx.GetEnvCache(),
 		persistence:      "",
@@ -41,7 +129,10 @@ func NewSqlCF(ctx context.LoopContext) loop.ControlFunction {
 		envUrl:           "",
 		envUser:          "",
 		envPassword:      "",
+		staleEnv:         false,
 	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
 }
 
 func (this *SqlCF) Describe() string {
@@ -49,6 +140,21 @@ func (this *SqlCF) Describe() string {
 }
 
 func (this *SqlCF) Sense() {
+	this.tls = ar.ApicurioRegistrySpecConfigurationDataSourceTls{}
+	this.requiredOption = ""
+	this.userRef = nil
+	this.passwordRef = nil
+	this.invalidOptions = nil
+	this.invalidDetails = nil
+	this.targetPoolEnv = make(map[string]string)
+	this.existingPoolEnv = make(map[string]string)
+	this.stalePoolEnv = nil
+	var pool ar.ApicurioRegistrySpecConfigurationSqlPool
+	var jdbcProperties map[string]string
+	var postgresClusterRef *ar.ApicurioRegistrySpecConfigurationSqlPostgresClusterRef
+	this.targetCaSecretName = ""
+	this.targetClientSecret = ""
+
 	// Observation #1
 	// Read the config values
 	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
@@ -58,26 +164,122 @@ func (this *SqlCF) Sense() {
 		this.user = spec.Configuration.Sql.DataSource.UserName
 		this.password = spec.Configuration.Sql.DataSource.Password // Leave empty as default
 		// TODO Use secrets!
+		this.tls = spec.Configuration.Sql.DataSource.Tls
+		pool = spec.Configuration.Sql.Pool
+		jdbcProperties = spec.Configuration.Sql.JdbcProperties
+		postgresClusterRef = spec.Configuration.Sql.PostgresClusterRef
 	}
 
-	// Observation #2 + #3
+	// Observation #2
+	// Resolve the referenced PostgreSQL cluster once per loop, the result is kept for the next attempts
+	if this.persistence != "sql" || postgresClusterRef == nil {
+		this.postgresResolved = false
+	} else {
+		if this.ctx.GetAttempts() == 0 {
+			this.postgresWaiting = ""
+			this.readPostgresCluster(*postgresClusterRef)
+		}
+		if this.postgresResolved {
+			if this.url != "" || this.user != "" || this.password != "" {
+				// The manual configuration is used
+				this.addInvalid(sqlPostgresClusterRefOptionPath,
+					"url, userName, and password of the data source can not be configured together with postgresClusterRef")
+			} else {
+				this.url = this.postgresConnection.url
+				this.userRef = &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{Name: this.postgresConnection.secretName},
+					Key:                  this.postgresConnection.userKey,
+				}
+				this.passwordRef = &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{Name: this.postgresConnection.secretName},
+					Key:                  this.postgresConnection.passwordKey,
+				}
+			}
+		}
+	}
+
+	// Observation #3 + #4
 	// Is the correct persistence type selected?
 	// Validate the config values
-	this.valid = this.persistence == "sql" && this.url != "" && this.user != ""
+	// Invalid optional values are skipped
+	this.valid = this.persistence == "sql" && this.url != "" && (this.user != "" || this.userRef != nil)
+	if this.valid {
+		var removedParameters []string
+		var parameters [][2]string
+		tlsConfigured := false
+		if this.tls != (ar.ApicurioRegistrySpecConfigurationDataSourceTls{}) {
+			var tlsInvalidDetails string
+			this.requiredOption, tlsInvalidDetails = validateSqlTls(this.tls, this.url)
+			if tlsInvalidDetails != "" {
+				this.addInvalid(sqlTlsOptionPath, tlsInvalidDetails)
+			}
+			if this.requiredOption == "" && tlsInvalidDetails == "" && this.tls.ClientCertificateSecretName != "" {
+				if this.ctx.GetAttempts() == 0 {
+					this.clientSecretDetails = this.readSqlTlsClientSecret(this.tls.ClientCertificateSecretName)
+				}
+				if tlsInvalidDetails = this.clientSecretDetails; tlsInvalidDetails != "" {
+					this.addInvalid(sqlTlsOptionPath+".clientCertificateSecretName", tlsInvalidDetails)
+				}
+			}
+			if this.requiredOption == "" && tlsInvalidDetails == "" {
+				tlsConfigured = true
+				removedParameters = sqlTlsUrlParameters
+				parameters = sqlTlsUrlParameterValues(this.tls)
+				this.targetCaSecretName = this.tls.CaSecretName
+				this.targetClientSecret = this.tls.ClientCertificateSecretName
+			}
+		}
+		parameters = append(parameters, this.getJdbcPropertyParameters(jdbcProperties, tlsConfigured)...)
+		if len(parameters) > 0 {
+			this.url = rewriteSqlJdbcUrl(this.url, removedParameters, parameters)
+		}
+		this.addPoolEnv(pool)
+	}
 
-	// Observation #4
+	// Observation #5
 	// Read the env values
+	this.envUrl = ""
 	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_DATASOURCE_URL); exists {
 		this.envUrl = val.GetValue().Value
 	}
-	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_DATASOURCE_USERNAME); exists {
-		this.envUser = val.GetValue().Value
+	this.envUser, this.envUserRef = this.readSqlEnv(ENV_REGISTRY_DATASOURCE_USERNAME)
+	this.envPassword, this.envPasswordRef = this.readSqlEnv(ENV_REGISTRY_DATASOURCE_PASSWORD)
+
+	for name := range this.targetPoolEnv {
+		if val, exists := this.svcEnvCache.Get(name); exists {
+			this.existingPoolEnv[name] = val.GetValue().Value
+		}
 	}
-	if val, exists := this.svcEnvCache.Get(ENV_REGISTRY_DATASOURCE_PASSWORD); exists {
-		this.envPassword = val.GetValue().Value
+
+	// Observation #6
+	// Are there env values set by this CF that are no longer needed?
+	this.staleEnv = !this.valid && len(this.svcEnvCache.GetByOwner(this.Describe())) > 0
+	if this.valid {
+		for _, entry := range this.svcEnvCache.GetByOwner(this.Describe()) {
+			name := entry.GetName()
+			if _, exists := this.targetPoolEnv[name]; !exists && name != ENV_REGISTRY_DATASOURCE_URL &&
+				name != ENV_REGISTRY_DATASOURCE_USERNAME && name != ENV_REGISTRY_DATASOURCE_PASSWORD {
+				this.stalePoolEnv = append(this.stalePoolEnv, name)
+			}
+		}
 	}
 
-	// We won't actively delete old env values if not used
+	// Observation #7
+	// Read the mounted TLS Secrets
+	this.existingCaSecretName = ""
+	this.existingClientSecret = ""
+	this.deploymentEntry, this.deploymentExists = this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	if this.deploymentExists {
+		deployment := this.deploymentEntry.GetValue().(*apps.Deployment)
+		for _, v := range deployment.Spec.Template.Spec.Volumes {
+			if v.Name == SqlTlsCaVolumeName && v.Secret != nil {
+				this.existingCaSecretName = v.Secret.SecretName
+			}
+			if v.Name == SqlTlsClientVolumeName && v.Secret != nil {
+				this.existingClientSecret = v.Secret.SecretName
+			}
+		}
+	}
 }
 
 func (this *SqlCF) Compare() bool {
@@ -85,21 +287,426 @@ func (this *SqlCF) Compare() bool {
 	// Is SQL & config values are valid
 	// Condition #2 + #3
 	// The required env vars are not present OR they differ
-	return this.valid && (this.url != this.envUrl ||
+	// Condition #4
+	// Stale env vars have to be removed
+	// Condition #5
+	// The TLS Secrets have to be mounted or removed
+	// Condition #6
+	// Report invalid configuration, or waiting for the Postgres operator, once per loop
+	return (this.valid && (this.url != this.envUrl ||
 		this.user != this.envUser ||
-		this.password != this.envPassword)
+		this.password != this.envPassword ||
+		!isSecretKeyRefEqual(this.userRef, this.envUserRef) ||
+		!isSecretKeyRefEqual(this.passwordRef, this.envPasswordRef) ||
+		!isEnvEqual(this.targetPoolEnv, this.existingPoolEnv))) ||
+		this.staleEnv || len(this.stalePoolEnv) > 0 ||
+		(this.deploymentExists && (this.targetCaSecretName != this.existingCaSecretName ||
+			this.targetClientSecret != this.existingClientSecret)) ||
+		((this.requiredOption != "" || len(this.invalidOptions) > 0 || this.postgresWaiting != "") && this.ctx.GetAttempts() == 0)
 }
 
 func (this *SqlCF) Respond() {
 	// Response #1
 	// Just set the value(s)!
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_URL, this.url).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_USERNAME, this.user).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_PASSWORD, this.password).Build())
+	if this.valid {
+		this.setEnv(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_URL, this.url), sqlDataSourceOptionPath+".url")
+		this.setEnv(env.NewEnvCacheEntryBuilder(sqlEnv(ENV_REGISTRY_DATASOURCE_USERNAME, this.user, this.userRef)),
+			sqlDataSourceOptionPath+".userName")
+		this.setEnv(env.NewEnvCacheEntryBuilder(sqlEnv(ENV_REGISTRY_DATASOURCE_PASSWORD, this.password, this.passwordRef)),
+			sqlDataSourceOptionPath+".password")
+		for name, value := range this.targetPoolEnv {
+			if existing, exists := this.existingPoolEnv[name]; !exists || existing != value {
+				this.setEnv(env.NewSimpleEnvCacheEntryBuilder(name, value), sqlPoolOptionPath)
+			}
+		}
+	}
+
+	// Response #2
+	// Remove the stale value(s)
+	if this.staleEnv {
+		this.svcEnvCache.DeleteByOwner(this.Describe())
+	}
+	for _, name := range this.stalePoolEnv {
+		this.svcEnvCache.DeleteByName(name)
+	}
+
+	// Response #3
+	// Mount or remove the TLS Secrets
+	if this.deploymentExists && (this.targetCaSecretName != this.existingCaSecretName ||
+		this.targetClientSecret != this.existingClientSecret) {
+		this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+			deployment := value.(*apps.Deployment).DeepCopy()
+			setSqlTlsSecretVolume(deployment, SqlTlsCaVolumeName, SqlTlsCaMountPath, this.targetCaSecretName)
+			setSqlTlsSecretVolume(deployment, SqlTlsClientVolumeName, SqlTlsClientMountPath, this.targetClientSecret)
+			return deployment
+		})
+	}
+
+	// Response #4
+	// Report the missing or the first invalid value
+	if this.ctx.GetAttempts() == 0 {
+		if this.requiredOption != "" {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionRequired(this.requiredOption)
+		}
+		if len(this.invalidOptions) > 0 {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().
+				TransitionInvalid(this.invalidDetails[0], this.invalidOptions[0])
+		}
+		if this.postgresWaiting != "" {
+			this.log.Infow("waiting for the Postgres operator", "details", this.postgresWaiting)
+			this.ctx.SetRequeueDelaySec(10)
+		}
+	}
+}
+
+// Read the referenced PostgreSQL cluster and check that the Secret with the credentials exists
+func (this *SqlCF) readPostgresCluster(ref ar.ApicurioRegistrySpecConfigurationSqlPostgresClusterRef) {
+	if ref.Name == "" {
+		this.requiredOption = sqlPostgresClusterRefOptionPath + ".name"
+		this.postgresResolved = false
+		return
+	}
+	namespace := this.ctx.GetAppNamespace()
+	var connection sqlPostgresConnection
+	switch ref.Kind {
+	case SqlPostgresClusterKindCnpg:
+		if !this.ctx.GetSupportedFeatures().SupportsCloudNativePG {
+			this.addPostgresInvalid("CloudNativePG is not installed in the cluster, restart the operator after installing it")
+			return
+		}
+		cluster, err := this.ctx.GetClients().Postgres().GetCnpgCluster(namespace, common.Name(ref.Name))
+		if err != nil {
+			this.handlePostgresReadError(ref.Kind, ref.Name, err)
+			return
+		}
+		connection = resolveCnpgCluster(cluster, namespace)
+	case SqlPostgresClusterKindPgo:
+		if !this.ctx.GetSupportedFeatures().SupportsCrunchyPostgres {
+			this.addPostgresInvalid("Crunchy Postgres for Kubernetes is not installed in the cluster, restart the operator after installing it")
+			return
+		}
+		cluster, err := this.ctx.GetClients().Postgres().GetPgoPostgresCluster(namespace, common.Name(ref.Name))
+		if err != nil {
+			this.handlePostgresReadError(ref.Kind, ref.Name, err)
+			return
+		}
+		var details string
+		if connection, details = resolvePgoPostgresCluster(cluster, namespace); details != "" {
+			this.addPostgresInvalid(details)
+			return
+		}
+	default:
+		this.addPostgresInvalid("unsupported kind '" + ref.Kind + "', supported values are " +
+			SqlPostgresClusterKindCnpg + " (CloudNativePG) and " + SqlPostgresClusterKindPgo + " (PGO)")
+		return
+	}
+	// The Secret is created by the Postgres operator after the cluster
+	if _, err := this.ctx.GetClients().Kube().GetSecret(namespace, common.Name(connection.secretName), &meta.GetOptions{}); err != nil {
+		if api_errors.IsNotFound(err) {
+			this.postgresWaiting = "Secret " + connection.secretName + " not found"
+		} else {
+			this.log.Warnw("could not read the Secret with the database credentials", "secret", connection.secretName, "error", err)
+			this.postgresWaiting = "could not read Secret " + connection.secretName
+		}
+		return
+	}
+	this.postgresConnection = connection
+	this.postgresResolved = true
+}
+
+func (this *SqlCF) addPostgresInvalid(details string) {
+	this.addInvalid(sqlPostgresClusterRefOptionPath, details)
+	this.postgresResolved = false
+}
+
+func (this *SqlCF) handlePostgresReadError(kind string, name string, err error) {
+	if api_errors.IsNotFound(err) {
+		this.addPostgresInvalid(kind + " " + name + " not found")
+	} else {
+		this.log.Warnw("could not read the PostgreSQL cluster", "kind", kind, "name", name, "error", err)
+		this.postgresWaiting = "could not read " + kind + " " + name
+	}
+}
+
+// Read the client certificate Secret, and return details if it can not be used by the PostgreSQL JDBC driver.
+// The Secret is validated only if it can be read, otherwise the Deployment reports the problem.
+func (this *SqlCF) readSqlTlsClientSecret(name string) string {
+	secret, err := this.ctx.GetClients().Kube().GetSecret(this.ctx.GetAppNamespace(), common.Name(name), &meta.GetOptions{})
+	if err != nil {
+		if api_errors.IsNotFound(err) {
+			return "Secret " + name + " not found"
+		}
+		this.log.Warnw("could not read the Secret with the client certificate", "secret", name, "error", err)
+		return ""
+	}
+	return validateSqlTlsClientSecret(secret)
+}
+
+// Return the value, or the Secret reference, of the env. variable
+func (this *SqlCF) readSqlEnv(name string) (string, *core.SecretKeySelector) {
+	if val, exists := this.svcEnvCache.Get(name); exists {
+		if val.GetValue().ValueFrom != nil {
+			return "", val.GetValue().ValueFrom.SecretKeyRef
+		}
+		return val.GetValue().Value, nil
+	}
+	return "", nil
+}
+
+// The env. variables are built from the spec, so an entry rejected by the cache is reported as a configuration error
+func (this *SqlCF) setEnv(builder env.EnvCacheEntryBuilder, optionPath string) {
+	if err := this.svcEnvCache.Set(builder.SetOwner(this.Describe()).Build()); err != nil {
+		this.log.Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(err.Error(), optionPath)
+	}
+}
+
+func (this *SqlCF) addInvalid(optionPath string, details string) {
+	this.invalidOptions = append(this.invalidOptions, optionPath)
+	this.invalidDetails = append(this.invalidDetails, details)
+}
+
+// Return the JDBC URL parameters for the valid JDBC properties, sorted by name
+func (this *SqlCF) getJdbcPropertyParameters(properties map[string]string, tlsConfigured bool) [][2]string {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([][2]string, 0)
+	for _, k := range keys {
+		optionPath := sqlJdbcPropertiesOptionPath + "[" + k + "]"
+		if !sqlJdbcPropertyRegex.MatchString(k) {
+			this.addInvalid(optionPath, "property name must start with a letter and contain only alphanumeric characters, '.', '_', and '-'")
+			continue
+		}
+		if common.ContainsString(sqlReservedJdbcProperties, strings.ToLower(k)) {
+			this.addInvalid(optionPath, "use spec.configuration.sql.dataSource instead")
+			continue
+		}
+		if tlsConfigured && common.ContainsString(sqlTlsUrlParameters, strings.ToLower(k)) {
+			this.addInvalid(optionPath, "property is managed by "+sqlTlsOptionPath)
+			continue
+		}
+		res = append(res, [2]string{k, url.QueryEscape(properties[k])})
+	}
+	return res
+}
 
+// Set the env. variables for the valid pool options
+func (this *SqlCF) addPoolEnv(pool ar.ApicurioRegistrySpecConfigurationSqlPool) {
+	if pool.MinSize < 0 {
+		this.addInvalid(sqlPoolOptionPath+".minSize", "must not be negative")
+	} else if pool.MaxSize < 0 {
+		this.addInvalid(sqlPoolOptionPath+".maxSize", "must not be negative")
+	} else if minSize, maxSize, details := getSqlPoolSize(pool); details != "" {
+		this.addInvalid(sqlPoolOptionPath+".minSize", details)
+	} else {
+		if minSize > 0 {
+			// Apicurio Registry initializes the pool with 20 connections by default, which may exceed the configured size
+			this.targetPoolEnv[ENV_REGISTRY_DATASOURCE_JDBC_INITIAL_SIZE] = strconv.Itoa(int(minSize))
+			this.targetPoolEnv[ENV_REGISTRY_DATASOURCE_JDBC_MIN_SIZE] = strconv.Itoa(int(minSize))
+		}
+		if maxSize > 0 {
+			this.targetPoolEnv[ENV_REGISTRY_DATASOURCE_JDBC_MAX_SIZE] = strconv.Itoa(int(maxSize))
+		}
+	}
+	if pool.AcquisitionTimeout != "" {
+		if value, details := toQuarkusDuration(pool.AcquisitionTimeout); details != "" {
+			this.addInvalid(sqlPoolOptionPath+".acquisitionTimeout", details)
+		} else {
+			this.targetPoolEnv[ENV_QUARKUS_DATASOURCE_JDBC_ACQUISITION_TIMEOUT] = value
+		}
+	}
+	if pool.LeakDetectionInterval != "" {
+		if value, details := toQuarkusDuration(pool.LeakDetectionInterval); details != "" {
+			this.addInvalid(sqlPoolOptionPath+".leakDetectionInterval", details)
+		} else {
+			this.targetPoolEnv[ENV_QUARKUS_DATASOURCE_JDBC_LEAK_DETECTION_INTERVAL] = value
+		}
+	}
 }
 
 func (this *SqlCF) Cleanup() bool {
 	// No cleanup
 	return true
 }
+
+// Mount the Secret in the registry container, or remove it if the Secret name is empty
+func setSqlTlsSecretVolume(deployment *apps.Deployment, volumeName string, mountPath string, secretName string) {
+	volume := &core.Volume{
+		Name: volumeName,
+		VolumeSource: core.VolumeSource{
+			Secret: &core.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		},
+	}
+	mount := &core.VolumeMount{
+		Name:      volumeName,
+		ReadOnly:  true,
+		MountPath: mountPath,
+	}
+	container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME)
+	if secretName != "" {
+		common.SetVolumeInDeployment(deployment, volume)
+		if container != nil {
+			common.AddVolumeMountToContainer(container, mount)
+		}
+	} else {
+		common.RemoveVolumeFromDeployment(deployment, volume)
+		if container != nil {
+			common.RemoveVolumeMountFromContainer(container, mount)
+		}
+	}
+}
+
+// Return the required option that is missing, or details of the invalid configuration
+func validateSqlTls(tls ar.ApicurioRegistrySpecConfigurationDataSourceTls, jdbcUrl string) (string, string) {
+	mode := getSqlSslMode(tls)
+	if !common.ContainsString(sqlSslModes, mode) {
+		return "", "unsupported sslMode " + mode + ", supported values are " + strings.Join(sqlSslModes, ", ")
+	}
+	if (mode == "verify-ca" || mode == "verify-full") && tls.CaSecretName == "" {
+		return sqlTlsOptionPath + ".caSecretName", ""
+	}
+	if !strings.HasPrefix(jdbcUrl, sqlJdbcUrlPrefix) {
+		return "", "TLS can only be configured for a PostgreSQL data source URL, starting with " + sqlJdbcUrlPrefix
+	}
+	return "", ""
+}
+
+// Return details if the Secret does not contain the client certificate, or the private key in the PKCS #8 DER format
+func validateSqlTlsClientSecret(secret *core.Secret) string {
+	if len(secret.Data[SqlTlsCertificateKey]) == 0 {
+		return "Secret " + secret.Name + " must contain the client certificate under the " + SqlTlsCertificateKey + " key"
+	}
+	key, exists := secret.Data[SqlTlsPrivateKeyKey]
+	if !exists {
+		return "Secret " + secret.Name + " must contain the private key in the PKCS #8 DER format under the " + SqlTlsPrivateKeyKey + " key"
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(key); err != nil {
+		return "the " + SqlTlsPrivateKeyKey + " key of Secret " + secret.Name +
+			" must contain an unencrypted private key in the PKCS #8 DER format"
+	}
+	return ""
+}
+
+func getSqlSslMode(tls ar.ApicurioRegistrySpecConfigurationDataSourceTls) string {
+	if tls.SslMode != "" {
+		return tls.SslMode
+	}
+	if tls.CaSecretName != "" {
+		return sqlDefaultSslModeTls
+	}
+	return sqlDefaultSslMode
+}
+
+// Return the JDBC URL parameters for the TLS configuration, in a stable order
+func sqlTlsUrlParameterValues(tls ar.ApicurioRegistrySpecConfigurationDataSourceTls) [][2]string {
+	res := [][2]string{{"sslmode", getSqlSslMode(tls)}}
+	if tls.CaSecretName != "" {
+		res = append(res, [2]string{"sslrootcert", SqlTlsCaMountPath + "/" + SqlTlsCaKey})
+	}
+	if tls.ClientCertificateSecretName != "" {
+		res = append(res, [2]string{"sslcert", SqlTlsClientMountPath + "/" + SqlTlsCertificateKey})
+		res = append(res, [2]string{"sslkey", SqlTlsClientMountPath + "/" + SqlTlsPrivateKeyKey})
+	}
+	return res
+}
+
+// Return the pool size to configure, zero values are not configured.
+// The minimum size is adjusted if only the maximum size is lower than the default minimum.
+func getSqlPoolSize(pool ar.ApicurioRegistrySpecConfigurationSqlPool) (int32, int32, string) {
+	minSize := pool.MinSize
+	maxSize := pool.MaxSize
+	if minSize == 0 && maxSize > 0 && maxSize < sqlDefaultPoolMinSize {
+		minSize = maxSize
+	}
+	if maxSize == 0 && minSize > sqlDefaultPoolMaxSize {
+		return 0, 0, "must not be greater than the default maximum size " + strconv.Itoa(sqlDefaultPoolMaxSize) + ", configure maxSize"
+	}
+	if maxSize > 0 && minSize > maxSize {
+		return 0, 0, "must not be greater than maxSize"
+	}
+	return minSize, maxSize, ""
+}
+
+// Convert a duration, e.g. `1m30s`, to the ISO-8601 format supported by Quarkus
+func toQuarkusDuration(value string) (string, string) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return "", "invalid duration, use for example 5s, 500ms, or 1m30s"
+	}
+	if duration <= 0 {
+		return "", "must be positive"
+	}
+	return "PT" + strconv.FormatFloat(duration.Seconds(), 'f', -1, 64) + "S", ""
+}
+
+// Return the env. variable with the value, or with the Secret reference if it is not nil
+func sqlEnv(name string, value string, ref *core.SecretKeySelector) *core.EnvVar {
+	if ref != nil {
+		return &core.EnvVar{
+			Name: name,
+			ValueFrom: &core.EnvVarSource{
+				SecretKeyRef: ref,
+			},
+		}
+	}
+	return &core.EnvVar{
+		Name:  name,
+		Value: value,
+	}
+}
+
+func isSecretKeyRefEqual(a *core.SecretKeySelector, b *core.SecretKeySelector) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name && a.Key == b.Key
+}
+
+func isEnvEqual(target map[string]string, existing map[string]string) bool {
+	if len(target) != len(existing) {
+		return false
+	}
+	for k, v := range target {
+		if e, exists := existing[k]; !exists || e != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Replace the parameters of the JDBC URL, the other parameters are kept in the same order.
+// The removed parameters are matched case-insensitively.
+func rewriteSqlJdbcUrl(jdbcUrl string, removedParameters []string, parameters [][2]string) string {
+	base := jdbcUrl
+	query := ""
+	if i := strings.Index(jdbcUrl, "?"); i != -1 {
+		base = jdbcUrl[:i]
+		query = jdbcUrl[i+1:]
+	}
+	res := make([]string, 0)
+	for _, p := range strings.Split(query, "&") {
+		if p == "" {
+			continue
+		}
+		name := p
+		if i := strings.Index(p, "="); i != -1 {
+			name = p[:i]
+		}
+		removed := common.ContainsString(removedParameters, strings.ToLower(name))
+		for _, np := range parameters {
+			removed = removed || np[0] == name
+		}
+		if !removed {
+			res = append(res, p)
+		}
+	}
+	for _, p := range parameters {
+		res = append(res, p[0]+"="+p[1])
+	}
+	return base + "?" + strings.Join(res, "&")
+}
@@ -0,0 +1,193 @@
+package cf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
+	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	core "k8s.io/api/core/v1"
+)
+
+func TestSqlTls(t *testing.T) {
+	tls := ar.ApicurioRegistrySpecConfigurationDataSourceTls{
+		CaSecretName:                "db-ca",
+		ClientCertificateSecretName: "registry-db-client",
+	}
+	c.AssertEquals(t, "jdbc:postgresql://db:5432/registry?ApplicationName=registry&sslmode=verify-full"+
+		"&sslrootcert=/etc/registry-sql-tls-ca/ca.crt"+
+		"&sslcert=/etc/registry-sql-tls-client/tls.crt&sslkey=/etc/registry-sql-tls-client/key.der",
+		rewriteSqlJdbcUrl("jdbc:postgresql://db:5432/registry?ssl=true&ApplicationName=registry&sslmode=disable",
+			sqlTlsUrlParameters, sqlTlsUrlParameterValues(tls)))
+
+	tls = ar.ApicurioRegistrySpecConfigurationDataSourceTls{SslMode: "require"}
+	c.AssertEquals(t, "jdbc:postgresql://db:5432/registry?sslmode=require",
+		rewriteSqlJdbcUrl("jdbc:postgresql://db:5432/registry", sqlTlsUrlParameters, sqlTlsUrlParameterValues(tls)))
+
+	// Validation
+	required, invalid := validateSqlTls(ar.ApicurioRegistrySpecConfigurationDataSourceTls{SslMode: "verify-ca"}, "jdbc:postgresql://db/registry")
+	c.AssertEquals(t, "spec.configuration.sql.dataSource.tls.caSecretName", required)
+	c.AssertEquals(t, "", invalid)
+	_, invalid = validateSqlTls(ar.ApicurioRegistrySpecConfigurationDataSourceTls{SslMode: "on"}, "jdbc:postgresql://db/registry")
+	c.AssertEquals(t, true, invalid != "")
+	_, invalid = validateSqlTls(tls, "jdbc:mysql://db/registry")
+	c.AssertEquals(t, true, invalid != "")
+
+	// Client certificate Secret
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &core.Secret{Data: map[string][]byte{"tls.crt": []byte("certificate"), "key.der": pkcs8}}
+	secret.Name = "registry-db-client"
+	c.AssertEquals(t, "", validateSqlTlsClientSecret(secret))
+	secret.Data["key.der"] = ecKey
+	c.AssertEquals(t, "the key.der key of Secret registry-db-client must contain an unencrypted private key in the PKCS #8 DER format",
+		validateSqlTlsClientSecret(secret))
+	delete(secret.Data, "key.der")
+	secret.Data["tls.key"] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+	c.AssertEquals(t, "Secret registry-db-client must contain the private key in the PKCS #8 DER format under the key.der key",
+		validateSqlTlsClientSecret(secret))
+	delete(secret.Data, "tls.crt")
+	c.AssertEquals(t, "Secret registry-db-client must contain the client certificate under the tls.crt key",
+		validateSqlTlsClientSecret(secret))
+}
+
+func TestSqlPool(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewSqlCF(ctx, services))
+
+	spec := &ar.ApicurioRegistry{
+		Spec: ar.ApicurioRegistrySpec{
+			Configuration: ar.ApicurioRegistrySpecConfiguration{
+				Persistence: "sql",
+				Sql: ar.ApicurioRegistrySpecConfigurationSql{
+					DataSource: ar.ApicurioRegistrySpecConfigurationDataSource{
+						Url:      "jdbc:postgresql://db:5432/registry?connectTimeout=5",
+						UserName: "registry",
+					},
+					Pool: ar.ApicurioRegistrySpecConfigurationSqlPool{
+						MaxSize:            10,
+						AcquisitionTimeout: "1m30s",
+					},
+					JdbcProperties: map[string]string{
+						"connectTimeout":  "10",
+						"ApplicationName": "apicurio registry",
+						"password":        "secret",
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+
+	assertEnv := func(name string, expected string) {
+		entry, exists := ctx.GetEnvCache().Get(name)
+		c.AssertEquals(t, expected != "", exists)
+		if exists {
+			c.AssertEquals(t, expected, entry.GetValue().Value)
+		}
+	}
+	assertEnv(ENV_REGISTRY_DATASOURCE_URL, "jdbc:postgresql://db:5432/registry?ApplicationName=apicurio+registry&connectTimeout=10")
+	assertEnv(ENV_REGISTRY_DATASOURCE_JDBC_INITIAL_SIZE, "10")
+	assertEnv(ENV_REGISTRY_DATASOURCE_JDBC_MIN_SIZE, "10")
+	assertEnv(ENV_REGISTRY_DATASOURCE_JDBC_MAX_SIZE, "10")
+	assertEnv(ENV_QUARKUS_DATASOURCE_JDBC_ACQUISITION_TIMEOUT, "PT90S")
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.sql.jdbcProperties[password]"))
+
+	// Removed options are removed from the env. variables
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Sql.Pool = ar.ApicurioRegistrySpecConfigurationSqlPool{MinSize: 30, MaxSize: 20}
+	spec.Spec.Configuration.Sql.JdbcProperties = nil
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	assertEnv(ENV_REGISTRY_DATASOURCE_URL, "jdbc:postgresql://db:5432/registry?connectTimeout=5")
+	assertEnv(ENV_REGISTRY_DATASOURCE_JDBC_MAX_SIZE, "")
+	assertEnv(ENV_QUARKUS_DATASOURCE_JDBC_ACQUISITION_TIMEOUT, "")
+	condition = services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.sql.pool.minSize"))
+}
+
+func TestSqlPostgresClusterRef(t *testing.T) {
+	// CloudNativePG
+	cnpg := &client.CnpgCluster{}
+	cnpg.Name = "db"
+	connection := resolveCnpgCluster(cnpg, "registry")
+	c.AssertEquals(t, "jdbc:postgresql://db-rw.registry.svc:5432/app", connection.url)
+	c.AssertEquals(t, "db-app", connection.secretName)
+	c.AssertEquals(t, "username", connection.userKey)
+
+	cnpg.Spec.Bootstrap = &client.CnpgBootstrap{Initdb: &client.CnpgBootstrapInitdb{
+		Database: "registry",
+		Secret:   &client.CnpgLocalObjectReference{Name: "registry-credentials"},
+	}}
+	connection = resolveCnpgCluster(cnpg, "registry")
+	c.AssertEquals(t, "jdbc:postgresql://db-rw.registry.svc:5432/registry", connection.url)
+	c.AssertEquals(t, "registry-credentials", connection.secretName)
+
+	// PGO
+	pgo := &client.PgoPostgresCluster{}
+	pgo.Name = "db"
+	connection, problem := resolvePgoPostgresCluster(pgo, "registry")
+	c.AssertEquals(t, "", problem)
+	c.AssertEquals(t, "jdbc:postgresql://db-primary.registry.svc:5432/db", connection.url)
+	c.AssertEquals(t, "db-pguser-db", connection.secretName)
+	c.AssertEquals(t, "user", connection.userKey)
+
+	port := int32(5433)
+	pgo.Spec.Port = &port
+	pgo.Spec.Users = []client.PgoUser{{Name: "admin"}, {Name: "registry", Databases: []string{"registry", "other"}}}
+	connection, problem = resolvePgoPostgresCluster(pgo, "registry")
+	c.AssertEquals(t, "", problem)
+	c.AssertEquals(t, "jdbc:postgresql://db-primary.registry.svc:5433/registry", connection.url)
+	c.AssertEquals(t, "db-pguser-registry", connection.secretName)
+
+	pgo.Spec.Users = []client.PgoUser{{Name: "admin"}}
+	_, problem = resolvePgoPostgresCluster(pgo, "registry")
+	c.AssertEquals(t, true, problem != "")
+
+	// Env. variables
+	ref := &core.SecretKeySelector{LocalObjectReference: core.LocalObjectReference{Name: "db-app"}, Key: "password"}
+	c.AssertEquals(t, ref, sqlEnv(ENV_REGISTRY_DATASOURCE_PASSWORD, "", ref).ValueFrom.SecretKeyRef)
+	c.AssertEquals(t, "secret", sqlEnv(ENV_REGISTRY_DATASOURCE_PASSWORD, "secret", nil).Value)
+	c.AssertEquals(t, true, isSecretKeyRefEqual(ref, ref.DeepCopy()))
+	c.AssertEquals(t, false, isSecretKeyRefEqual(ref, nil))
+}
+
+func TestSqlSetEnvError(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	sqlCF := NewSqlCF(ctx, services).(*SqlCF)
+
+	// An entry rejected by the cache is reported as a configuration error
+	sqlCF.setEnv(env.NewSimpleEnvCacheEntryBuilder("1INVALID", "value"), sqlDataSourceOptionPath+".url")
+	_, exists := ctx.GetEnvCache().Get("1INVALID")
+	c.AssertEquals(t, false, exists)
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID), condition.Reason)
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.sql.dataSource.url"))
+}
@@ -0,0 +1,260 @@
+package cf
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/cf/kafkasql"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+)
+
+var _ loop.ControlFunction = &StorageConnectivityCF{}
+
+const (
+	storageDialTimeout = 3 * time.Second
+	// The checks block the control loop, so the servers are checked only until this timeout
+	storageCheckTimeout   = 5 * time.Second
+	storageCheckInterval  = 60 * time.Second
+	sqlDefaultPort        = "5432"
+	sqlSslRequestResponse = 'S'
+)
+
+// PostgreSQL SSLRequest message, the server responds with a single byte, 'S' if TLS is supported
+var sqlSslRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+// JDBC URL parameter values that require TLS
+var sqlTlsSslModes = []string{"require", "verify-ca", "verify-full"}
+
+// This CF checks that the storage is reachable from the operator, so a wrong data source URL
+// or bootstrap servers are reported before the registry pods fail to start.
+// The endpoints are read from the env. variables set by SqlCF and KafkasqlCF, so it must be executed after them.
+// The result is reported in the StorageReady condition, but it does not block the deployment,
+// since the operator may not have the same network access as the registry pods.
+type StorageConnectivityCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+
+	endpoints []storageEndpoint
+	// The last check result is kept, the endpoints are checked again after the interval, or when they change
+	checkedEndpoints []storageEndpoint
+	lastCheck        time.Time
+	reachable        string
+	problem          string
+}
+
+// Address of a storage server, in the `host:port` format
+type storageEndpoint struct {
+	address string
+	tls     bool
+	// TLS is negotiated using the PostgreSQL SSLRequest message
+	postgres bool
+}
+
+func NewStorageConnectivityCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &StorageConnectivityCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *StorageConnectivityCF) Describe() string {
+	return "StorageConnectivityCF"
+}
+
+func (this *StorageConnectivityCF) Sense() {
+	// The storage is checked once per loop, the condition is reported at the same time
+	if this.ctx.GetAttempts() != 0 {
+		return
+	}
+	this.endpoints = nil
+
+	// Observation #1
+	// Read the storage endpoints
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		switch specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Persistence {
+		case "sql":
+			if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_DATASOURCE_URL); exists {
+				this.endpoints = parseSqlStorageEndpoints(entry.GetValue().Value)
+			}
+		case kafkasql.PERSISTENCE_ID:
+			if entry, exists := this.svcEnvCache.Get(kafkasql.ENV_KAFKA_BOOTSTRAP_SERVERS); exists {
+				protocol := ""
+				if entry, exists := this.svcEnvCache.Get(kafkasql.ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL); exists {
+					protocol = entry.GetValue().Value
+				}
+				this.endpoints = parseKafkaStorageEndpoints(entry.GetValue().Value, strings.HasSuffix(protocol, "SSL"))
+			}
+		}
+	}
+
+	// Observation #2
+	// Check the endpoints
+	if len(this.endpoints) > 0 && (!isStorageEndpointsEqual(this.endpoints, this.checkedEndpoints) ||
+		time.Since(this.lastCheck) >= storageCheckInterval) {
+		this.reachable, this.problem = checkStorageEndpoints(this.endpoints, time.Now().Add(storageCheckTimeout))
+		this.checkedEndpoints = this.endpoints
+		this.lastCheck = time.Now()
+		if this.problem != "" {
+			this.log.Infow("storage is not reachable", "details", this.problem)
+		}
+	}
+}
+
+func (this *StorageConnectivityCF) Compare() bool {
+	// Condition #1
+	// Report the result once per loop
+	return len(this.endpoints) > 0 && this.ctx.GetAttempts() == 0
+}
+
+func (this *StorageConnectivityCF) Respond() {
+	// Response #1
+	// Report the result
+	condition := this.services.GetConditionManager().GetStorageReadyCondition()
+	if this.problem != "" {
+		condition.TransitionStorageUnreachable(this.problem)
+		// Check again after the interval, even if nothing else triggers the reconciliation
+		this.ctx.SetRequeueDelaySec(uint(storageCheckInterval.Seconds()))
+	} else {
+		condition.TransitionStorageReachable("Storage is reachable at " + this.reachable)
+	}
+}
+
+func (this *StorageConnectivityCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Return the servers of the PostgreSQL JDBC URL, e.g. `jdbc:postgresql://host1:5432,host2/database?sslmode=require`.
+// Returns nil if the URL is not supported.
+func parseSqlStorageEndpoints(jdbcUrl string) []storageEndpoint {
+	if !strings.HasPrefix(jdbcUrl, sqlJdbcUrlPrefix+"//") {
+		return nil
+	}
+	hosts := strings.TrimPrefix(jdbcUrl, sqlJdbcUrlPrefix+"//")
+	query := ""
+	if i := strings.Index(hosts, "?"); i != -1 {
+		query = hosts[i+1:]
+		hosts = hosts[:i]
+	}
+	if i := strings.Index(hosts, "/"); i != -1 {
+		hosts = hosts[:i]
+	}
+	useTls := false
+	if parameters, err := url.ParseQuery(query); err == nil {
+		useTls = common.ContainsString(sqlTlsSslModes, parameters.Get("sslmode")) ||
+			(parameters.Get("sslmode") == "" && parameters.Get("ssl") == "true")
+	}
+	res := make([]storageEndpoint, 0)
+	for _, host := range strings.Split(hosts, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), sqlDefaultPort)
+		}
+		res = append(res, storageEndpoint{address: host, tls: useTls, postgres: true})
+	}
+	return res
+}
+
+// Return the Kafka bootstrap servers, e.g. `broker1:9092,broker2:9092`
+func parseKafkaStorageEndpoints(bootstrapServers string, useTls bool) []storageEndpoint {
+	res := make([]storageEndpoint, 0)
+	for _, server := range strings.Split(bootstrapServers, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			res = append(res, storageEndpoint{address: server, tls: useTls})
+		}
+	}
+	return res
+}
+
+// Check the endpoints until one of them is reachable, or the deadline is exceeded.
+// Returns the reachable endpoint, or the problem with the first one.
+func checkStorageEndpoints(endpoints []storageEndpoint, deadline time.Time) (string, string) {
+	problem := ""
+	for _, endpoint := range endpoints {
+		if !time.Now().Before(deadline) {
+			if problem == "" {
+				problem = "Could not connect to the storage at " + endpoint.address + ": timeout exceeded"
+			}
+			break
+		}
+		endpointDeadline := time.Now().Add(storageDialTimeout)
+		if endpointDeadline.After(deadline) {
+			endpointDeadline = deadline
+		}
+		err := checkStorageEndpoint(endpoint, endpointDeadline)
+		if err == nil {
+			return endpoint.address, ""
+		}
+		if problem == "" {
+			problem = "Could not connect to the storage at " + endpoint.address + ": " + err.Error()
+		}
+	}
+	return "", problem
+}
+
+// Open a TCP connection to the endpoint, and perform the TLS handshake if required.
+// The server certificate is not verified, the truststore is used only by the registry.
+func checkStorageEndpoint(endpoint storageEndpoint, deadline time.Time) error {
+	if _, _, err := net.SplitHostPort(endpoint.address); err != nil {
+		return err
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.Dial("tcp", endpoint.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !endpoint.tls {
+		return nil
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if endpoint.postgres {
+		if _, err := conn.Write(sqlSslRequest); err != nil {
+			return err
+		}
+		response := make([]byte, 1)
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return err
+		}
+		if response[0] != sqlSslRequestResponse {
+			return errors.New("the server does not support TLS")
+		}
+	}
+	host, _, _ := net.SplitHostPort(endpoint.address)
+	return tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true}).Handshake()
+}
+
+func isStorageEndpointsEqual(a []storageEndpoint, b []storageEndpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package cf
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+)
+
+func TestStorageEndpoints(t *testing.T) {
+	c.AssertEquals(t, []storageEndpoint{
+		{address: "db1:5432", tls: true, postgres: true},
+		{address: "db2:5433", tls: true, postgres: true},
+	}, parseSqlStorageEndpoints("jdbc:postgresql://db1,db2:5433/registry?sslmode=verify-full&sslrootcert=/etc/ca.crt"))
+	c.AssertEquals(t, []storageEndpoint{{address: "[::1]:5432", postgres: true}},
+		parseSqlStorageEndpoints("jdbc:postgresql://[::1]/registry"))
+	c.AssertEquals(t, []storageEndpoint{{address: "db:5432", postgres: true}},
+		parseSqlStorageEndpoints("jdbc:postgresql://db:5432/registry?sslmode=prefer"))
+	c.AssertEquals(t, 0, len(parseSqlStorageEndpoints("jdbc:mysql://db:3306/registry")))
+
+	c.AssertEquals(t, []storageEndpoint{{address: "broker1:9092"}, {address: "broker2:9092"}},
+		parseKafkaStorageEndpoints("broker1:9092, broker2:9092", false))
+}
+
+func TestStorageConnectivity(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Refuse TLS, as a PostgreSQL server without TLS
+			_, _ = conn.Write([]byte{'N'})
+			conn.Close()
+		}
+	}()
+
+	reachable, problem := checkStorageEndpoints([]storageEndpoint{{address: "127.0.0.1:1"}, {address: address}}, time.Now().Add(time.Second))
+	c.AssertEquals(t, address, reachable)
+	c.AssertEquals(t, "", problem)
+
+	err = checkStorageEndpoint(storageEndpoint{address: address, tls: true, postgres: true}, time.Now().Add(time.Second))
+	c.AssertEquals(t, "the server does not support TLS", err.Error())
+
+	// TLS handshake
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	c.AssertEquals(t, nil, checkStorageEndpoint(storageEndpoint{address: server.Listener.Addr().String(), tls: true}, time.Now().Add(time.Second)))
+
+	// The remaining endpoints are not checked after the deadline
+	_, problem = checkStorageEndpoints([]storageEndpoint{{address: address}}, time.Now())
+	c.AssertEquals(t, "Could not connect to the storage at "+address+": timeout exceeded", problem)
+
+	listener.Close()
+	_, problem = checkStorageEndpoints([]storageEndpoint{{address: address}}, time.Now().Add(time.Second))
+	c.AssertEquals(t, true, strings.HasPrefix(problem, "Could not connect to the storage at "+address))
+}
@@ -0,0 +1,537 @@
+package cf
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ loop.ControlFunction = &TrustedCaCF{}
+
+const (
+	TrustedCaConfigMapKey = "ca-bundle.crt"
+	TrustedCaSecretKey    = "ca.crt"
+
+	// The CA certificate sources are projected into a single volume of the init container, one file per source
+	TrustedCaVolumeName        = "registry-trusted-ca"
+	TrustedCaMountPath         = "/etc/" + TrustedCaVolumeName
+	TrustedCaConfigMapFile     = "configmap.crt"
+	TrustedCaSecretFile        = "secret.crt"
+	TrustedCaClusterBundleFile = "cluster-bundle.crt"
+
+	TruststoreVolumeName        = "registry-truststore"
+	TruststoreMountPath         = "/etc/" + TruststoreVolumeName
+	TruststoreFile              = TruststoreMountPath + "/truststore.p12"
+	TruststoreInitContainerName = "registry-truststore-init"
+	// The truststore contains only public certificates, the password does not protect any secret
+	TruststorePassword = "changeit"
+
+	// Changes to the pod template annotation roll the pods when the CA certificates change
+	TrustedCaHashAnnotation = "apicur.io/trusted-ca-hash"
+	// OpenShift injects the cluster trusted CA bundle into the ConfigMaps with this label
+	OcpInjectTrustedCaBundleLabel = "config.openshift.io/inject-trusted-cabundle"
+	OcpTrustedCaBundleSuffix      = "-trusted-ca-bundle"
+
+	trustedCaOptionPath = "spec.configuration.security.trustedCa"
+)
+
+// The init container runs the script with the POSIX shell of the registry image.
+// The registry images provide `/bin/sh`, `keytool`, and the coreutils used below.
+const truststoreInitShell = "/bin/sh"
+
+// Creates a PKCS12 truststore with the default JVM CA certificates, and the CA certificates in the mounted PEM files.
+// The default certificates are required, otherwise the registry would not trust the public CAs.
+// The JVM location is taken from the `JAVA_HOME` variable of the image, or from the `keytool` executable on the `PATH`.
+// The PEM files are split, because `keytool -importcert` imports only the first certificate of a file.
+const truststoreInitScript = `set -e
+if [ -z "$JAVA_HOME" ]; then
+  KEYTOOL="$(command -v keytool)" || { echo "Could not find keytool, set JAVA_HOME in the registry image" >&2; exit 1; }
+  JAVA_HOME="$(dirname "$(dirname "$(readlink -f "$KEYTOOL")")")"
+fi
+KEYTOOL="$JAVA_HOME/bin/keytool"
+CACERTS="$JAVA_HOME/lib/security/cacerts"
+if [ ! -f "$CACERTS" ]; then
+  CACERTS="$JAVA_HOME/jre/lib/security/cacerts"
+fi
+if [ ! -x "$KEYTOOL" ] || [ ! -f "$CACERTS" ]; then
+  echo "Could not find keytool and the default JVM truststore in JAVA_HOME $JAVA_HOME" >&2
+  exit 1
+fi
+rm -f "$TRUSTSTORE_FILE"
+"$KEYTOOL" -importkeystore -noprompt -srckeystore "$CACERTS" -srcstorepass changeit \
+  -destkeystore "$TRUSTSTORE_FILE" -deststoretype PKCS12 -deststorepass "$TRUSTSTORE_PASSWORD"
+CERT_FILE="$TRUSTSTORE_FILE.pem"
+COUNT=0
+for FILE in "$TRUSTED_CA_DIR"/*.crt; do
+  [ -f "$FILE" ] || continue
+  CERT=""
+  while IFS= read -r LINE || [ -n "$LINE" ]; do
+    case "$LINE" in
+      *"-----BEGIN CERTIFICATE-----"*)
+        CERT="$LINE"
+        ;;
+      *"-----END CERTIFICATE-----"*)
+        if [ -n "$CERT" ]; then
+          COUNT=$((COUNT + 1))
+          printf '%s\n%s\n' "$CERT" "$LINE" > "$CERT_FILE"
+          "$KEYTOOL" -importcert -noprompt -alias "trusted-ca-$COUNT" -file "$CERT_FILE" \
+            -keystore "$TRUSTSTORE_FILE" -storetype PKCS12 -storepass "$TRUSTSTORE_PASSWORD"
+          CERT=""
+        fi
+        ;;
+      *)
+        if [ -n "$CERT" ]; then
+          CERT="$CERT
+$LINE"
+        fi
+        ;;
+    esac
+  done < "$FILE"
+done
+rm -f "$CERT_FILE"
+echo "Imported $COUNT trusted CA certificates"
+`
+
+var truststoreJavaOptions = map[string]string{
+	"-Djavax.net.ssl.trustStore":         TruststoreFile,
+	"-Djavax.net.ssl.trustStorePassword": TruststorePassword,
+	"-Djavax.net.ssl.trustStoreType":     "PKCS12",
+}
+
+// CA certificate sources mounted in the deployment, and the hash of their content
+type trustedCaState struct {
+	configMapName        string
+	secretName           string
+	clusterConfigMapName string
+	// Image of the init container, the same as the registry container
+	image string
+	hash  string
+}
+
+// This CF builds a JVM truststore from the CA certificates referenced in `spec.configuration.security.trustedCa`,
+// using an init container, so the registry trusts internal CAs for outbound TLS connections, e.g. to Keycloak.
+type TrustedCaCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+
+	specEntry       resources.ResourceCacheEntry
+	deploymentEntry resources.ResourceCacheEntry
+
+	valid          bool
+	invalidDetails string
+	invalidOption  string
+	target         trustedCaState
+	existing       trustedCaState
+
+	clusterConfigMapName   string
+	createClusterConfigMap bool
+	deleteClusterConfigMap bool
+
+	javaOptions       map[string]string
+	javaOptionsExists bool
+}
+
+func NewTrustedCaCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &TrustedCaCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *TrustedCaCF) Describe() string {
+	return "TrustedCaCF"
+}
+
+func (this *TrustedCaCF) Sense() {
+	this.valid = false
+	this.invalidDetails = ""
+	this.invalidOption = ""
+	this.target = trustedCaState{}
+	this.existing = trustedCaState{}
+	this.clusterConfigMapName = this.ctx.GetAppName().Str() + OcpTrustedCaBundleSuffix
+	this.createClusterConfigMap = false
+	this.deleteClusterConfigMap = false
+
+	// Observation #1
+	// Read the config values and the deployment
+	var specExists, deploymentExists bool
+	this.specEntry, specExists = this.svcResourceCache.Get(resources.RC_KEY_SPEC)
+	this.deploymentEntry, deploymentExists = this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	if !specExists || !deploymentExists {
+		return
+	}
+	registry := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	config := registry.Spec.Configuration.Security.TrustedCa
+	deployment := this.deploymentEntry.GetValue().(*apps.Deployment)
+	this.existing = readTrustedCaState(deployment)
+
+	// Observation #2
+	// Read the CA certificates, their hash rolls the pods when they change
+	this.valid = true
+	enabled := false
+	var certificates [][]byte
+	if config.ConfigMapName != "" {
+		enabled = true
+		this.target.configMapName = config.ConfigMapName
+		value, problem := this.readConfigMapKey(config.ConfigMapName, TrustedCaConfigMapKey)
+		certificates = append(certificates, this.parseCertificates(value, problem, trustedCaOptionPath+".configMapName")...)
+	}
+	if config.SecretName != "" {
+		enabled = true
+		this.target.secretName = config.SecretName
+		value, problem := this.readSecretKey(config.SecretName, TrustedCaSecretKey)
+		certificates = append(certificates, this.parseCertificates(value, problem, trustedCaOptionPath+".secretName")...)
+	}
+	if config.InjectClusterBundle {
+		if !this.ctx.GetSupportedFeatures().IsOCP {
+			this.addInvalid("the cluster trusted CA bundle is supported only on OpenShift", trustedCaOptionPath+".injectClusterBundle")
+		} else {
+			enabled = true
+			this.target.clusterConfigMapName = this.clusterConfigMapName
+			configMap, err := this.ctx.GetClients().Kube().GetConfigMap(this.ctx.GetAppNamespace(), common.Name(this.clusterConfigMapName))
+			if err == nil {
+				// The bundle may not have been injected yet
+				certificates = append(certificates, this.parseCertificates(configMap.Data[TrustedCaConfigMapKey], "",
+					trustedCaOptionPath+".injectClusterBundle")...)
+			} else if api_errors.IsNotFound(err) {
+				this.createClusterConfigMap = true
+			} else {
+				this.addInvalid("could not read ConfigMap "+this.clusterConfigMapName+": "+err.Error(),
+					trustedCaOptionPath+".injectClusterBundle")
+			}
+		}
+	} else if this.ctx.GetSupportedFeatures().IsOCP && this.ctx.GetAttempts() == 0 && (enabled || this.existing != (trustedCaState{})) {
+		// The managed ConfigMap is no longer used
+		configMap, err := this.ctx.GetClients().Kube().GetConfigMap(this.ctx.GetAppNamespace(), common.Name(this.clusterConfigMapName))
+		this.deleteClusterConfigMap = err == nil && meta.IsControlledBy(configMap, registry)
+	}
+	if enabled {
+		if container := common.GetContainerByName(deployment.Spec.Template.Spec.Containers, factory.REGISTRY_CONTAINER_NAME); container != nil {
+			this.target.image = container.Image
+		}
+		hash := sha256.New()
+		for _, certificate := range certificates {
+			hash.Write(certificate)
+		}
+		this.target.hash = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	// Observation #3
+	// Read the Java options
+	this.javaOptions = env.ParseJavaOptionsMap(this.svcEnvCache)
+	this.javaOptionsExists = true
+	for k, v := range truststoreJavaOptions {
+		existing, exists := this.javaOptions[k]
+		this.javaOptionsExists = this.javaOptionsExists && exists && existing == v
+	}
+}
+
+func (this *TrustedCaCF) readConfigMapKey(name string, key string) (string, string) {
+	configMap, err := this.ctx.GetClients().Kube().GetConfigMap(this.ctx.GetAppNamespace(), common.Name(name))
+	if err != nil {
+		return "", "could not read ConfigMap " + name + ": " + err.Error()
+	}
+	value, exists := configMap.Data[key]
+	if !exists {
+		return "", "ConfigMap " + name + " does not contain the " + key + " key"
+	}
+	return value, ""
+}
+
+func (this *TrustedCaCF) readSecretKey(name string, key string) (string, string) {
+	secret, err := this.ctx.GetClients().Kube().GetSecret(this.ctx.GetAppNamespace(), common.Name(name), &meta.GetOptions{})
+	if err != nil {
+		return "", "could not read Secret " + name + ": " + err.Error()
+	}
+	value, exists := secret.Data[key]
+	if !exists {
+		return "", "Secret " + name + " does not contain the " + key + " key"
+	}
+	return string(value), ""
+}
+
+// Return the certificates of the source, or report the problem with reading or parsing them
+func (this *TrustedCaCF) parseCertificates(data string, problem string, optionPath string) [][]byte {
+	if problem != "" {
+		this.addInvalid(problem, optionPath)
+		return nil
+	}
+	res, err := parsePemCertificates([]byte(data))
+	if err != nil {
+		this.addInvalid("could not parse a CA certificate: "+err.Error(), optionPath)
+	}
+	return res
+}
+
+func (this *TrustedCaCF) addInvalid(details string, optionPath string) {
+	this.valid = false
+	if this.invalidDetails == "" {
+		this.invalidDetails = details
+		this.invalidOption = optionPath
+	}
+}
+
+func (this *TrustedCaCF) Compare() bool {
+	enabled := this.target != (trustedCaState{})
+	// Condition #1
+	// The truststore has to be configured, updated, or removed
+	// Condition #2
+	// The Java options have to be added or removed
+	// Condition #3
+	// The managed ConfigMap has to be created or deleted
+	// Condition #4
+	// Report invalid configuration once per loop
+	return (this.valid && (this.target != this.existing || enabled != this.javaOptionsExists ||
+		this.createClusterConfigMap || this.deleteClusterConfigMap)) ||
+		(!this.valid && this.invalidDetails != "" && this.ctx.GetAttempts() == 0)
+}
+
+func (this *TrustedCaCF) Respond() {
+	if this.valid {
+		enabled := this.target != (trustedCaState{})
+		owner := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+
+		// Response #1
+		// Create the ConfigMap for the cluster trusted CA bundle
+		if this.createClusterConfigMap {
+			_, err := this.ctx.GetClients().Kube().CreateConfigMap(owner, this.ctx.GetAppNamespace(), &core.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{
+					Name:      this.clusterConfigMapName,
+					Namespace: this.ctx.GetAppNamespace().Str(),
+					Labels: map[string]string{
+						OcpInjectTrustedCaBundleLabel: "true",
+					},
+				},
+			})
+			if err != nil && !api_errors.IsAlreadyExists(err) {
+				this.log.Warnw("could not create the trusted CA bundle ConfigMap", "name", this.clusterConfigMapName, "error", err)
+			}
+		}
+
+		// Response #2
+		// Configure or remove the truststore
+		if this.target != this.existing {
+			this.deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+				deployment := value.(*apps.Deployment).DeepCopy()
+				setTrustedCaState(deployment, this.target)
+				return deployment
+			})
+		}
+
+		// Response #3
+		// Add or remove the Java options
+		if enabled && !this.javaOptionsExists {
+			for k, v := range truststoreJavaOptions {
+				this.javaOptions[k] = v
+			}
+			if err := env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, true); err != nil {
+				this.log.Errorw("could not add java options", "error", err)
+			}
+		}
+		if !enabled && this.javaOptionsExists {
+			for k := range truststoreJavaOptions {
+				delete(this.javaOptions, k)
+			}
+			if err := env.SaveJavaOptionsMap(this.svcEnvCache, this.javaOptions, false); err != nil {
+				this.log.Errorw("could not remove java options", "error", err)
+			}
+		}
+
+		// Response #4
+		// Delete the ConfigMap that is no longer used
+		if this.deleteClusterConfigMap {
+			err := this.ctx.GetClients().Kube().DeleteConfigMap(&core.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{
+					Name:      this.clusterConfigMapName,
+					Namespace: this.ctx.GetAppNamespace().Str(),
+				},
+			})
+			if err != nil && !api_errors.IsNotFound(err) {
+				this.log.Warnw("could not delete the trusted CA bundle ConfigMap", "name", this.clusterConfigMapName, "error", err)
+			}
+		}
+	}
+
+	// Response #5
+	// Report the first invalid value, the previous truststore is kept until the problem is fixed
+	if !this.valid && this.invalidDetails != "" && this.ctx.GetAttempts() == 0 {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(this.invalidDetails, this.invalidOption)
+		this.ctx.SetRequeueDelaySec(10)
+	}
+}
+
+func (this *TrustedCaCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+func readTrustedCaState(deployment *apps.Deployment) trustedCaState {
+	res := trustedCaState{}
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		if v.Name != TrustedCaVolumeName || v.Projected == nil {
+			continue
+		}
+		for _, source := range v.Projected.Sources {
+			switch {
+			case source.ConfigMap != nil && len(source.ConfigMap.Items) == 1 && source.ConfigMap.Items[0].Path == TrustedCaClusterBundleFile:
+				res.clusterConfigMapName = source.ConfigMap.Name
+			case source.ConfigMap != nil:
+				res.configMapName = source.ConfigMap.Name
+			case source.Secret != nil:
+				res.secretName = source.Secret.Name
+			}
+		}
+	}
+	if container := common.GetContainerByName(deployment.Spec.Template.Spec.InitContainers, TruststoreInitContainerName); container != nil {
+		res.image = container.Image
+	}
+	res.hash = deployment.Spec.Template.Annotations[TrustedCaHashAnnotation]
+	return res
+}
+
+// Configure the init container, volumes, and mounts for the target state, or remove them if the target is empty
+func setTrustedCaState(deployment *apps.Deployment, target trustedCaState) {
+	podSpec := &deployment.Spec.Template.Spec
+	// The cluster bundle may not have been injected yet, the init container imports the certificates that are present
+	optional := true
+	sources := make([]core.VolumeProjection, 0)
+	if target.configMapName != "" {
+		sources = append(sources, core.VolumeProjection{
+			ConfigMap: &core.ConfigMapProjection{
+				LocalObjectReference: core.LocalObjectReference{Name: target.configMapName},
+				Items:                []core.KeyToPath{{Key: TrustedCaConfigMapKey, Path: TrustedCaConfigMapFile}},
+			},
+		})
+	}
+	if target.secretName != "" {
+		sources = append(sources, core.VolumeProjection{
+			Secret: &core.SecretProjection{
+				LocalObjectReference: core.LocalObjectReference{Name: target.secretName},
+				Items:                []core.KeyToPath{{Key: TrustedCaSecretKey, Path: TrustedCaSecretFile}},
+			},
+		})
+	}
+	if target.clusterConfigMapName != "" {
+		sources = append(sources, core.VolumeProjection{
+			ConfigMap: &core.ConfigMapProjection{
+				LocalObjectReference: core.LocalObjectReference{Name: target.clusterConfigMapName},
+				Items:                []core.KeyToPath{{Key: TrustedCaConfigMapKey, Path: TrustedCaClusterBundleFile}},
+				Optional:             &optional,
+			},
+		})
+	}
+	trustedCaVolume := core.Volume{
+		Name: TrustedCaVolumeName,
+		VolumeSource: core.VolumeSource{
+			Projected: &core.ProjectedVolumeSource{
+				Sources: sources,
+			},
+		},
+	}
+	truststoreVolume := core.Volume{
+		Name: TruststoreVolumeName,
+		VolumeSource: core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		},
+	}
+	truststoreMount := core.VolumeMount{
+		Name:      TruststoreVolumeName,
+		ReadOnly:  true,
+		MountPath: TruststoreMountPath,
+	}
+	container := common.GetContainerByName(podSpec.Containers, factory.REGISTRY_CONTAINER_NAME)
+
+	// Remove the previous init container, so it is recreated with the current image
+	for i, c := range podSpec.InitContainers {
+		if c.Name == TruststoreInitContainerName {
+			podSpec.InitContainers = append(podSpec.InitContainers[:i], podSpec.InitContainers[i+1:]...)
+			break
+		}
+	}
+	if target == (trustedCaState{}) {
+		common.RemoveVolumeFromDeployment(deployment, &trustedCaVolume)
+		common.RemoveVolumeFromDeployment(deployment, &truststoreVolume)
+		if container != nil {
+			common.RemoveVolumeMountFromContainer(container, &truststoreMount)
+		}
+		delete(deployment.Spec.Template.Annotations, TrustedCaHashAnnotation)
+		return
+	}
+
+	initContainer := core.Container{
+		Name:    TruststoreInitContainerName,
+		Image:   target.image,
+		Command: []string{truststoreInitShell, "-c", truststoreInitScript},
+		Env: []core.EnvVar{
+			{Name: "TRUSTSTORE_FILE", Value: TruststoreFile},
+			{Name: "TRUSTSTORE_PASSWORD", Value: TruststorePassword},
+			{Name: "TRUSTED_CA_DIR", Value: TrustedCaMountPath},
+		},
+		VolumeMounts: []core.VolumeMount{
+			{
+				Name:      TruststoreVolumeName,
+				MountPath: TruststoreMountPath,
+			},
+			{
+				Name:      TrustedCaVolumeName,
+				ReadOnly:  true,
+				MountPath: TrustedCaMountPath,
+			},
+		},
+	}
+	if container != nil {
+		initContainer.ImagePullPolicy = container.ImagePullPolicy
+	}
+	podSpec.InitContainers = append(podSpec.InitContainers, initContainer)
+	common.SetVolumeInDeployment(deployment, &trustedCaVolume)
+	common.SetVolumeInDeployment(deployment, &truststoreVolume)
+	if container != nil {
+		common.AddVolumeMountToContainer(container, &truststoreMount)
+	}
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = make(map[string]string)
+	}
+	deployment.Spec.Template.Annotations[TrustedCaHashAnnotation] = target.hash
+}
+
+// Return the DER encoded certificates from the PEM data, other PEM blocks are ignored
+func parsePemCertificates(data []byte) ([][]byte, error) {
+	res := make([][]byte, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return res, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, err
+		}
+		res = append(res, block.Bytes)
+	}
+}
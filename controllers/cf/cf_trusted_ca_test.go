@@ -0,0 +1,91 @@
+package cf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+func TestTrustedCaState(t *testing.T) {
+	deployment := &apps.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []core.Container{
+		{Name: factory.REGISTRY_CONTAINER_NAME, Image: "registry:latest"},
+	}
+	c.AssertEquals(t, trustedCaState{}, readTrustedCaState(deployment))
+
+	target := trustedCaState{
+		configMapName:        "internal-ca",
+		clusterConfigMapName: "registry" + OcpTrustedCaBundleSuffix,
+		image:                "registry:latest",
+		hash:                 "hash",
+	}
+	setTrustedCaState(deployment, target)
+	c.AssertEquals(t, target, readTrustedCaState(deployment))
+	c.AssertEquals(t, 1, len(deployment.Spec.Template.Spec.InitContainers))
+	c.AssertEquals(t, truststoreInitShell, deployment.Spec.Template.Spec.InitContainers[0].Command[0])
+	c.AssertEquals(t, 2, len(deployment.Spec.Template.Spec.InitContainers[0].VolumeMounts))
+	c.AssertEquals(t, 2, len(deployment.Spec.Template.Spec.Volumes))
+	c.AssertEquals(t, 2, len(deployment.Spec.Template.Spec.Volumes[0].Projected.Sources))
+	c.AssertEquals(t, TruststoreMountPath, deployment.Spec.Template.Spec.Containers[0].VolumeMounts[0].MountPath)
+
+	// Update
+	target.configMapName = ""
+	target.secretName = "internal-ca"
+	target.image = "registry:next"
+	target.hash = "next"
+	setTrustedCaState(deployment, target)
+	c.AssertEquals(t, target, readTrustedCaState(deployment))
+	c.AssertEquals(t, 1, len(deployment.Spec.Template.Spec.InitContainers))
+	c.AssertEquals(t, 2, len(deployment.Spec.Template.Spec.Volumes))
+	c.AssertEquals(t, TrustedCaSecretFile, deployment.Spec.Template.Spec.Volumes[0].Projected.Sources[0].Secret.Items[0].Path)
+
+	// Remove
+	setTrustedCaState(deployment, trustedCaState{})
+	c.AssertEquals(t, trustedCaState{}, readTrustedCaState(deployment))
+	c.AssertEquals(t, 0, len(deployment.Spec.Template.Spec.InitContainers))
+	c.AssertEquals(t, 0, len(deployment.Spec.Template.Spec.Volumes))
+	c.AssertEquals(t, 0, len(deployment.Spec.Template.Spec.Containers[0].VolumeMounts))
+}
+
+func TestParsePemCertificates(t *testing.T) {
+	certificate := newTestCertificate(t)
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), "\n# comment\n"...)
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})...)
+	certificates, err := parsePemCertificates(data)
+	c.AssertEquals(t, nil, err)
+	c.AssertEquals(t, 2, len(certificates))
+	c.AssertEquals(t, certificate, certificates[0])
+	_, err = parsePemCertificates(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")}))
+	c.AssertEquals(t, true, err != nil)
+}
+
+func newTestCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Internal CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	res, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
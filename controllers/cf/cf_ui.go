@@ -69,7 +69,9 @@ func (this *UICF) Respond() {
 	if this.UIReadOnly {
 		val = "true"
 	}
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_UI_READ_ONLY, val).Build())
+	if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_UI_READ_ONLY, val).SetOwner(this.Describe()).Build()); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+	}
 }
 
 func (this *UICF) Cleanup() bool {
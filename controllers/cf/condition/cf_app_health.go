@@ -2,36 +2,41 @@ package condition
 
 import (
 	"crypto/tls"
-	"github.com/Apicurio/apicurio-registry-operator/controllers/cf"
-	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
-	core "k8s.io/api/core/v1"
-	"net/http"
-	"os"
-	"strings"
-	"time"
 )
 
 var _ loop.ControlFunction = &AppHealthCF{}
 
+// This CF checks the health of each Apicurio Registry pod, since a request via the Service
+// would be answered by a random pod. The application is down if none of the pods is ready or live,
+// and degraded if only some of them are.
 type AppHealthCF struct {
 	ctx          context.LoopContext
 	log          *zap.SugaredLogger
 	services     services.LoopServices
 	httpClient   http.Client
 	initializing bool
+	resyncPeriod time.Duration
 
-	targetType core.ServiceType
-	targetIP   string
-
-	requestReadinessOk bool
-	requestLivenessOk  bool
+	podsRead      bool
+	pods          []ar.ApicurioRegistryStatusPod
+	readyReplicas int32
+	liveReplicas  int32
 
-	disabled bool
+	statusEntry   resources.ResourceCacheEntry
+	statusChanged bool
 }
 
 func NewAppHealthCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
@@ -45,11 +50,10 @@ func NewAppHealthCF(ctx context.LoopContext, services services.LoopServices) loo
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
 		},
-		initializing:       true,
-		requestReadinessOk: false,
-		requestLivenessOk:  false,
+		initializing: true,
 	}
 	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	res.resyncPeriod = getHealthResyncPeriod(res.log)
 	return res
 }
 
@@ -62,95 +66,124 @@ func (this *AppHealthCF) Sense() {
 	if this.ctx.GetAttempts() > 0 {
 		return
 	}
-
-	this.disabled = false
-	if entry, exists := this.ctx.GetEnvCache().Get(cf.ENV_REGISTRY_AUTH_ENABLED); exists {
-		this.disabled = strings.ToLower(entry.GetValue().Value) == "true"
+	this.podsRead = false
+	this.pods = make([]ar.ApicurioRegistryStatusPod, 0)
+	this.readyReplicas = 0
+	this.liveReplicas = 0
+	this.statusChanged = false
+
+	// Observation #1
+	// Check the health of the pods of the deployment
+	if deploymentEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_DEPLOYMENT); exists &&
+		deploymentEntry.GetName() != resources.RC_NOT_CREATED_NAME_EMPTY {
+		this.podsRead = this.checkPods()
+	}
+	this.readyReplicas, this.liveReplicas = countHealthyPods(this.pods)
+	if this.readyReplicas > 0 {
+		this.initializing = false
 	}
-	if !this.disabled {
-
-		var port = "8080"
-		var scheme = "http://"
-		if serviceEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE); exists {
-			this.targetType = serviceEntry.GetValue().(*core.Service).Spec.Type
-			this.targetIP = serviceEntry.GetValue().(*core.Service).Spec.ClusterIP
-
-			if c.HasPort("https", serviceEntry.GetValue().(*core.Service).Spec.Ports) {
-				port = "8443"
-				scheme = "https://"
-			}
 
+	if this.ctx.GetTestingSupport().IsEnabled() {
+		this.podsRead = true
+		if this.ctx.GetTestingSupport().GetMockCanMakeHTTPRequestToOperand(this.ctx.GetAppNamespace().Str()) {
+			this.initializing = false
 		}
-
-		this.requestReadinessOk = false
-		this.requestLivenessOk = false
-		if this.targetType == core.ServiceTypeClusterIP && this.targetIP != "" {
-			url := scheme + this.targetIP + ":" + port + "/health/ready"
-			res, err := this.httpClient.Get(url)
-			if err == nil {
-				// TODO Unify this with InitializingCF?
-				defer res.Body.Close()
-				if res.StatusCode == 200 {
-					this.requestReadinessOk = true
-					this.initializing = false
-				} else {
-					this.log.Warnw("request to check Apicurio Registry instance readiness has failed with a status", "url", url, "status", res.StatusCode)
-				}
-			} else if os.IsTimeout(err) {
-				this.log.Warnw("request to check Apicurio Registry instance readiness has timed out", "url", url, "timeout", this.httpClient.Timeout)
-			} else {
-				this.log.Warnw("request to check Apicurio Registry instance readiness has failed", "url", url)
-			}
-			url = scheme + this.targetIP + ":" + port + "/health/live"
-			res, err = this.httpClient.Get(url)
-			if err == nil {
-				defer res.Body.Close()
-				if res.StatusCode == 200 {
-					this.requestLivenessOk = true
-				} else {
-					this.log.Warnw("request to check Apicurio Registry instance liveness has failed with a status", "url", url, "status", res.StatusCode)
-				}
-			} else if os.IsTimeout(err) {
-				this.log.Warnw("request to check Apicurio Registry instance liveness has timed out", "url", url, "timeout", this.httpClient.Timeout)
-			} else {
-				this.log.Warnw("request to check Apicurio Registry instance liveness has failed", "url", url)
+		this.readyReplicas = 0
+		if this.ctx.GetTestingSupport().GetMockOperandMetricsReportReady(this.ctx.GetAppNamespace().Str()) {
+			this.readyReplicas = int32(len(this.pods))
+			if this.readyReplicas == 0 {
+				this.readyReplicas = 1
 			}
 		}
+		this.liveReplicas = this.readyReplicas
+	}
 
-		if this.ctx.GetTestingSupport().IsEnabled() {
-			if this.ctx.GetTestingSupport().GetMockCanMakeHTTPRequestToOperand(this.ctx.GetAppNamespace().Str()) {
-				this.initializing = false
-			}
-			this.requestLivenessOk = this.ctx.GetTestingSupport().GetMockOperandMetricsReportReady(this.ctx.GetAppNamespace().Str())
-			this.requestReadinessOk = this.ctx.GetTestingSupport().GetMockOperandMetricsReportReady(this.ctx.GetAppNamespace().Str())
-		}
+	// Observation #2
+	// Compare the replica status
+	var statusExists bool
+	this.statusEntry, statusExists = this.ctx.GetResourceCache().Get(resources.RC_KEY_STATUS)
+	if statusExists && this.podsRead {
+		status := this.statusEntry.GetValue().(*ar.ApicurioRegistryStatus)
+		this.statusChanged = status.Replicas != int32(len(this.pods)) ||
+			status.ReadyReplicas != this.readyReplicas ||
+			!reflect.DeepEqual(status.Pods, this.pods)
+	}
+}
 
-	} else {
-		this.log.Infow("health check is disabled because auth is enabled")
-		this.initializing = false
+// Returns false if the pods could not be read
+func (this *AppHealthCF) checkPods() bool {
+	pods, err := getOperandPods(this.ctx, this.services.GetKubeFactory())
+	if err != nil {
+		this.log.Warnw("could not list the Apicurio Registry pods", "error", err)
+		return false
 	}
+	// The pods are checked in parallel, so the duration of the checks does not depend on the number of replicas
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	for i := range pods {
+		pod := &pods[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			podHealth := checkPodHealth(this.log, &this.httpClient, getPodBaseUrl(this.ctx, pod), pod)
+			lock.Lock()
+			defer lock.Unlock()
+			this.pods = append(this.pods, podHealth)
+		}()
+	}
+	wg.Wait()
+	sort.Slice(this.pods, func(i, j int) bool {
+		return this.pods[i].Name < this.pods[j].Name
+	})
+	return true
 }
 
 func (this *AppHealthCF) Compare() bool {
 	// Executing AFTER initialization,
 	// that part is handled by InitializingCF
 	// Prevent loop from getting stable by only executing once
-	return !this.disabled && !this.initializing && this.ctx.GetAttempts() == 0
+	return !this.initializing && this.ctx.GetAttempts() == 0
 }
 
 func (this *AppHealthCF) Respond() {
-	if !this.requestReadinessOk {
+	// Response #1
+	// Update the replica status
+	if this.statusChanged {
+		this.statusEntry.ApplyPatch(func(value interface{}) interface{} {
+			status := value.(*ar.ApicurioRegistryStatus).DeepCopy()
+			status.Replicas = int32(len(this.pods))
+			status.ReadyReplicas = this.readyReplicas
+			status.Pods = this.pods
+			return status
+		})
+	}
+
+	// Response #2
+	// Report the aggregated health, the application is down if none of the pods is ready or live.
+	// The reconciler is executed again when a pod changes, so only failures to read the pods are retried soon.
+	if !this.podsRead {
+		this.ctx.SetRequeueDelaySoon()
+		return
+	}
+	total := int32(len(this.pods))
+	if this.ctx.GetTestingSupport().IsEnabled() && total == 0 {
+		total = 1
+	}
+	if this.readyReplicas == 0 {
 		this.services.GetConditionManager().GetApplicationNotHealthyCondition().TransitionNotReady()
 		this.services.GetConditionManager().GetReadyCondition().TransitionError()
-		this.ctx.SetRequeueDelaySoon()
 	}
-	if !this.requestLivenessOk {
+	if this.liveReplicas == 0 {
 		this.services.GetConditionManager().GetApplicationNotHealthyCondition().TransitionNotLive()
 		this.services.GetConditionManager().GetReadyCondition().TransitionError()
-		this.ctx.SetRequeueDelaySoon()
+	}
+	if this.readyReplicas < total || this.liveReplicas < total {
+		this.services.GetConditionManager().GetApplicationNotHealthyCondition().TransitionReplicasNotHealthy(
+			strconv.Itoa(int(this.readyReplicas)) + " of " + strconv.Itoa(int(total)) + " replicas are ready, and " +
+				strconv.Itoa(int(this.liveReplicas)) + " are live. Please check the status of the pods.")
 	}
 
-	this.ctx.SetRequeueDelaySec(3 * 60) // 3 min
+	this.ctx.SetRequeueDelaySec(uint(this.resyncPeriod.Seconds()))
 
 	if this.ctx.GetTestingSupport().IsEnabled() && !this.ctx.GetTestingSupport().GetMockOperandMetricsReportReady(this.ctx.GetAppNamespace().Str()) {
 		this.ctx.SetRequeueNow() // Ensure the reconciler is executed again very soon
@@ -0,0 +1,216 @@
+package condition
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var _ loop.ControlFunction = &DegradedCF{}
+
+// An OOMKilled container that has been running since then is reported only for this long
+const oomKilledReportPeriod = 10 * time.Minute
+
+// A problem with a container of an Apicurio Registry pod
+type podProblem struct {
+	reason    conditions.DegradedConditionReason
+	pod       string
+	container string
+	restarts  int32
+	message   string
+}
+
+// This CF inspects the container statuses of the Apicurio Registry pods,
+// and reports crash loops, image pull failures and OOMKilled containers in the Degraded condition.
+// A Warning Event is recorded for each new problem, or when the container has been restarted again.
+type DegradedCF struct {
+	ctx      context.LoopContext
+	log      *zap.SugaredLogger
+	services services.LoopServices
+
+	podsRead bool
+	problems []podProblem
+	owner    *ar.ApicurioRegistry
+
+	// Restart counts of the problems that have already been recorded as Events, by pod, container and reason
+	recordedProblems map[string]int32
+}
+
+func NewDegradedCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &DegradedCF{
+		ctx:              ctx,
+		services:         services,
+		recordedProblems: make(map[string]int32),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *DegradedCF) Describe() string {
+	return "DegradedCF"
+}
+
+func (this *DegradedCF) Sense() {
+	// Avoid listing the pods more than once per loop
+	if this.ctx.GetAttempts() > 0 {
+		return
+	}
+	this.podsRead = false
+	this.problems = nil
+	this.owner = nil
+
+	// Observation #1
+	// Inspect the pods of the application
+	pods, err := getOperandPods(this.ctx, this.services.GetKubeFactory())
+	if err != nil {
+		this.log.Warnw("could not list the Apicurio Registry pods", "error", err)
+		return
+	}
+	this.podsRead = true
+	this.problems = findPodProblems(pods, time.Now())
+
+	// Observation #2
+	// Get the ApicurioRegistry the Events are recorded for
+	if specEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SPEC); exists {
+		this.owner = specEntry.GetValue().(*ar.ApicurioRegistry)
+	}
+}
+
+func (this *DegradedCF) Compare() bool {
+	// Prevent loop from getting stable by only executing once
+	return this.podsRead && this.ctx.GetAttempts() == 0
+}
+
+func (this *DegradedCF) Respond() {
+	// Response #1
+	// Report the most important problem
+	if len(this.problems) > 0 {
+		problem := this.problems[0]
+		message := problem.message
+		if len(this.problems) > 1 {
+			message += " (" + strconv.Itoa(len(this.problems)-1) + " more problem(s) found, see the Events for details)"
+		}
+		switch problem.reason {
+		case conditions.DEGRADED_REASON_IMAGE_PULL_BACK_OFF:
+			this.services.GetConditionManager().GetDegradedCondition().TransitionImagePullBackOff(message)
+		case conditions.DEGRADED_REASON_OOM_KILLED:
+			this.services.GetConditionManager().GetDegradedCondition().TransitionOOMKilled(message)
+		case conditions.DEGRADED_REASON_CRASH_LOOP_BACK_OFF:
+			this.services.GetConditionManager().GetDegradedCondition().TransitionCrashLoopBackOff(message)
+		}
+	}
+
+	// Response #2
+	// Record an Event for each new problem, or if the container has been restarted since
+	recordedProblems := make(map[string]int32, len(this.problems))
+	for _, problem := range this.problems {
+		key := problem.pod + "/" + problem.container + "/" + string(problem.reason)
+		if restarts, exists := this.recordedProblems[key]; exists && restarts >= problem.restarts {
+			recordedProblems[key] = restarts
+			continue
+		}
+		this.log.Warnw("Apicurio Registry pod is degraded", "pod", problem.pod, "reason", problem.reason, "message", problem.message)
+		if this.owner != nil {
+			this.ctx.GetEventRecorder().Event(this.owner, core.EventTypeWarning, string(problem.reason), problem.message)
+		}
+		recordedProblems[key] = problem.restarts
+	}
+	this.recordedProblems = recordedProblems
+}
+
+func (this *DegradedCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Find the problems with the containers of the pods, ordered by priority
+func findPodProblems(pods []core.Pod, now time.Time) []podProblem {
+	res := make([]podProblem, 0)
+	for i := range pods {
+		pod := &pods[i]
+		statuses := append(append([]core.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if problem, found := findContainerProblem(pod, &status, now); found {
+				res = append(res, problem)
+			}
+		}
+	}
+	priorities := map[conditions.DegradedConditionReason]int{
+		conditions.DEGRADED_REASON_IMAGE_PULL_BACK_OFF: 0,
+		conditions.DEGRADED_REASON_OOM_KILLED:          1,
+		conditions.DEGRADED_REASON_CRASH_LOOP_BACK_OFF: 2,
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if priorities[res[i].reason] != priorities[res[j].reason] {
+			return priorities[res[i].reason] < priorities[res[j].reason]
+		}
+		if res[i].pod != res[j].pod {
+			return res[i].pod < res[j].pod
+		}
+		return res[i].container < res[j].container
+	})
+	return res
+}
+
+func findContainerProblem(pod *core.Pod, status *core.ContainerStatus, now time.Time) (podProblem, bool) {
+	res := podProblem{
+		pod:       pod.Name,
+		container: status.Name,
+		restarts:  status.RestartCount,
+	}
+	prefix := "container " + status.Name + " in pod " + pod.Name
+	waitingReason := ""
+	if status.State.Waiting != nil {
+		waitingReason = status.State.Waiting.Reason
+	}
+	switch {
+	case waitingReason == "ImagePullBackOff" || waitingReason == "ErrImagePull" || waitingReason == "InvalidImageName":
+		res.reason = conditions.DEGRADED_REASON_IMAGE_PULL_BACK_OFF
+		res.message = prefix + " cannot pull image " + status.Image + " (" + waitingReason + ")"
+	case isOOMKilled(status, now):
+		res.reason = conditions.DEGRADED_REASON_OOM_KILLED
+		res.message = prefix + " OOMKilled" + formatRestarts(pod, status, now)
+	case waitingReason == "CrashLoopBackOff":
+		res.reason = conditions.DEGRADED_REASON_CRASH_LOOP_BACK_OFF
+		res.message = prefix + " is in CrashLoopBackOff" + formatRestarts(pod, status, now)
+	default:
+		return res, false
+	}
+	return res, true
+}
+
+// The container is OOMKilled if it has not recovered yet, or if it has happened recently
+func isOOMKilled(status *core.ContainerStatus, now time.Time) bool {
+	if terminated := status.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		return true
+	}
+	if terminated := status.LastTerminationState.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		return status.State.Running == nil || now.Sub(terminated.FinishedAt.Time) < oomKilledReportPeriod
+	}
+	return false
+}
+
+// Format the restart count of the container, e.g. ", restarted 3 times in 10m"
+func formatRestarts(pod *core.Pod, status *core.ContainerStatus, now time.Time) string {
+	if status.RestartCount == 0 {
+		return ""
+	}
+	res := ", restarted " + strconv.Itoa(int(status.RestartCount)) + " times"
+	if status.RestartCount == 1 {
+		res = ", restarted once"
+	}
+	if pod.Status.StartTime != nil {
+		res += " in " + duration.HumanDuration(now.Sub(pod.Status.StartTime.Time))
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package condition
+
+import (
+	"testing"
+	"time"
+
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindPodProblems(t *testing.T) {
+	now := time.Now()
+	startTime := meta.NewTime(now.Add(-10 * time.Minute))
+	pods := []core.Pod{
+		{
+			ObjectMeta: meta.ObjectMeta{Name: "registry-1"},
+			Status: core.PodStatus{
+				StartTime: &startTime,
+				ContainerStatuses: []core.ContainerStatus{{
+					Name:         "registry",
+					RestartCount: 3,
+					State: core.ContainerState{
+						Waiting: &core.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+					LastTerminationState: core.ContainerState{
+						Terminated: &core.ContainerStateTerminated{Reason: "OOMKilled", FinishedAt: meta.NewTime(now.Add(-time.Minute))},
+					},
+				}},
+			},
+		},
+		{
+			ObjectMeta: meta.ObjectMeta{Name: "registry-2"},
+			Status: core.PodStatus{
+				StartTime: &startTime,
+				ContainerStatuses: []core.ContainerStatus{{
+					Name:         "registry",
+					RestartCount: 1,
+					State: core.ContainerState{
+						Waiting: &core.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+					LastTerminationState: core.ContainerState{
+						Terminated: &core.ContainerStateTerminated{Reason: "Error", FinishedAt: meta.NewTime(now.Add(-time.Minute))},
+					},
+				}},
+			},
+		},
+		{
+			ObjectMeta: meta.ObjectMeta{Name: "registry-3"},
+			Status: core.PodStatus{
+				InitContainerStatuses: []core.ContainerStatus{{
+					Name:  "registry-truststore-init",
+					Image: "registry:missing",
+					State: core.ContainerState{
+						Waiting: &core.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+					},
+				}},
+			},
+		},
+		{
+			// Recovered from an OOMKilled a long time ago
+			ObjectMeta: meta.ObjectMeta{Name: "registry-4"},
+			Status: core.PodStatus{
+				ContainerStatuses: []core.ContainerStatus{{
+					Name:         "registry",
+					RestartCount: 1,
+					State: core.ContainerState{
+						Running: &core.ContainerStateRunning{},
+					},
+					LastTerminationState: core.ContainerState{
+						Terminated: &core.ContainerStateTerminated{Reason: "OOMKilled", FinishedAt: meta.NewTime(now.Add(-time.Hour))},
+					},
+				}},
+			},
+		},
+	}
+
+	problems := findPodProblems(pods, now)
+	c.AssertEquals(t, 3, len(problems))
+	c.AssertEquals(t, conditions.DEGRADED_REASON_IMAGE_PULL_BACK_OFF, problems[0].reason)
+	c.AssertEquals(t, "container registry-truststore-init in pod registry-3 cannot pull image registry:missing (ImagePullBackOff)", problems[0].message)
+	c.AssertEquals(t, conditions.DEGRADED_REASON_OOM_KILLED, problems[1].reason)
+	c.AssertEquals(t, "container registry in pod registry-1 OOMKilled, restarted 3 times in 10m", problems[1].message)
+	c.AssertEquals(t, conditions.DEGRADED_REASON_CRASH_LOOP_BACK_OFF, problems[2].reason)
+	c.AssertEquals(t, "container registry in pod registry-2 is in CrashLoopBackOff, restarted once in 10m", problems[2].message)
+
+	c.AssertEquals(t, 0, len(findPodProblems(pods[3:], now)))
+}
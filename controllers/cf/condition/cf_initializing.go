@@ -2,22 +2,19 @@ package condition
 
 import (
 	"crypto/tls"
-	"github.com/Apicurio/apicurio-registry-operator/controllers/cf"
-	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
-	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
 	core "k8s.io/api/core/v1"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 )
 
 var _ loop.ControlFunction = &InitializingCF{}
 
+const initializingRequeueDelay = 30 // sec
+
 type InitializingCF struct {
 	ctx          context.LoopContext
 	log          *zap.SugaredLogger
@@ -25,12 +22,7 @@ type InitializingCF struct {
 	httpClient   http.Client
 	initializing bool
 
-	targetType core.ServiceType
-	targetIP   string
-
 	requestOk bool
-
-	disabled bool
 }
 
 func NewInitializingCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
@@ -62,65 +54,42 @@ func (this *InitializingCF) Sense() {
 		return
 	}
 
-	this.disabled = false
-	if entry, exists := this.ctx.GetEnvCache().Get(cf.ENV_REGISTRY_AUTH_ENABLED); exists {
-		this.disabled = strings.ToLower(entry.GetValue().Value) == "true"
-	}
-	if !this.disabled {
-
-		// The application is initialized if we can make an HTTP request to the app via the Service
-		// (as Ingress/Route might not work on some systems, or without additional config).
-
-		var port string = "8080"
-		var scheme string = "http://"
-		if serviceEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE); exists {
-			this.targetType = serviceEntry.GetValue().(*core.Service).Spec.Type
-			this.targetIP = serviceEntry.GetValue().(*core.Service).Spec.ClusterIP
-
-			if c.HasPort("https", serviceEntry.GetValue().(*core.Service).Spec.Ports) {
-				port = "8443"
-				scheme = "https://"
+	// The application is initialized if any of the pods responds to the readiness check,
+	// which works also when the authentication is enabled.
+	// The liveness check is not used, because it can succeed before the application is able to serve requests.
+	// The pods are checked directly, because the Service endpoints might not be updated yet
+	// when the reconciler is executed after a pod change.
+	this.requestOk = false
+	if pods, err := getOperandPods(this.ctx, this.services.GetKubeFactory()); err == nil {
+		for i := range pods {
+			baseUrl := getPodBaseUrl(this.ctx, &pods[i])
+			if pods[i].Status.Phase == core.PodRunning && baseUrl != "" &&
+				checkOperandHealth(this.log, &this.httpClient, baseUrl+healthReadyPath, "readiness") == "" {
+				this.requestOk = true
+				break
 			}
-
-		}
-
-		this.requestOk = false
-		if this.targetType == core.ServiceTypeClusterIP && this.targetIP != "" {
-			url := scheme + this.targetIP + ":" + port
-			res, err := this.httpClient.Get(url)
-			if err == nil {
-				defer res.Body.Close()
-				if res.StatusCode >= 200 && res.StatusCode < 300 {
-					this.requestOk = true
-				} else {
-					this.log.Warnw("request to check that Apicurio Registry instance is available has failed with a status", "url", url, "status", res.StatusCode)
-				}
-			} else if os.IsTimeout(err) {
-				this.log.Warnw("request to check that Apicurio Registry instance is available has timed out", "url", url, "timeout", this.httpClient.Timeout)
-			} else {
-				this.log.Warnw("request to check that Apicurio Registry instance is available has failed", "url", url)
-			}
-		}
-
-		if this.ctx.GetTestingSupport().IsEnabled() {
-			this.requestOk = this.ctx.GetTestingSupport().GetMockCanMakeHTTPRequestToOperand(this.ctx.GetAppNamespace().Str())
 		}
 	} else {
-		this.log.Infow("initializing health check is disabled because auth is enabled")
-		this.initializing = false
+		this.log.Warnw("could not list the Apicurio Registry pods", "error", err)
+	}
+
+	if this.ctx.GetTestingSupport().IsEnabled() {
+		this.requestOk = this.ctx.GetTestingSupport().GetMockCanMakeHTTPRequestToOperand(this.ctx.GetAppNamespace().Str())
 	}
 }
 
 func (this *InitializingCF) Compare() bool {
 	// Executing only when initializing
 	// Prevent loop from getting stable by only executing once
-	return !this.disabled && this.initializing && this.ctx.GetAttempts() == 0
+	return this.initializing && this.ctx.GetAttempts() == 0
 }
 
 func (this *InitializingCF) Respond() {
 	if !this.requestOk {
 		this.services.GetConditionManager().GetReadyCondition().TransitionInitializing()
-		this.ctx.SetRequeueDelaySoon()
+		// The reconciler is executed again when a pod changes,
+		// but the application might start responding later than the pod is running
+		this.ctx.SetRequeueDelaySec(initializingRequeueDelay)
 	} else {
 		this.initializing = false
 		this.httpClient.CloseIdleConnections()
@@ -0,0 +1,120 @@
+package condition
+
+import (
+	"strconv"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+var _ loop.ControlFunction = &ProgressingCF{}
+
+// This CF reports the rollout of the Apicurio Registry deployment in the Progressing condition,
+// similarly to the `kubectl rollout status` command.
+// The reconciler is executed when the deployment status changes, so the condition follows the rollout.
+type ProgressingCF struct {
+	ctx      context.LoopContext
+	log      *zap.SugaredLogger
+	services services.LoopServices
+
+	deploymentExists bool
+	reason           conditions.ProgressingConditionReason
+	message          string
+}
+
+func NewProgressingCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &ProgressingCF{
+		ctx:      ctx,
+		services: services,
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *ProgressingCF) Describe() string {
+	return "ProgressingCF"
+}
+
+func (this *ProgressingCF) Sense() {
+	if this.ctx.GetAttempts() > 0 {
+		return
+	}
+	this.deploymentExists = false
+
+	// Observation #1
+	// Read the rollout status of the deployment
+	if deploymentEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_DEPLOYMENT); exists {
+		this.deploymentExists = true
+		if deploymentEntry.GetName() == resources.RC_NOT_CREATED_NAME_EMPTY {
+			this.reason = conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS
+			this.message = "The deployment is being created."
+		} else if deploymentEntry.HasChanged() {
+			// This CF runs after the deployment modifiers, a rollout starts when their changes are applied
+			this.reason = conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS
+			this.message = "The deployment is being updated."
+		} else {
+			this.reason, this.message = getRolloutStatus(deploymentEntry.GetValue().(*apps.Deployment))
+		}
+	}
+}
+
+func (this *ProgressingCF) Compare() bool {
+	// Prevent loop from getting stable by only executing once
+	return this.deploymentExists && this.ctx.GetAttempts() == 0
+}
+
+func (this *ProgressingCF) Respond() {
+	// Response #1
+	// Report the rollout status
+	switch this.reason {
+	case conditions.PROGRESSING_REASON_DEADLINE_EXCEEDED:
+		this.services.GetConditionManager().GetProgressingCondition().TransitionProgressDeadlineExceeded(this.message)
+	case conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS:
+		this.services.GetConditionManager().GetProgressingCondition().TransitionRolloutInProgress(this.message)
+	case conditions.PROGRESSING_REASON_ROLLOUT_COMPLETE:
+		this.services.GetConditionManager().GetProgressingCondition().TransitionRolloutComplete(this.message)
+	}
+}
+
+func (this *ProgressingCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Return the rollout status of the deployment, using the same rules as the `kubectl rollout status` command
+func getRolloutStatus(deployment *apps.Deployment) (conditions.ProgressingConditionReason, string) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, "Waiting for the deployment spec update to be observed."
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == apps.DeploymentProgressing && condition.Status == core.ConditionFalse &&
+			condition.Reason == string(conditions.PROGRESSING_REASON_DEADLINE_EXCEEDED) {
+			return conditions.PROGRESSING_REASON_DEADLINE_EXCEEDED, "The rollout has exceeded its progress deadline. " +
+				"Please check the status of the pods."
+		}
+	}
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.UpdatedReplicas < replicas {
+		return conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, strconv.Itoa(int(status.UpdatedReplicas)) + " of " +
+			strconv.Itoa(int(replicas)) + " new replicas have been updated."
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, strconv.Itoa(int(status.Replicas-status.UpdatedReplicas)) +
+			" old replicas are pending termination."
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, strconv.Itoa(int(status.AvailableReplicas)) + " of " +
+			strconv.Itoa(int(status.UpdatedReplicas)) + " updated replicas are available."
+	}
+	return conditions.PROGRESSING_REASON_ROLLOUT_COMPLETE, "The deployment has been successfully rolled out."
+}
@@ -0,0 +1,50 @@
+package condition
+
+import (
+	"testing"
+
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+func TestRolloutStatus(t *testing.T) {
+	var replicas int32 = 2
+	deployment := &apps.Deployment{}
+	deployment.Generation = 2
+	deployment.Spec.Replicas = &replicas
+	deployment.Status.ObservedGeneration = 1
+
+	assertRolloutStatus := func(expectedReason conditions.ProgressingConditionReason, expectedMessage string) {
+		t.Helper()
+		reason, message := getRolloutStatus(deployment)
+		c.AssertEquals(t, expectedReason, reason)
+		c.AssertEquals(t, expectedMessage, message)
+	}
+
+	assertRolloutStatus(conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, "Waiting for the deployment spec update to be observed.")
+
+	deployment.Status.ObservedGeneration = 2
+	deployment.Status.Replicas = 3
+	deployment.Status.UpdatedReplicas = 1
+	assertRolloutStatus(conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, "1 of 2 new replicas have been updated.")
+
+	deployment.Status.UpdatedReplicas = 2
+	assertRolloutStatus(conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, "1 old replicas are pending termination.")
+
+	deployment.Status.Replicas = 2
+	deployment.Status.AvailableReplicas = 1
+	assertRolloutStatus(conditions.PROGRESSING_REASON_ROLLOUT_IN_PROGRESS, "1 of 2 updated replicas are available.")
+
+	deployment.Status.Conditions = []apps.DeploymentCondition{{
+		Type:   apps.DeploymentProgressing,
+		Status: core.ConditionFalse,
+		Reason: "ProgressDeadlineExceeded",
+	}}
+	assertRolloutStatus(conditions.PROGRESSING_REASON_DEADLINE_EXCEEDED, "The rollout has exceeded its progress deadline. Please check the status of the pods.")
+
+	deployment.Status.Conditions = nil
+	deployment.Status.AvailableReplicas = 2
+	assertRolloutStatus(conditions.PROGRESSING_REASON_ROLLOUT_COMPLETE, "The deployment has been successfully rolled out.")
+}
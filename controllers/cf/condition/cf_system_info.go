@@ -0,0 +1,131 @@
+package condition
+
+import (
+	"strings"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ loop.ControlFunction = &SystemInfoCF{}
+
+// This CF reads the version of the running Apicurio Registry application, so it can be reported in the status.
+// The version is read again only after the image has changed and the rollout is complete.
+type SystemInfoCF struct {
+	ctx       context.LoopContext
+	log       *zap.SugaredLogger
+	services  services.LoopServices
+	svcStatus *status.Status
+
+	image        string
+	version      string
+	versionImage string
+	readVersion  bool
+}
+
+func NewSystemInfoCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &SystemInfoCF{
+		ctx:       ctx,
+		services:  services,
+		svcStatus: services.GetStatus(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *SystemInfoCF) Describe() string {
+	return "SystemInfoCF"
+}
+
+func (this *SystemInfoCF) Sense() {
+	// Observation #1
+	// Get the image of the deployment
+	this.image = this.svcStatus.GetConfig(status.CFG_STA_IMAGE)
+
+	// Observation #2
+	// The version can be read if the rollout of the image is complete, and some of the pods are ready
+	this.readVersion = false
+	if this.ctx.GetAttempts() == 0 && this.image != "" && this.image != this.versionImage &&
+		!this.ctx.GetTestingSupport().IsEnabled() {
+		rolledOut := false
+		if deploymentEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_DEPLOYMENT); exists &&
+			deploymentEntry.GetName() != resources.RC_NOT_CREATED_NAME_EMPTY && !deploymentEntry.HasChanged() {
+			reason, _ := getRolloutStatus(deploymentEntry.GetValue().(*apps.Deployment))
+			rolledOut = reason == conditions.PROGRESSING_REASON_ROLLOUT_COMPLETE
+		}
+		ready := false
+		if statusEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_STATUS); exists {
+			ready = statusEntry.GetValue().(*ar.ApicurioRegistryStatus).ReadyReplicas > 0
+		}
+		this.readVersion = rolledOut && ready && getOperandBaseUrl(this.ctx) != ""
+	}
+
+	// Update the status, the version of the previous image is not reported
+	if this.image == this.versionImage {
+		this.svcStatus.SetConfig(status.CFG_STA_VERSION, this.version)
+	} else {
+		this.svcStatus.SetConfig(status.CFG_STA_VERSION, "")
+	}
+}
+
+func (this *SystemInfoCF) Compare() bool {
+	// Condition #1
+	// The image has changed, executed only once per loop, so a failing request is retried in the next one
+	return this.readVersion
+}
+
+func (this *SystemInfoCF) Respond() {
+	// Response #1
+	// Read the version
+	endpoint, err := this.getEndpoint()
+	var info *client.SystemInfo
+	if err == nil {
+		info, err = this.ctx.GetClients().Registry().GetSystemInfo(endpoint)
+	}
+	if err != nil {
+		this.log.Warnw("could not read the Apicurio Registry version", "error", err)
+		return
+	}
+	this.version = info.Version
+	this.versionImage = this.image
+	this.svcStatus.SetConfig(status.CFG_STA_VERSION, this.version)
+}
+
+func (this *SystemInfoCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// The Service is called using its ClusterIP, so the HTTPS certificate is verified using the Service DNS name
+func (this *SystemInfoCF) getEndpoint() (*client.RegistryEndpoint, error) {
+	endpoint := &client.RegistryEndpoint{BaseUrl: getOperandBaseUrl(this.ctx)}
+	if !strings.HasPrefix(endpoint.BaseUrl, "https://") {
+		return endpoint, nil
+	}
+	if serviceEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE); exists {
+		endpoint.ServerName = serviceEntry.GetValue().(*core.Service).Name + "." + this.ctx.GetAppNamespace().Str() + ".svc"
+	}
+	var httpsSecret *core.Secret
+	if specEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SPEC); exists {
+		if secretName := specEntry.GetValue().(*ar.ApicurioRegistry).Spec.Configuration.Security.Https.SecretName; secretName != "" {
+			var err error
+			httpsSecret, err = this.ctx.GetClients().Kube().GetSecret(this.ctx.GetAppNamespace(), c.Name(secretName), &meta.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	endpoint.CaBundle = client.GetRegistryCaBundle(httpsSecret)
+	return endpoint, nil
+}
@@ -0,0 +1,150 @@
+package condition
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// The health endpoints are used by the probes, so they are not secured even when the authentication is enabled
+	healthLivePath  = "/health/live"
+	healthReadyPath = "/health/ready"
+)
+
+// The health is updated when the pods or the deployment change,
+// so the health checks are repeated periodically only as a fallback.
+const ENV_OPERATOR_HEALTH_RESYNC_PERIOD = "HEALTH_RESYNC_PERIOD"
+
+const defaultHealthResyncPeriod = 10 * time.Minute
+
+// Return the period of the health checks, configured using a duration string such as "10m"
+func getHealthResyncPeriod(log *zap.SugaredLogger) time.Duration {
+	value := os.Getenv(ENV_OPERATOR_HEALTH_RESYNC_PERIOD)
+	if value == "" {
+		return defaultHealthResyncPeriod
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil || period < time.Second {
+		log.Warnw("invalid value of the "+ENV_OPERATOR_HEALTH_RESYNC_PERIOD+" environment variable, using the default",
+			"value", value, "default", defaultHealthResyncPeriod)
+		return defaultHealthResyncPeriod
+	}
+	return period
+}
+
+// Return the Apicurio Registry pods that are not being deleted.
+// The pods are watched, so they are read from the cache, instead of listing them on every health check.
+func getOperandPods(ctx context.LoopContext, kubeFactory *factory.KubeFactory) ([]core.Pod, error) {
+	podList, err := ctx.GetClients().Kube().GetCachedPods(ctx.GetAppNamespace(), kubeFactory.GetSelectorLabels())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]core.Pod, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp == nil {
+			res = append(res, pod)
+		}
+	}
+	return res, nil
+}
+
+// Return the scheme and port of the Apicurio Registry instance, HTTPS is used if it is enabled on the Service
+func getOperandSchemeAndPort(ctx context.LoopContext) (string, string) {
+	if serviceEntry, exists := ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE); exists &&
+		c.HasPort("https", serviceEntry.GetValue().(*core.Service).Spec.Ports) {
+		return "https://", "8443"
+	}
+	return "http://", "8080"
+}
+
+// Return the URL of the Apicurio Registry instance via the Service
+// (as Ingress/Route might not work on some systems, or without additional config),
+// or an empty string if the Service is not available.
+func getOperandBaseUrl(ctx context.LoopContext) string {
+	serviceEntry, exists := ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE)
+	if !exists {
+		return ""
+	}
+	service := serviceEntry.GetValue().(*core.Service)
+	if service.Spec.Type != core.ServiceTypeClusterIP || service.Spec.ClusterIP == "" {
+		return ""
+	}
+	scheme, port := getOperandSchemeAndPort(ctx)
+	return scheme + net.JoinHostPort(service.Spec.ClusterIP, port)
+}
+
+// Return the URL of the Apicurio Registry pod, or an empty string if the pod does not have an IP address yet
+func getPodBaseUrl(ctx context.LoopContext, pod *core.Pod) string {
+	if pod.Status.PodIP == "" {
+		return ""
+	}
+	scheme, port := getOperandSchemeAndPort(ctx)
+	return scheme + net.JoinHostPort(pod.Status.PodIP, port)
+}
+
+// Make a request to the health endpoint.
+// Returns an empty string if it responds with a success status, or the problem otherwise.
+func checkOperandHealth(log *zap.SugaredLogger, httpClient *http.Client, url string, check string) string {
+	res, err := httpClient.Get(url)
+	if err == nil {
+		defer res.Body.Close()
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return ""
+		}
+		log.Warnw("request to check Apicurio Registry instance "+check+" has failed with a status", "url", url, "status", res.StatusCode)
+		return "The " + check + " check has failed with status " + strconv.Itoa(res.StatusCode) + "."
+	} else if os.IsTimeout(err) {
+		log.Warnw("request to check Apicurio Registry instance "+check+" has timed out", "url", url, "timeout", httpClient.Timeout)
+		return "The " + check + " check has timed out."
+	} else {
+		log.Warnw("request to check Apicurio Registry instance "+check+" has failed", "url", url)
+		return "The " + check + " check has failed."
+	}
+}
+
+// Check the readiness and liveness of a pod, using the pod URL.
+// The checks are made only for running pods.
+func checkPodHealth(log *zap.SugaredLogger, httpClient *http.Client, baseUrl string, pod *core.Pod) ar.ApicurioRegistryStatusPod {
+	res := ar.ApicurioRegistryStatusPod{
+		Name: pod.Name,
+	}
+	if pod.Status.Phase != core.PodRunning || baseUrl == "" {
+		res.Message = "The pod is " + string(pod.Status.Phase) + "."
+		return res
+	}
+	readyProblem := checkOperandHealth(log, httpClient, baseUrl+healthReadyPath, "readiness")
+	liveProblem := checkOperandHealth(log, httpClient, baseUrl+healthLivePath, "liveness")
+	res.Ready = readyProblem == ""
+	res.Live = liveProblem == ""
+	if liveProblem != "" {
+		res.Message = liveProblem
+	} else {
+		res.Message = readyProblem
+	}
+	return res
+}
+
+// Return the number of the ready and live pods
+func countHealthyPods(pods []ar.ApicurioRegistryStatusPod) (int32, int32) {
+	var ready, live int32
+	for _, pod := range pods {
+		if pod.Ready {
+			ready++
+		}
+		if pod.Live {
+			live++
+		}
+	}
+	return ready, live
+}
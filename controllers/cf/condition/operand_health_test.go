@@ -0,0 +1,116 @@
+package condition
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestOperandHealth(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	c.AssertEquals(t, "", getOperandBaseUrl(ctx))
+
+	service := &core.Service{
+		Spec: core.ServiceSpec{
+			Type:      core.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+			Ports:     []core.ServicePort{{Name: "http", Port: 8080}},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SERVICE, resources.NewResourceCacheEntry(ctx.GetAppName(), service))
+	c.AssertEquals(t, "http://10.0.0.1:8080", getOperandBaseUrl(ctx))
+
+	// The health endpoints respond without credentials, while the rest of the API requires them
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthLivePath {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	log := ctx.GetLog().Sugar()
+	c.AssertEquals(t, "", checkOperandHealth(log, server.Client(), server.URL+healthLivePath, "liveness"))
+	c.AssertEquals(t, "The readiness check has failed with status 401.", checkOperandHealth(log, server.Client(), server.URL+healthReadyPath, "readiness"))
+}
+
+func TestPodHealth(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	log := ctx.GetLog().Sugar()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthReadyPath {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	pod := &core.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: "registry-1"},
+		Status:     core.PodStatus{Phase: core.PodRunning, PodIP: "10.0.0.2"},
+	}
+	c.AssertEquals(t, "http://10.0.0.2:8080", getPodBaseUrl(ctx, pod))
+	c.AssertEquals(t, ar.ApicurioRegistryStatusPod{
+		Name:    "registry-1",
+		Live:    true,
+		Message: "The readiness check has failed with status 503.",
+	}, checkPodHealth(log, server.Client(), server.URL, pod))
+
+	pending := &core.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: "registry-2"},
+		Status:     core.PodStatus{Phase: core.PodPending},
+	}
+	c.AssertEquals(t, "", getPodBaseUrl(ctx, pending))
+	c.AssertEquals(t, ar.ApicurioRegistryStatusPod{
+		Name:    "registry-2",
+		Message: "The pod is Pending.",
+	}, checkPodHealth(log, server.Client(), "", pending))
+
+	ready, live := countHealthyPods([]ar.ApicurioRegistryStatusPod{
+		{Name: "registry-1", Ready: true, Live: true},
+		{Name: "registry-2", Live: true},
+		{Name: "registry-3"},
+	})
+	c.AssertEquals(t, int32(1), ready)
+	c.AssertEquals(t, int32(2), live)
+}
+
+func TestOperandPods(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	now := meta.Now()
+	cachedReader := fake.NewClientBuilder().WithObjects(
+		&core.Pod{ObjectMeta: meta.ObjectMeta{Name: "mock-1", Namespace: "mock", Labels: map[string]string{"app": "mock"}}},
+		&core.Pod{ObjectMeta: meta.ObjectMeta{Name: "mock-2", Namespace: "mock", Labels: map[string]string{"app": "mock"},
+			DeletionTimestamp: &now}},
+		&core.Pod{ObjectMeta: meta.ObjectMeta{Name: "other-1", Namespace: "mock", Labels: map[string]string{"app": "other"}}},
+		&core.Pod{ObjectMeta: meta.ObjectMeta{Name: "mock-1", Namespace: "other", Labels: map[string]string{"app": "mock"}}},
+	).Build()
+	// The pods are read only from the cache, the API server is not used
+	ctx.SetClients(client.NewClients(zap.NewNop(), scheme.Scheme, &rest.Config{Host: "http://127.0.0.1:1"}, cachedReader))
+
+	pods, err := getOperandPods(ctx, factory.NewKubeFactory(ctx))
+	c.AssertEquals(t, nil, err)
+	c.AssertEquals(t, 1, len(pods))
+	c.AssertEquals(t, "mock-1", pods[0].Name)
+	c.AssertEquals(t, "mock", pods[0].Namespace)
+}
+
+func TestHealthResyncPeriod(t *testing.T) {
+	log := context.NewLoopContextMock().GetLog().Sugar()
+	c.AssertEquals(t, defaultHealthResyncPeriod, getHealthResyncPeriod(log))
+	t.Setenv(ENV_OPERATOR_HEALTH_RESYNC_PERIOD, "30m")
+	c.AssertEquals(t, 30*time.Minute, getHealthResyncPeriod(log))
+	t.Setenv(ENV_OPERATOR_HEALTH_RESYNC_PERIOD, "30")
+	c.AssertEquals(t, defaultHealthResyncPeriod, getHealthResyncPeriod(log))
+}
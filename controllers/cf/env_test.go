@@ -3,15 +3,19 @@ package cf
 import (
 	v1 "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
 	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
 	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +23,7 @@ func TestEnvCF(t *testing.T) {
 	ctx := context.NewLoopContextMock()
 	services := services2.NewLoopServicesMock(ctx)
 	loop := loop_impl.NewControlLoopImpl(ctx, services)
-	loop.AddControlFunction(NewEnvCF(ctx))
+	loop.AddControlFunction(NewEnvCF(ctx, services))
 
 	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
 		Spec: v1.ApicurioRegistrySpec{
@@ -55,7 +59,7 @@ func TestEnvCF(t *testing.T) {
 			Name:  "VAR_3_NAME",
 			Value: "VAR_3_VALUE",
 		},
-	}, ctx.GetEnvCache().GetSorted())
+	}, getSorted(t, ctx.GetEnvCache()))
 	// Reordering
 	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
 		Spec: v1.ApicurioRegistrySpec{
@@ -91,7 +95,7 @@ func TestEnvCF(t *testing.T) {
 			Name:  "VAR_1_NAME",
 			Value: "VAR_1_VALUE",
 		},
-	}, ctx.GetEnvCache().GetSorted())
+	}, getSorted(t, ctx.GetEnvCache()))
 	// Removing
 	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
 		Spec: v1.ApicurioRegistrySpec{
@@ -119,15 +123,15 @@ func TestEnvCF(t *testing.T) {
 			Name:  "VAR_1_NAME",
 			Value: "VAR_1_VALUE",
 		},
-	}, ctx.GetEnvCache().GetSorted())
+	}, getSorted(t, ctx.GetEnvCache()))
 }
 
 func TestEnvOrdering(t *testing.T) {
 	ctx := context.NewLoopContextMock()
 	services := services2.NewLoopServicesMock(ctx)
 	loop := loop_impl.NewControlLoopImpl(ctx, services)
-	loop.AddControlFunction(NewEnvCF(ctx))
-	loop.AddControlFunction(NewEnvApplyCF(ctx))
+	loop.AddControlFunction(NewEnvCF(ctx, services))
+	loop.AddControlFunction(NewEnvApplyCF(ctx, services))
 
 	ctx.GetResourceCache().Set(resources.RC_KEY_DEPLOYMENT, resources.NewResourceCacheEntry(ctx.GetAppName(), &apps.Deployment{
 		Spec: apps.DeploymentSpec{
@@ -186,7 +190,7 @@ func TestEnvOrdering(t *testing.T) {
 		},
 	}))
 	loop.Run()
-	sorted := ctx.GetEnvCache().GetSorted()
+	sorted := getSorted(t, ctx.GetEnvCache())
 	sortedI := convert(sorted)
 	c.AssertIsInOrder(t, sortedI,
 		corev1.EnvVar{
@@ -212,8 +216,8 @@ func TestEnvPriority(t *testing.T) {
 	ctx := context.NewLoopContextMock()
 	services := services2.NewLoopServicesMock(ctx)
 	loop := loop_impl.NewControlLoopImpl(ctx, services)
-	loop.AddControlFunction(NewEnvCF(ctx))
-	loop.AddControlFunction(NewEnvApplyCF(ctx))
+	loop.AddControlFunction(NewEnvCF(ctx, services))
+	loop.AddControlFunction(NewEnvApplyCF(ctx, services))
 
 	// In reverse priority
 	// Spec Sourced
@@ -270,7 +274,7 @@ func TestEnvPriority(t *testing.T) {
 	}))
 	loop.Run()
 
-	sorted := ctx.GetEnvCache().GetSorted()
+	sorted := getSorted(t, ctx.GetEnvCache())
 	sortedI := convert(sorted)
 	c.AssertSliceContains(t, sortedI, corev1.EnvVar{
 		Name:  "VAR_1_NAME",
@@ -304,6 +308,158 @@ func TestEnvPriority(t *testing.T) {
 		})
 }
 
+func TestEnvStaleRemoval(t *testing.T) {
+	sqlSpec := &v1.ApicurioRegistry{
+		Spec: v1.ApicurioRegistrySpec{
+			Configuration: v1.ApicurioRegistrySpecConfiguration{
+				Persistence: "sql",
+				Sql: v1.ApicurioRegistrySpecConfigurationSql{
+					DataSource: v1.ApicurioRegistrySpecConfigurationDataSource{
+						Url:      "jdbc:postgresql://db:5432/registry",
+						UserName: "user",
+					},
+				},
+				Env: []corev1.EnvVar{
+					{
+						Name:  "SPEC_VAR_NAME",
+						Value: "SPEC_VAR_VALUE",
+					},
+				},
+			},
+		},
+	}
+	memSpec := sqlSpec.DeepCopy()
+	memSpec.Spec.Configuration.Persistence = ""
+
+	newLoop := func(deployment *apps.Deployment) (*context.LoopContextMock, loop.ControlLoop) {
+		ctx := context.NewLoopContextMock()
+		services := services2.NewLoopServicesMock(ctx)
+		controlLoop := loop_impl.NewControlLoopImpl(ctx, services)
+		controlLoop.AddControlFunction(NewEnvCF(ctx, services))
+		controlLoop.AddControlFunction(NewSqlCF(ctx, services))
+		controlLoop.AddControlFunction(NewEnvApplyCF(ctx, services))
+		ctx.GetResourceCache().Set(resources.RC_KEY_DEPLOYMENT, resources.NewResourceCacheEntry(ctx.GetAppName(), deployment))
+		return ctx, controlLoop
+	}
+	getDeployment := func(ctx *context.LoopContextMock) *apps.Deployment {
+		entry, _ := ctx.GetResourceCache().Get(resources.RC_KEY_DEPLOYMENT)
+		return entry.GetValue().(*apps.Deployment)
+	}
+
+	ctx, controlLoop := newLoop(&apps.Deployment{
+		ObjectMeta: meta.ObjectMeta{
+			Name: "test",
+		},
+		Spec: apps.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: factory.REGISTRY_CONTAINER_NAME,
+						},
+					},
+				},
+			},
+		},
+	})
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), sqlSpec))
+	controlLoop.Run()
+	_, exists := ctx.GetEnvCache().Get(ENV_REGISTRY_DATASOURCE_URL)
+	c.AssertEquals(t, true, exists)
+	deployment := getDeployment(ctx).DeepCopy()
+	c.AssertEquals(t, `{"REGISTRY_DATASOURCE_PASSWORD":"SqlCF","REGISTRY_DATASOURCE_URL":"SqlCF","REGISTRY_DATASOURCE_USERNAME":"SqlCF"}`,
+		deployment.Annotations[ENV_OWNERS_ANNOTATION])
+
+	// Vars are removed when the persistence is changed
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), memSpec))
+	controlLoop.Run()
+	sortedI := convert(getSorted(t, ctx.GetEnvCache()))
+	c.AssertEquals(t, 1, len(sortedI))
+	c.AssertSliceContains(t, sortedI, corev1.EnvVar{
+		Name:  "SPEC_VAR_NAME",
+		Value: "SPEC_VAR_VALUE",
+	})
+	_, exists = getDeployment(ctx).Annotations[ENV_OWNERS_ANNOTATION]
+	c.AssertEquals(t, false, exists)
+
+	// Owners are restored from the Deployment after a restart
+	ctx, controlLoop = newLoop(deployment)
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), memSpec))
+	controlLoop.Run()
+	sortedI = convert(getSorted(t, ctx.GetEnvCache()))
+	c.AssertEquals(t, 1, len(sortedI))
+	c.AssertSliceContains(t, sortedI, corev1.EnvVar{
+		Name:  "SPEC_VAR_NAME",
+		Value: "SPEC_VAR_VALUE",
+	})
+}
+
+func TestEnvDependencyCycle(t *testing.T) {
+	envCache := env.NewEnvCache(zap.NewNop())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("VAR_1_NAME", "VAR_1_VALUE").Build())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("VAR_2_NAME", "VAR_2_VALUE").SetDependency("VAR_3_NAME").Build())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("VAR_3_NAME", "VAR_3_VALUE").SetDependency("VAR_2_NAME").Build())
+	sorted, err := envCache.GetSorted()
+	c.AssertEquals(t, &env.DependencyCycleError{Names: []string{"VAR_2_NAME", "VAR_3_NAME"}}, err)
+	c.AssertEquals(t, 3, len(sorted))
+
+	c.AssertEquals(t, true, envCache.Set(env.NewSimpleEnvCacheEntryBuilder(" ", "VALUE").Build()) != nil)
+	_, exists := envCache.Get(" ")
+	c.AssertEquals(t, false, exists)
+}
+
+func TestEnvInvalidSpec(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewEnvCF(ctx, services))
+
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), &v1.ApicurioRegistry{
+		Spec: v1.ApicurioRegistrySpec{
+			Configuration: v1.ApicurioRegistrySpecConfiguration{
+				Env: []corev1.EnvVar{
+					{
+						Name:  "VAR_1_NAME",
+						Value: "VAR_1_VALUE",
+					},
+					{
+						Name:  "VAR_2_NAME",
+						Value: "VAR_2_VALUE",
+					},
+					{
+						Name:  "VAR_1_NAME",
+						Value: "VAR_1_OTHER_VALUE",
+					},
+					{
+						Name:  " ",
+						Value: "VALUE",
+					},
+				},
+			},
+		},
+	}))
+	loop.Run()
+	c.AssertEquals(t, []corev1.EnvVar{
+		{
+			Name:  "VAR_1_NAME",
+			Value: "VAR_1_VALUE",
+		},
+		{
+			Name:  "VAR_2_NAME",
+			Value: "VAR_2_VALUE",
+		},
+	}, getSorted(t, ctx.GetEnvCache()))
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID), condition.Reason)
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.env[2].name"))
+}
+
+func getSorted(t *testing.T, envCache env.EnvCache) []corev1.EnvVar {
+	sorted, err := envCache.GetSorted()
+	c.AssertEquals(t, nil, err)
+	return sorted
+}
+
 func convert(data []corev1.EnvVar) []interface{} {
 	res := make([]interface{}, len(data))
 	for i, v := range data {
@@ -311,3 +467,15 @@ func convert(data []corev1.EnvVar) []interface{} {
 	}
 	return res
 }
+
+func TestEnvFromConflicts(t *testing.T) {
+	envCache := env.NewEnvCache(zap.NewNop())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_DATASOURCE_URL, "jdbc:postgresql://db:5432/registry").
+		SetOwner("SqlCF").Build())
+	envCache.Set(env.NewSimpleEnvCacheEntryBuilder("SPEC_VAR_NAME", "SPEC_VAR_VALUE").
+		SetPriority(env.PRIORITY_SPEC).Build())
+
+	c.AssertEquals(t, []string{}, findEnvFromConflicts(envCache, "", []string{"SPEC_VAR_NAME", "OTHER"}))
+	c.AssertEquals(t, []string{ENV_REGISTRY_DATASOURCE_URL}, findEnvFromConflicts(envCache, "REGISTRY_", []string{"DATASOURCE_URL"}))
+	c.AssertEquals(t, []string{}, findEnvFromConflicts(envCache, "APP_", []string{"DATASOURCE_URL"}))
+}
@@ -1,9 +1,14 @@
 package kafkasql
 
 import (
+	"regexp"
+	"sort"
+	"strings"
+
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 )
@@ -13,27 +18,65 @@ var _ loop.ControlFunction = &KafkasqlCF{}
 const (
 	PERSISTENCE_ID              = "kafkasql"
 	ENV_KAFKA_BOOTSTRAP_SERVERS = "KAFKA_BOOTSTRAP_SERVERS"
+
+	ENV_REGISTRY_KAFKASQL_TOPIC                 = "REGISTRY_KAFKASQL_TOPIC"
+	ENV_REGISTRY_KAFKASQL_CONSUMER_GROUP_PREFIX = "REGISTRY_KAFKASQL_CONSUMER_GROUP_PREFIX"
+
+	// Enables the env. variables with the prefixes below, and the ones set by the security CFs.
+	// It is managed only by this CF, so it has a single owner.
+	ENV_REGISTRY_PROPERTIES_PREFIX = "REGISTRY_PROPERTIES_PREFIX"
+
+	// Env. variables with these prefixes are converted to the Kafka client properties by Apicurio Registry,
+	// e.g. REGISTRY_KAFKASQL_PRODUCER_REQUEST_TIMEOUT_MS -> request.timeout.ms
+	ENV_PREFIX_REGISTRY_KAFKA_COMMON        = "REGISTRY_KAFKA_COMMON_"
+	ENV_PREFIX_REGISTRY_KAFKASQL_PRODUCER   = "REGISTRY_KAFKASQL_PRODUCER_"
+	ENV_PREFIX_REGISTRY_KAFKASQL_CONSUMER   = "REGISTRY_KAFKASQL_CONSUMER_"
+	ENV_PREFIX_REGISTRY_KAFKASQL_ADMIN      = "REGISTRY_KAFKASQL_ADMIN_"
+	REGISTRY_PROPERTIES_PREFIX_VALUE        = "REGISTRY_"
+	KAFKA_PROPERTY_BOOTSTRAP_SERVERS        = "bootstrap.servers"
+	KAFKA_PROPERTY_GROUP_ID                 = "group.id"
+	kafkaTopicMaxLength                     = 249
+	kafkasqlPropertiesOptionPath            = "spec.configuration.kafkasql.properties"
+	kafkasqlTopicOptionPath                 = "spec.configuration.kafkasql.topic"
+	kafkasqlConsumerGroupIdPrefixOptionPath = "spec.configuration.kafkasql.consumerGroupIdPrefix"
 )
 
+var kafkaNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Kafka client property names are lowercase and separated by dots,
+// other characters can not be converted to an env. variable name and back.
+var kafkaPropertyRegex = regexp.MustCompile(`^[a-z0-9]+(\.[a-z0-9]+)*$`)
+
+// Properties managed by the security configuration
+var kafkaSecurityPropertyPrefixes = []string{"security.", "ssl.", "sasl."}
+
 type KafkasqlCF struct {
-	ctx                 context.LoopContext
-	svcResourceCache    resources.ResourceCache
-	svcEnvCache         env.EnvCache
-	persistence         string
-	bootstrapServers    string
-	valid               bool
-	envBootstrapServers string
+	ctx              context.LoopContext
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+	svcEnvCache      env.EnvCache
+	persistence      string
+	bootstrapServers string
+	valid            bool
+	targetEnv        map[string]string
+	targetOptions    map[string]string
+	invalidOptions   []string
+	invalidDetails   []string
+	existingEnv      map[string]string
+	staleEnv         []string
 }
 
-func NewKafkasqlCF(ctx context.LoopContext) loop.ControlFunction {
+func NewKafkasqlCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &KafkasqlCF{
-		ctx:                 ctx,
-		svcResourceCache:    ctx.GetResourceCache(),
-		svcEnvCache:         ctx.GetEnvCache(),
-		persistence:         "",
-		bootstrapServers:    "",
-		valid:               true,
-		envBootstrapServers: "",
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+		persistence:      "",
+		bootstrapServers: "",
+		valid:            true,
+		targetEnv:        make(map[string]string),
+		existingEnv:      make(map[string]string),
 	}
 }
 
@@ -42,26 +85,101 @@ func (this *KafkasqlCF) Describe() string {
 }
 
 func (this *KafkasqlCF) Sense() {
+	this.targetEnv = make(map[string]string)
+	this.targetOptions = make(map[string]string)
+	this.invalidOptions = nil
+	this.invalidDetails = nil
+	this.existingEnv = make(map[string]string)
+	this.staleEnv = nil
+
 	// Observation #1
 	// Read the config values
+	var config ar.ApicurioRegistrySpecConfigurationKafkasql
 	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
 		spec := specEntry.GetValue().(*ar.ApicurioRegistry)
 		this.persistence = spec.Spec.Configuration.Persistence
-		this.bootstrapServers = spec.Spec.Configuration.Kafkasql.BootstrapServers
+		config = spec.Spec.Configuration.Kafkasql
+		this.bootstrapServers = config.BootstrapServers
 	}
 
 	// Observation #2 + #3
 	// Is the correct persistence type selected?
-	// Validate the config values
+	// Validate the config values, invalid optional values are skipped
 	this.valid = this.persistence == PERSISTENCE_ID && this.bootstrapServers != ""
+	if this.valid {
+		this.addTarget(ENV_KAFKA_BOOTSTRAP_SERVERS, this.bootstrapServers, "spec.configuration.kafkasql.bootstrapServers")
+		if config.Topic != "" {
+			if details := validateTopic(config.Topic); details != "" {
+				this.addInvalid(kafkasqlTopicOptionPath, details)
+			} else {
+				this.addTarget(ENV_REGISTRY_KAFKASQL_TOPIC, config.Topic, kafkasqlTopicOptionPath)
+			}
+		}
+		if config.ConsumerGroupIdPrefix != "" {
+			if !kafkaNameRegex.MatchString(config.ConsumerGroupIdPrefix) {
+				this.addInvalid(kafkasqlConsumerGroupIdPrefixOptionPath, "only alphanumeric characters, '.', '_', and '-' are allowed")
+			} else {
+				this.addTarget(ENV_REGISTRY_KAFKASQL_CONSUMER_GROUP_PREFIX, config.ConsumerGroupIdPrefix, kafkasqlConsumerGroupIdPrefixOptionPath)
+			}
+		}
+		securityConfigured := config.Security.Tls != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityTls{}) ||
+			config.Security.Scram != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityScram{}) ||
+			config.Security.Oauth != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityOauth{})
+		propertiesAdded := this.addProperties("common", config.Properties.Common, ENV_PREFIX_REGISTRY_KAFKA_COMMON, securityConfigured)
+		propertiesAdded = this.addProperties("producer", config.Properties.Producer, ENV_PREFIX_REGISTRY_KAFKASQL_PRODUCER, securityConfigured) || propertiesAdded
+		propertiesAdded = this.addProperties("consumer", config.Properties.Consumer, ENV_PREFIX_REGISTRY_KAFKASQL_CONSUMER, securityConfigured) || propertiesAdded
+		propertiesAdded = this.addProperties("admin", config.Properties.Admin, ENV_PREFIX_REGISTRY_KAFKASQL_ADMIN, securityConfigured) || propertiesAdded
+		if propertiesAdded || securityConfigured {
+			this.addTarget(ENV_REGISTRY_PROPERTIES_PREFIX, REGISTRY_PROPERTIES_PREFIX_VALUE, kafkasqlPropertiesOptionPath)
+		}
+	}
 
 	// Observation #4
 	// Read the env values
-	if val, exists := this.svcEnvCache.Get(ENV_KAFKA_BOOTSTRAP_SERVERS); exists {
-		this.envBootstrapServers = val.GetValue().Value
+	for name := range this.targetEnv {
+		if entry, exists := this.svcEnvCache.Get(name); exists && entry.GetValue().ValueFrom == nil {
+			this.existingEnv[name] = entry.GetValue().Value
+		}
 	}
 
-	// We won't actively delete old env values if not used
+	// Observation #5
+	// Are there env values set by this CF that are no longer needed?
+	for _, entry := range this.svcEnvCache.GetByOwner(this.Describe()) {
+		if _, exists := this.targetEnv[entry.GetName()]; !exists {
+			this.staleEnv = append(this.staleEnv, entry.GetName())
+		}
+	}
+}
+
+// Env. variable, and the option it is configured by
+func (this *KafkasqlCF) addTarget(name string, value string, optionPath string) {
+	this.targetEnv[name] = value
+	this.targetOptions[name] = optionPath
+}
+
+func (this *KafkasqlCF) addInvalid(optionPath string, details string) {
+	this.invalidOptions = append(this.invalidOptions, optionPath)
+	this.invalidDetails = append(this.invalidDetails, details)
+}
+
+// Returns true if at least one property has been added
+func (this *KafkasqlCF) addProperties(mapName string, properties map[string]string, envPrefix string, securityConfigured bool) bool {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	added := false
+	for _, k := range keys {
+		optionPath := kafkasqlPropertiesOptionPath + "." + mapName + "[" + k + "]"
+		if details := validateProperty(mapName, k, securityConfigured); details != "" {
+			this.addInvalid(optionPath, details)
+			continue
+		}
+		this.addTarget(envPrefix+propertyToEnvSuffix(k), properties[k], optionPath)
+		added = true
+	}
+	return added
 }
 
 func (this *KafkasqlCF) Compare() bool {
@@ -69,16 +187,93 @@ func (this *KafkasqlCF) Compare() bool {
 	// Config values are valid
 	// Condition #2 + #3
 	// The required env vars are not present OR they differ
-	return this.valid && (this.bootstrapServers != this.envBootstrapServers)
+	// Condition #4
+	// Stale env vars have to be removed
+	// Condition #5
+	// Report invalid values once per loop
+	return (this.valid && !isEnvEqual(this.targetEnv, this.existingEnv)) ||
+		len(this.staleEnv) > 0 ||
+		(len(this.invalidOptions) > 0 && this.ctx.GetAttempts() == 0)
 }
 
 func (this *KafkasqlCF) Respond() {
 	// Response #1
 	// Just set the value(s)!
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_KAFKA_BOOTSTRAP_SERVERS, this.bootstrapServers).Build())
+	for name, value := range this.targetEnv {
+		if existing, exists := this.existingEnv[name]; !exists || existing != value {
+			if err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(name, value).SetOwner(this.Describe()).Build()); err != nil {
+				this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+				this.services.GetConditionManager().GetConfigurationErrorCondition().
+					TransitionInvalid(err.Error(), this.targetOptions[name])
+			}
+		}
+	}
+
+	// Response #2
+	// Remove the stale value(s)
+	for _, name := range this.staleEnv {
+		this.svcEnvCache.DeleteByName(name)
+	}
+
+	// Response #3
+	// Report the first invalid value
+	if len(this.invalidOptions) > 0 && this.ctx.GetAttempts() == 0 {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(this.invalidDetails[0], this.invalidOptions[0])
+	}
 }
 
 func (this *KafkasqlCF) Cleanup() bool {
 	// No cleanup
 	return true
 }
+
+func validateTopic(topic string) string {
+	if topic == "." || topic == ".." {
+		return "topic name can not be '.' or '..'"
+	}
+	if len(topic) > kafkaTopicMaxLength {
+		return "topic name is longer than 249 characters"
+	}
+	if !kafkaNameRegex.MatchString(topic) {
+		return "only alphanumeric characters, '.', '_', and '-' are allowed"
+	}
+	return ""
+}
+
+func validateProperty(mapName string, key string, securityConfigured bool) string {
+	if !kafkaPropertyRegex.MatchString(key) {
+		return "property name must consist of lowercase alphanumeric segments separated by '.'"
+	}
+	if key == KAFKA_PROPERTY_BOOTSTRAP_SERVERS {
+		return "use spec.configuration.kafkasql.bootstrapServers instead"
+	}
+	// Each replica must use a unique consumer group
+	if mapName == "consumer" && key == KAFKA_PROPERTY_GROUP_ID {
+		return "use spec.configuration.kafkasql.consumerGroupIdPrefix instead"
+	}
+	if securityConfigured {
+		for _, prefix := range kafkaSecurityPropertyPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return "property is managed by spec.configuration.kafkasql.security"
+			}
+		}
+	}
+	return ""
+}
+
+func propertyToEnvSuffix(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func isEnvEqual(target map[string]string, existing map[string]string) bool {
+	if len(target) != len(existing) {
+		return false
+	}
+	for k, v := range target {
+		if e, exists := existing[k]; !exists || e != v {
+			return false
+		}
+	}
+	return true
+}
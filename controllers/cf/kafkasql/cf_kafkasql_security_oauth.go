@@ -0,0 +1,337 @@
+package kafkasql
+
+import (
+	"net/url"
+
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+var _ loop.ControlFunction = &KafkasqlSecurityOAuthCF{}
+
+const ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_ID = "REGISTRY_KAFKASQL_OAUTH_CLIENT_ID"
+const ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET = "REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET"
+
+const ENV_REGISTRY_KAFKA_COMMON_SASL_LOGIN_CALLBACK_HANDLER_CLASS = "REGISTRY_KAFKA_COMMON_SASL_LOGIN_CALLBACK_HANDLER_CLASS"
+
+const OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME = "registry-kafkasql-oauth-truststore"
+
+const (
+	OAUTH_SASL_MECHANISM = "OAUTHBEARER"
+	// Provided by the Strimzi OAuth library, which is included in Apicurio Registry
+	OAUTH_LOGIN_CALLBACK_HANDLER_CLASS = "io.strimzi.kafka.oauth.client.JaasClientOauthLoginCallbackHandler"
+	OAUTH_CLIENT_SECRET_KEY            = "clientSecret"
+
+	oauthOptionPath = "spec.configuration.kafkasql.security.oauth"
+)
+
+type KafkasqlSecurityOAuthCF struct {
+	ctx                       context.LoopContext
+	services                  services.LoopServices
+	svcResourceCache          resources.ResourceCache
+	svcEnvCache               env.EnvCache
+	persistence               string
+	bootstrapServers          string
+	config                    ar.ApicurioRegistrySpecConfigurationKafkaSecurityOauth
+	valid                     bool
+	requiredOption            string
+	invalidOption             string
+	invalidDetails            string
+	jaasConfig                string
+	foundJaasConfig           string
+	foundClientId             string
+	foundClientSecretName     string
+	foundTruststoreSecretName string
+	deploymentEntry           resources.ResourceCacheEntry
+	staleEnv                  bool
+}
+
+func NewKafkasqlSecurityOAuthCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	return &KafkasqlSecurityOAuthCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+		svcEnvCache:      ctx.GetEnvCache(),
+		persistence:      "",
+		bootstrapServers: "",
+		valid:            false,
+	}
+}
+
+func (this *KafkasqlSecurityOAuthCF) Describe() string {
+	return "KafkasqlSecurityOAuthCF"
+}
+
+func (this *KafkasqlSecurityOAuthCF) Sense() {
+	this.requiredOption = ""
+	this.invalidOption = ""
+	this.invalidDetails = ""
+	this.config = ar.ApicurioRegistrySpecConfigurationKafkaSecurityOauth{}
+	var security ar.ApicurioRegistrySpecConfigurationKafkaSecurity
+
+	// Observation #1
+	// Read the config values
+	if specEntry, exists := this.svcResourceCache.Get(resources.RC_KEY_SPEC); exists {
+		spec := specEntry.GetValue().(*ar.ApicurioRegistry)
+		this.persistence = spec.Spec.Configuration.Persistence
+		this.bootstrapServers = spec.Spec.Configuration.Kafkasql.BootstrapServers
+		security = spec.Spec.Configuration.Kafkasql.Security
+		this.config = security.Oauth
+	}
+
+	// Observation #2
+	// Deployment exists
+	this.foundTruststoreSecretName = ""
+	deploymentEntry, deploymentExists := this.svcResourceCache.Get(resources.RC_KEY_DEPLOYMENT)
+	if deploymentExists {
+		deployment := deploymentEntry.GetValue().(*apps.Deployment)
+		for i, v := range deployment.Spec.Template.Spec.Volumes {
+			if v.Name == OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME {
+				this.foundTruststoreSecretName = deployment.Spec.Template.Spec.Volumes[i].VolumeSource.Secret.SecretName
+			}
+		}
+	}
+	this.deploymentEntry = deploymentEntry
+
+	this.foundClientId = ""
+	this.foundClientSecretName = ""
+	this.foundJaasConfig = ""
+	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_ID); exists {
+		this.foundClientId = entry.GetValue().Value
+	}
+	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET); exists && entry.GetValue().ValueFrom != nil {
+		this.foundClientSecretName = entry.GetValue().ValueFrom.SecretKeyRef.Name
+	}
+	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG); exists {
+		this.foundJaasConfig = entry.GetValue().Value
+	}
+
+	// Observation #3
+	// Validate the config values
+	configured := this.config != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityOauth{})
+	if this.persistence == PERSISTENCE_ID && this.bootstrapServers != "" && configured {
+		if this.config.TokenEndpointUri == "" {
+			this.requiredOption = oauthOptionPath + ".tokenEndpointUri"
+		} else if this.config.ClientId == "" {
+			this.requiredOption = oauthOptionPath + ".clientId"
+		} else if this.config.ClientSecretName == "" {
+			this.requiredOption = oauthOptionPath + ".clientSecretName"
+		} else if details := validateTokenEndpointUri(this.config.TokenEndpointUri); details != "" {
+			this.invalidOption = oauthOptionPath + ".tokenEndpointUri"
+			this.invalidDetails = details
+		} else if security.Tls != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityTls{}) ||
+			security.Scram != (ar.ApicurioRegistrySpecConfigurationKafkaSecurityScram{}) {
+			this.invalidOption = oauthOptionPath
+			this.invalidDetails = "only one of tls, scram, or oauth can be configured"
+		}
+	}
+	this.valid = this.persistence == PERSISTENCE_ID && this.bootstrapServers != "" && configured &&
+		this.requiredOption == "" && this.invalidOption == ""
+	if this.valid {
+		this.jaasConfig = buildOAuthJaasConfig(this.config.TokenEndpointUri, this.config.TruststoreSecretName != "")
+	}
+
+	// Observation #4
+	// Are there env values set by this CF that are no longer needed?
+	this.staleEnv = !this.valid && (len(this.svcEnvCache.GetByOwner(this.Describe())) > 0 || this.foundTruststoreSecretName != "")
+}
+
+func (this *KafkasqlSecurityOAuthCF) Compare() bool {
+	// Condition #1
+	// The config is valid and the env vars or the volume differ
+	// Condition #2
+	// Stale env vars have to be removed
+	// Condition #3
+	// Report invalid configuration once per loop
+	return (this.valid && (this.jaasConfig != this.foundJaasConfig ||
+		this.config.ClientId != this.foundClientId ||
+		this.config.ClientSecretName != this.foundClientSecretName ||
+		this.config.TruststoreSecretName != this.foundTruststoreSecretName)) ||
+		this.staleEnv ||
+		((this.requiredOption != "" || this.invalidOption != "") && this.ctx.GetAttempts() == 0)
+}
+
+func (this *KafkasqlSecurityOAuthCF) Respond() {
+	// Response #1
+	// Report invalid configuration
+	if this.ctx.GetAttempts() == 0 {
+		if this.requiredOption != "" {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionRequired(this.requiredOption)
+		}
+		if this.invalidOption != "" {
+			this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(this.invalidDetails, this.invalidOption)
+		}
+	}
+
+	// Response #2
+	// Remove stale env vars and the truststore volume
+	if this.staleEnv {
+		this.svcEnvCache.DeleteByOwner(this.Describe())
+		this.RemoveSecretVolumePatch(this.deploymentEntry, OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME)
+		return
+	}
+	if !this.valid {
+		return
+	}
+
+	// Response #3
+	// Set the env vars and the truststore volume
+	this.svcEnvCache.DeleteByOwner(this.Describe())
+	if err := this.AddEnv(); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(err.Error(), "spec.configuration.kafkasql.security.oauth")
+	}
+	if this.deploymentEntry == nil {
+		return
+	}
+	if this.config.TruststoreSecretName != "" {
+		this.AddSecretVolumePatch(this.deploymentEntry, this.config.TruststoreSecretName, OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME)
+		this.AddSecretMountPatch(this.deploymentEntry, OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME, "etc/"+OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME)
+	} else {
+		this.RemoveSecretVolumePatch(this.deploymentEntry, OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME)
+	}
+}
+
+func (this *KafkasqlSecurityOAuthCF) AddEnv() error {
+
+	entries := []env.EnvCacheEntry{
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_ID, this.config.ClientId).SetOwner(this.Describe()).Build(),
+		env.NewEnvCacheEntryBuilder(secretKeyEnv(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET, this.config.ClientSecretName, OAUTH_CLIENT_SECRET_KEY)).
+			SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_MECHANISM, OAUTH_SASL_MECHANISM).SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_LOGIN_CALLBACK_HANDLER_CLASS,
+			OAUTH_LOGIN_CALLBACK_HANDLER_CLASS).SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL, "SASL_SSL").SetOwner(this.Describe()).Build(),
+	}
+
+	jaasConfigBuilder := env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG, this.jaasConfig).
+		SetDependency(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_ID).
+		SetDependency(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET)
+
+	if this.config.TruststoreSecretName != "" {
+		entries = append(entries,
+			env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE, "PKCS12").SetOwner(this.Describe()).Build(),
+			env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_LOCATION,
+				"/etc/"+OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME+"/ca.p12").SetOwner(this.Describe()).Build(),
+			env.NewEnvCacheEntryBuilder(secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD, this.config.TruststoreSecretName, "ca.password")).
+				SetOwner(this.Describe()).Build())
+		jaasConfigBuilder.SetDependency(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD)
+	}
+
+	return setEnvEntries(this.svcEnvCache, append(entries, jaasConfigBuilder.SetOwner(this.Describe()).Build())...)
+}
+
+func (this *KafkasqlSecurityOAuthCF) AddSecretVolumePatch(deploymentEntry resources.ResourceCacheEntry, secretName string, volumeName string) {
+	deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+		deployment := value.(*apps.Deployment).DeepCopy()
+		volume := core.Volume{
+			Name: volumeName,
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: secretName,
+				},
+			},
+		}
+		j := -1
+		for i, v := range deployment.Spec.Template.Spec.Volumes {
+			if v.Name == volumeName {
+				j = i
+				deployment.Spec.Template.Spec.Volumes[i] = volume
+			}
+		}
+		if j == -1 {
+			deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, volume)
+		}
+		return deployment
+	})
+}
+
+func (this *KafkasqlSecurityOAuthCF) AddSecretMountPatch(deploymentEntry resources.ResourceCacheEntry, volumeName string, mountPath string) {
+	deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+		deployment := value.(*apps.Deployment).DeepCopy()
+		for ci, c := range deployment.Spec.Template.Spec.Containers {
+			if c.Name == factory.REGISTRY_CONTAINER_NAME {
+				mount := core.VolumeMount{
+					Name:      volumeName,
+					ReadOnly:  true,
+					MountPath: mountPath,
+				}
+				j := -1
+				for i, v := range deployment.Spec.Template.Spec.Containers[ci].VolumeMounts {
+					if v.Name == volumeName {
+						j = i
+						deployment.Spec.Template.Spec.Containers[ci].VolumeMounts[i] = mount
+					}
+				}
+				if j == -1 {
+					deployment.Spec.Template.Spec.Containers[ci].VolumeMounts = append(deployment.Spec.Template.Spec.Containers[ci].VolumeMounts, mount)
+				}
+			}
+		}
+		return deployment
+	})
+}
+
+func (this *KafkasqlSecurityOAuthCF) RemoveSecretVolumePatch(deploymentEntry resources.ResourceCacheEntry, volumeName string) {
+	if deploymentEntry == nil {
+		return
+	}
+	deploymentEntry.ApplyPatch(func(value interface{}) interface{} {
+		deployment := value.(*apps.Deployment).DeepCopy()
+		volumes := make([]core.Volume, 0, len(deployment.Spec.Template.Spec.Volumes))
+		for _, v := range deployment.Spec.Template.Spec.Volumes {
+			if v.Name != volumeName {
+				volumes = append(volumes, v)
+			}
+		}
+		deployment.Spec.Template.Spec.Volumes = volumes
+		for ci, c := range deployment.Spec.Template.Spec.Containers {
+			mounts := make([]core.VolumeMount, 0, len(c.VolumeMounts))
+			for _, m := range c.VolumeMounts {
+				if m.Name != volumeName {
+					mounts = append(mounts, m)
+				}
+			}
+			deployment.Spec.Template.Spec.Containers[ci].VolumeMounts = mounts
+		}
+		return deployment
+	})
+}
+
+func (this *KafkasqlSecurityOAuthCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+func validateTokenEndpointUri(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "could not parse the URL: " + err.Error()
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "an absolute http or https URL is required"
+	}
+	return ""
+}
+
+// The client ID, secret, and truststore password are referenced as env. variables, so they are not part of the value.
+func buildOAuthJaasConfig(tokenEndpointUri string, truststore bool) string {
+	res := "org.apache.kafka.common.security.oauthbearer.OAuthBearerLoginModule required" +
+		" oauth.client.id=\"$(" + ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_ID + ")\"" +
+		" oauth.client.secret=\"$(" + ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET + ")\"" +
+		" oauth.token.endpoint.uri=\"" + tokenEndpointUri + "\""
+	if truststore {
+		res += " oauth.ssl.truststore.location=\"/etc/" + OAUTH_TRUSTSTORE_SECRET_VOLUME_NAME + "/ca.p12\"" +
+			" oauth.ssl.truststore.password=\"$(" + ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD + ")\"" +
+			" oauth.ssl.truststore.type=\"PKCS12\""
+	}
+	return res + ";"
+}
@@ -4,6 +4,7 @@ import (
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
@@ -25,13 +26,18 @@ const SCRAM_TRUSTSTORE_SECRET_VOLUME_NAME = "registry-kafkasql-scram-truststore"
 
 type KafkasqlSecurityScramCF struct {
 	ctx                          context.LoopContext
+	services                     services.LoopServices
 	svcResourceCache             resources.ResourceCache
 	svcEnvCache                  env.EnvCache
 	persistence                  string
 	bootstrapServers             string
 	truststoreSecretName         string
+	format                       string
+	truststore                   kafkaStore
+	invalidDetails               string
 	valid                        bool
 	foundTruststoreSecretName    string
+	foundTruststoreFormat        string
 	deploymentExists             bool
 	deploymentEntry              resources.ResourceCacheEntry
 	scramUser                    string
@@ -40,11 +46,13 @@ type KafkasqlSecurityScramCF struct {
 	foundScramUser               string
 	foundScramPasswordSecretName string
 	foundScramMechanism          string
+	staleEnv                     bool
 }
 
-func NewKafkasqlSecurityScramCF(ctx context.LoopContext) loop.ControlFunction {
+func NewKafkasqlSecurityScramCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &KafkasqlSecurityScramCF{
 		ctx:                          ctx,
+		services:                     services,
 		svcResourceCache:             ctx.GetResourceCache(),
 		svcEnvCache:                  ctx.GetEnvCache(),
 		persistence:                  "",
@@ -77,6 +85,7 @@ func (this *KafkasqlSecurityScramCF) Sense() {
 		this.scramUser = spec.Spec.Configuration.Kafkasql.Security.Scram.User
 		this.scramPasswordSecretName = spec.Spec.Configuration.Kafkasql.Security.Scram.PasswordSecretName
 		this.scramMechanism = spec.Spec.Configuration.Kafkasql.Security.Scram.Mechanism
+		this.format = spec.Spec.Configuration.Kafkasql.Security.Scram.Format
 	}
 
 	if this.scramMechanism == "" {
@@ -99,6 +108,8 @@ func (this *KafkasqlSecurityScramCF) Sense() {
 	this.deploymentExists = deploymentExists
 	this.deploymentEntry = deploymentEntry
 
+	this.foundScramUser = ""
+	this.foundScramPasswordSecretName = ""
 	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_KAFKASQL_SCRAM_USER); exists {
 		this.foundScramUser = entry.GetValue().Value
 	}
@@ -106,6 +117,8 @@ func (this *KafkasqlSecurityScramCF) Sense() {
 		this.foundScramPasswordSecretName = entry.GetValue().ValueFrom.SecretKeyRef.Name
 	}
 
+	this.foundTruststoreFormat = getStoreEnvFormat(this.svcEnvCache, ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE)
+
 	mech := ""
 	if entry, exists := this.svcEnvCache.Get(ENV_REGISTRY_KAFKA_COMMON_SASL_MECHANISM); exists {
 		mech = entry.GetValue().Value
@@ -119,20 +132,60 @@ func (this *KafkasqlSecurityScramCF) Sense() {
 		this.scramPasswordSecretName != ""
 
 	this.foundScramMechanism = mech
-	// We won't actively delete old env values if not used
+
+	// Observation #4
+	// Detect the format of the truststore Secret once per loop, unless it is configured
+	if this.ctx.GetAttempts() == 0 {
+		this.invalidDetails = ""
+	}
+	if this.valid && this.ctx.GetAttempts() == 0 {
+		this.invalidDetails = validateStoreFormat(this.format)
+		if this.invalidDetails == "" {
+			this.truststore, this.invalidDetails = getTruststore(this.format, readStoreSecret(this.ctx, this.format, this.truststoreSecretName))
+		}
+	}
+	this.valid = this.valid && this.invalidDetails == ""
+
+	// Observation #5
+	// Are there env values set by this CF that are no longer needed?
+	this.staleEnv = !this.valid && len(this.svcEnvCache.GetByOwner(this.Describe())) > 0
 }
 
 func (this *KafkasqlSecurityScramCF) Compare() bool {
 	// Condition #1
-	return this.valid && (this.truststoreSecretName != this.foundTruststoreSecretName ||
+	// Condition #2
+	// Stale env vars have to be removed
+	// Condition #3
+	// Report invalid configuration once per loop
+	return (this.valid && (this.truststoreSecretName != this.foundTruststoreSecretName ||
 		this.scramUser != this.foundScramUser ||
 		this.scramPasswordSecretName != this.foundScramPasswordSecretName ||
-		this.scramMechanism != this.foundScramMechanism)
+		this.scramMechanism != this.foundScramMechanism ||
+		this.truststore.format != this.foundTruststoreFormat)) || this.staleEnv ||
+		(this.invalidDetails != "" && this.ctx.GetAttempts() == 0)
 }
 
 func (this *KafkasqlSecurityScramCF) Respond() {
-	this.AddEnv(this.truststoreSecretName, SCRAM_TRUSTSTORE_SECRET_VOLUME_NAME,
-		this.scramUser, this.scramPasswordSecretName, this.scramMechanism)
+	if this.invalidDetails != "" && this.ctx.GetAttempts() == 0 {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(this.invalidDetails, "spec.configuration.kafkasql.security.scram")
+	}
+	if this.staleEnv {
+		this.svcEnvCache.DeleteByOwner(this.Describe())
+		return
+	}
+	if !this.valid {
+		return
+	}
+	// Remove the env. variables of the previous format
+	this.svcEnvCache.DeleteByOwner(this.Describe())
+
+	if err := this.AddEnv(this.truststoreSecretName, SCRAM_TRUSTSTORE_SECRET_VOLUME_NAME,
+		this.scramUser, this.scramPasswordSecretName, this.scramMechanism); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(err.Error(), "spec.configuration.kafkasql.security.scram")
+	}
 
 	this.AddSecretVolumePatch(this.deploymentEntry, this.truststoreSecretName, SCRAM_TRUSTSTORE_SECRET_VOLUME_NAME)
 
@@ -140,48 +193,24 @@ func (this *KafkasqlSecurityScramCF) Respond() {
 }
 
 func (this *KafkasqlSecurityScramCF) AddEnv(truststoreSecretName string, truststoreSecretVolumeName string,
-	scramUser string, scramPasswordSecretName string, scramMechanism string) {
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_PROPERTIES_PREFIX, "REGISTRY_").Build())
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKASQL_SCRAM_USER, scramUser).Build())
-	this.svcEnvCache.Set(env.NewEnvCacheEntryBuilder(&core.EnvVar{
-		Name: ENV_REGISTRY_KAFKASQL_SCRAM_PASSWORD,
-		ValueFrom: &core.EnvVarSource{
-			SecretKeyRef: &core.SecretKeySelector{
-				LocalObjectReference: core.LocalObjectReference{
-					Name: scramPasswordSecretName,
-				},
-				Key: "password",
-			},
-		},
-	}).Build())
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_MECHANISM, scramMechanism).Build())
+	scramUser string, scramPasswordSecretName string, scramMechanism string) error {
 
 	jaasConfig := "org.apache.kafka.common.security.scram.ScramLoginModule required username='$(" + ENV_REGISTRY_KAFKASQL_SCRAM_USER +
 		")' password='$(" + ENV_REGISTRY_KAFKASQL_SCRAM_PASSWORD + ")';"
 
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG, jaasConfig).
-		SetDependency(ENV_REGISTRY_KAFKASQL_SCRAM_USER).
-		SetDependency(ENV_REGISTRY_KAFKASQL_SCRAM_PASSWORD).Build())
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL, "SASL_SSL").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE, "PKCS12").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_LOCATION,
-		"/etc/"+truststoreSecretVolumeName+"/ca.p12").Build())
-	this.svcEnvCache.Set(env.NewEnvCacheEntryBuilder(&core.EnvVar{
-		Name: ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD,
-		ValueFrom: &core.EnvVarSource{
-			SecretKeyRef: &core.SecretKeySelector{
-				LocalObjectReference: core.LocalObjectReference{
-					Name: truststoreSecretName,
-				},
-				Key: "ca.password",
-			},
-		},
-	}).Build())
-
+	err := setEnvEntries(this.svcEnvCache,
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKASQL_SCRAM_USER, scramUser).SetOwner(this.Describe()).Build(),
+		env.NewEnvCacheEntryBuilder(secretKeyEnv(ENV_REGISTRY_KAFKASQL_SCRAM_PASSWORD, scramPasswordSecretName, "password")).
+			SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_MECHANISM, scramMechanism).SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG, jaasConfig).
+			SetDependency(ENV_REGISTRY_KAFKASQL_SCRAM_USER).
+			SetDependency(ENV_REGISTRY_KAFKASQL_SCRAM_PASSWORD).SetOwner(this.Describe()).Build(),
+		env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL, "SASL_SSL").SetOwner(this.Describe()).Build())
+	if truststoreErr := addTruststoreEnv(this.svcEnvCache, this.Describe(), this.truststore, truststoreSecretName, "/etc/"+truststoreSecretVolumeName); err == nil {
+		err = truststoreErr
+	}
+	return err
 }
 
 func (this *KafkasqlSecurityScramCF) AddSecretVolumePatch(deploymentEntry resources.ResourceCacheEntry, secretName string, volumeName string) {
@@ -4,6 +4,7 @@ import (
 	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/factory"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
@@ -13,8 +14,6 @@ import (
 
 var _ loop.ControlFunction = &KafkasqlSecurityTLSCF{}
 
-const ENV_REGISTRY_PROPERTIES_PREFIX = "REGISTRY_PROPERTIES_PREFIX"
-
 // =====
 
 const ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL = "REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL"
@@ -36,22 +35,31 @@ const TRUSTSTORE_SECRET_VOLUME_NAME = "registry-kafkasql-tls-truststore"
 
 type KafkasqlSecurityTLSCF struct {
 	ctx                       context.LoopContext
+	services                  services.LoopServices
 	svcResourceCache          resources.ResourceCache
 	svcEnvCache               env.EnvCache
 	persistence               string
 	bootstrapServers          string
 	keystoreSecretName        string
 	truststoreSecretName      string
+	format                    string
+	keystore                  kafkaStore
+	truststore                kafkaStore
+	invalidDetails            string
 	valid                     bool
 	foundKeystoreSecretName   string
 	foundTruststoreSecretName string
+	foundKeystoreFormat       string
+	foundTruststoreFormat     string
 	deploymentExists          bool
+	staleEnv                  bool
 	deploymentEntry           resources.ResourceCacheEntry
 }
 
-func NewKafkasqlSecurityTLSCF(ctx context.LoopContext) loop.ControlFunction {
+func NewKafkasqlSecurityTLSCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
 	return &KafkasqlSecurityTLSCF{
 		ctx:                       ctx,
+		services:                  services,
 		svcResourceCache:          ctx.GetResourceCache(),
 		svcEnvCache:               ctx.GetEnvCache(),
 		persistence:               "",
@@ -78,6 +86,7 @@ func (this *KafkasqlSecurityTLSCF) Sense() {
 
 		this.keystoreSecretName = spec.Spec.Configuration.Kafkasql.Security.Tls.KeystoreSecretName
 		this.truststoreSecretName = spec.Spec.Configuration.Kafkasql.Security.Tls.TruststoreSecretName
+		this.format = spec.Spec.Configuration.Kafkasql.Security.Tls.Format
 	}
 
 	// Observation #2
@@ -100,23 +109,72 @@ func (this *KafkasqlSecurityTLSCF) Sense() {
 	this.deploymentExists = deploymentExists
 	this.deploymentEntry = deploymentEntry
 
+	this.foundKeystoreFormat = getStoreEnvFormat(this.svcEnvCache, ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_TYPE)
+	this.foundTruststoreFormat = getStoreEnvFormat(this.svcEnvCache, ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE)
+
 	// Observation #3
 	// Validate the config values
 	this.valid = this.persistence == PERSISTENCE_ID && this.bootstrapServers != "" &&
 		this.keystoreSecretName != "" && this.truststoreSecretName != ""
 
-	// We won't actively delete old env values if not used
+	// Observation #4
+	// Detect the format of the Secrets once per loop, unless it is configured
+	if this.ctx.GetAttempts() == 0 {
+		this.invalidDetails = ""
+	}
+	if this.valid && this.ctx.GetAttempts() == 0 {
+		this.invalidDetails = validateStoreFormat(this.format)
+		if this.invalidDetails == "" {
+			var keystoreDetails, truststoreDetails string
+			this.keystore, keystoreDetails = getKeystore(this.format, readStoreSecret(this.ctx, this.format, this.keystoreSecretName))
+			this.truststore, truststoreDetails = getTruststore(this.format, readStoreSecret(this.ctx, this.format, this.truststoreSecretName))
+			this.invalidDetails = keystoreDetails
+			if this.invalidDetails == "" {
+				this.invalidDetails = truststoreDetails
+			}
+		}
+	}
+	this.valid = this.valid && this.invalidDetails == ""
+
+	// Observation #5
+	// Are there env values set by this CF that are no longer needed?
+	this.staleEnv = !this.valid && len(this.svcEnvCache.GetByOwner(this.Describe())) > 0
 }
 
 func (this *KafkasqlSecurityTLSCF) Compare() bool {
 	// Condition #1
-	return this.valid && (this.keystoreSecretName != this.foundKeystoreSecretName ||
-		this.truststoreSecretName != this.foundTruststoreSecretName)
+	// Condition #2
+	// Stale env vars have to be removed
+	// Condition #3
+	// Report invalid configuration once per loop
+	return (this.valid && (this.keystoreSecretName != this.foundKeystoreSecretName ||
+		this.truststoreSecretName != this.foundTruststoreSecretName ||
+		this.keystore.format != this.foundKeystoreFormat ||
+		this.truststore.format != this.foundTruststoreFormat)) || this.staleEnv ||
+		(this.invalidDetails != "" && this.ctx.GetAttempts() == 0)
 }
 
 func (this *KafkasqlSecurityTLSCF) Respond() {
-	this.AddEnv(this.keystoreSecretName, KEYSTORE_SECRET_VOLUME_NAME,
-		this.truststoreSecretName, TRUSTSTORE_SECRET_VOLUME_NAME)
+	if this.invalidDetails != "" && this.ctx.GetAttempts() == 0 {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(this.invalidDetails, "spec.configuration.kafkasql.security.tls")
+	}
+	if this.staleEnv {
+		this.svcEnvCache.DeleteByOwner(this.Describe())
+		return
+	}
+	if !this.valid {
+		return
+	}
+	// Remove the env. variables of the previous format
+	this.svcEnvCache.DeleteByOwner(this.Describe())
+
+	if err := this.AddEnv(this.keystoreSecretName, KEYSTORE_SECRET_VOLUME_NAME,
+		this.truststoreSecretName, TRUSTSTORE_SECRET_VOLUME_NAME); err != nil {
+		this.ctx.GetLog().Sugar().Errorw("could not add environment variable", "error", err)
+		this.services.GetConditionManager().GetConfigurationErrorCondition().
+			TransitionInvalid(err.Error(), "spec.configuration.kafkasql.security.tls")
+	}
 
 	this.AddSecretVolumePatch(this.deploymentEntry, this.keystoreSecretName, KEYSTORE_SECRET_VOLUME_NAME)
 	this.AddSecretVolumePatch(this.deploymentEntry, this.truststoreSecretName, TRUSTSTORE_SECRET_VOLUME_NAME)
@@ -126,40 +184,16 @@ func (this *KafkasqlSecurityTLSCF) Respond() {
 }
 
 func (this *KafkasqlSecurityTLSCF) AddEnv(keystoreSecretName string, keystoreSecretVolumeName string,
-	truststoreSecretName string, truststoreSecretVolumeName string) {
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_PROPERTIES_PREFIX, "REGISTRY_").Build())
-
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL, "SSL").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_TYPE, "PKCS12").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_LOCATION,
-		"/etc/"+keystoreSecretVolumeName+"/user.p12").Build())
-	this.svcEnvCache.Set(env.NewEnvCacheEntryBuilder(&core.EnvVar{
-		Name: ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_PASSWORD,
-		ValueFrom: &core.EnvVarSource{
-			SecretKeyRef: &core.SecretKeySelector{
-				LocalObjectReference: core.LocalObjectReference{
-					Name: keystoreSecretName,
-				},
-				Key: "user.password",
-			},
-		},
-	}).Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE, "PKCS12").Build())
-	this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_LOCATION,
-		"/etc/"+truststoreSecretVolumeName+"/ca.p12").Build())
-	this.svcEnvCache.Set(env.NewEnvCacheEntryBuilder(&core.EnvVar{
-		Name: ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD,
-		ValueFrom: &core.EnvVarSource{
-			SecretKeyRef: &core.SecretKeySelector{
-				LocalObjectReference: core.LocalObjectReference{
-					Name: truststoreSecretName,
-				},
-				Key: "ca.password",
-			},
-		},
-	}).Build())
+	truststoreSecretName string, truststoreSecretVolumeName string) error {
 
+	err := this.svcEnvCache.Set(env.NewSimpleEnvCacheEntryBuilder(ENV_REGISTRY_KAFKA_COMMON_SECURITY_PROTOCOL, "SSL").SetOwner(this.Describe()).Build())
+	if keystoreErr := addKeystoreEnv(this.svcEnvCache, this.Describe(), this.keystore, keystoreSecretName, "/etc/"+keystoreSecretVolumeName); err == nil {
+		err = keystoreErr
+	}
+	if truststoreErr := addTruststoreEnv(this.svcEnvCache, this.Describe(), this.truststore, truststoreSecretName, "/etc/"+truststoreSecretVolumeName); err == nil {
+		err = truststoreErr
+	}
+	return err
 }
 
 func (this *KafkasqlSecurityTLSCF) AddSecretVolumePatch(deploymentEntry resources.ResourceCacheEntry, secretName string, volumeName string) {
@@ -0,0 +1,258 @@
+package kafkasql
+
+import (
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var _ loop.ControlFunction = &KafkasqlStrimziCF{}
+
+const (
+	STRIMZI_AUTHENTICATION_TLS   = "tls"
+	STRIMZI_AUTHENTICATION_SCRAM = "scram-sha-512"
+	STRIMZI_LISTENER_INTERNAL    = "internal"
+	// Secret created by Strimzi, that contains the cluster CA truststore under the `ca.p12` and `ca.password` keys
+	STRIMZI_CLUSTER_CA_CERT_SECRET_SUFFIX = "-cluster-ca-cert"
+
+	strimziOptionPath = "spec.configuration.kafkasql.strimzi"
+)
+
+// This CF reads the Kafka and KafkaUser resources referenced in `spec.configuration.kafkasql.strimzi`,
+// and fills in the bootstrap servers and security configuration of the cached spec.
+// The cache entry is replaced, so the change is never patched into the resource,
+// and the configuration is applied by KafkasqlCF, KafkasqlSecurityTLSCF, and KafkasqlSecurityScramCF.
+// It must be executed before them.
+type KafkasqlStrimziCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+
+	specEntry        resources.ResourceCacheEntry
+	enabled          bool
+	invalidDetails   string
+	waitingDetails   string
+	resolved         bool
+	bootstrapServers string
+	security         ar.ApicurioRegistrySpecConfigurationKafkaSecurity
+	specUpToDate     bool
+}
+
+func NewKafkasqlStrimziCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &KafkasqlStrimziCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *KafkasqlStrimziCF) Describe() string {
+	return "KafkasqlStrimziCF"
+}
+
+func (this *KafkasqlStrimziCF) Sense() {
+	this.enabled = false
+	this.specUpToDate = true
+
+	// Observation #1
+	// Read the config values
+	var specExists bool
+	if this.specEntry, specExists = this.svcResourceCache.Get(resources.RC_KEY_SPEC); !specExists {
+		return
+	}
+	spec := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	config := spec.Spec.Configuration.Kafkasql
+	this.enabled = spec.Spec.Configuration.Persistence == PERSISTENCE_ID && config.Strimzi.KafkaRef != nil
+	if !this.enabled {
+		return
+	}
+
+	// Observation #2
+	// Read the Strimzi resources once per loop, the result is kept for the next attempts.
+	// While waiting for Strimzi, the last resolved configuration is used, so the deployment is not changed.
+	if this.ctx.GetAttempts() == 0 {
+		this.invalidDetails = ""
+		this.waitingDetails = ""
+		this.readStrimziResources(config.Strimzi)
+	}
+
+	// Observation #3
+	// Is the cached spec up to date?
+	if this.resolved {
+		this.specUpToDate = config.BootstrapServers == this.bootstrapServers && config.Security == this.security
+		// The values must not be configured manually
+		if !this.specUpToDate && (config.BootstrapServers != "" || config.Security != (ar.ApicurioRegistrySpecConfigurationKafkaSecurity{})) {
+			this.invalidDetails = "bootstrapServers and security can not be configured together with the strimzi section"
+			this.specUpToDate = true
+		}
+	}
+}
+
+func (this *KafkasqlStrimziCF) readStrimziResources(config ar.ApicurioRegistrySpecConfigurationKafkasqlStrimzi) {
+	if !this.ctx.GetSupportedFeatures().SupportsStrimzi {
+		this.invalidDetails = "Strimzi is not installed in the cluster, restart the operator after installing it"
+		this.resolved = false
+		return
+	}
+	namespace := this.ctx.GetAppNamespace()
+	kafka, err := this.ctx.GetClients().Strimzi().GetKafka(namespace, common.Name(config.KafkaRef.Name))
+	if err != nil {
+		this.handleReadError("Kafka", config.KafkaRef.Name, err)
+		return
+	}
+	var user *client.StrimziKafkaUser
+	if config.KafkaUserRef != nil {
+		user, err = this.ctx.GetClients().Strimzi().GetKafkaUser(namespace, common.Name(config.KafkaUserRef.Name))
+		if err != nil {
+			this.handleReadError("KafkaUser", config.KafkaUserRef.Name, err)
+			return
+		}
+	}
+	bootstrapServers, security, problem, waiting := resolveStrimzi(kafka, user, config.ListenerName)
+	if waiting {
+		this.waitingDetails = problem
+	} else if problem != "" {
+		this.invalidDetails = problem
+		this.resolved = false
+	} else {
+		this.bootstrapServers = bootstrapServers
+		this.security = security
+		this.resolved = true
+	}
+}
+
+func (this *KafkasqlStrimziCF) handleReadError(kind string, name string, err error) {
+	if api_errors.IsNotFound(err) {
+		this.invalidDetails = kind + " " + name + " not found"
+		this.resolved = false
+	} else {
+		this.log.Warnw("could not read Strimzi resource", "kind", kind, "name", name, "error", err)
+		this.waitingDetails = "could not read " + kind + " " + name
+	}
+}
+
+func (this *KafkasqlStrimziCF) Compare() bool {
+	// Condition #1
+	// The cached spec has to be updated
+	// Condition #2
+	// Report problems once per loop
+	return (this.enabled && !this.specUpToDate) ||
+		(this.enabled && this.ctx.GetAttempts() == 0 && (this.invalidDetails != "" || this.waitingDetails != ""))
+}
+
+func (this *KafkasqlStrimziCF) Respond() {
+	// Response #1
+	// Fill in the configuration
+	if !this.specUpToDate {
+		resolved := this.specEntry.GetValue().(*ar.ApicurioRegistry).DeepCopy()
+		resolved.Spec.Configuration.Kafkasql.BootstrapServers = this.bootstrapServers
+		resolved.Spec.Configuration.Kafkasql.Security = this.security
+		this.svcResourceCache.Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(this.specEntry.GetName(), resolved))
+	}
+
+	// Response #2
+	// Report problems
+	if this.invalidDetails != "" {
+		this.services.GetConditionManager().GetConfigurationErrorCondition().TransitionInvalid(this.invalidDetails, strimziOptionPath)
+		this.ctx.SetRequeueDelaySec(10)
+	}
+	if this.waitingDetails != "" {
+		this.log.Infow("waiting for Strimzi resources", "details", this.waitingDetails)
+		this.ctx.SetRequeueDelaySec(10)
+	}
+}
+
+func (this *KafkasqlStrimziCF) Cleanup() bool {
+	// No cleanup
+	return true
+}
+
+// Return the bootstrap servers and security configuration for the given Kafka and (optional) KafkaUser.
+// Returns a problem description instead, and whether the problem may be resolved by waiting for Strimzi.
+func resolveStrimzi(kafka *client.StrimziKafka, user *client.StrimziKafkaUser, listenerName string) (
+	string, ar.ApicurioRegistrySpecConfigurationKafkaSecurity, string, bool) {
+
+	security := ar.ApicurioRegistrySpecConfigurationKafkaSecurity{}
+
+	authentication := ""
+	if user != nil {
+		authentication = user.Spec.Authentication.Type
+		if authentication != STRIMZI_AUTHENTICATION_TLS && authentication != STRIMZI_AUTHENTICATION_SCRAM {
+			return "", security, "authentication type '" + authentication + "' of KafkaUser " + user.Name + " is not supported", false
+		}
+	}
+
+	// Find a listener that supports the authentication
+	var listener *client.StrimziKafkaListener
+	for i, l := range kafka.Spec.Kafka.Listeners {
+		if (listenerName != "" && l.Name == listenerName) || (listenerName == "" && l.Type == STRIMZI_LISTENER_INTERNAL) {
+			if isListenerCompatible(l, authentication) {
+				listener = &kafka.Spec.Kafka.Listeners[i]
+				break
+			}
+			if listenerName != "" {
+				return "", security, "listener " + listenerName + " of Kafka " + kafka.Name + " does not support the authentication of the KafkaUser", false
+			}
+		}
+	}
+	if listener == nil {
+		return "", security, "Kafka " + kafka.Name + " does not have a compatible listener", false
+	}
+
+	bootstrapServers := ""
+	for _, s := range kafka.Status.Listeners {
+		// Older Strimzi versions use the type field for the listener name
+		if s.Name == listener.Name || (s.Name == "" && s.Type == listener.Name) {
+			bootstrapServers = s.BootstrapServers
+		}
+	}
+	if bootstrapServers == "" {
+		return "", security, "Kafka " + kafka.Name + " does not report the address of listener " + listener.Name, true
+	}
+
+	if user != nil {
+		if user.Status.Secret == "" || user.Status.Username == "" {
+			return "", security, "KafkaUser " + user.Name + " is not ready", true
+		}
+		truststoreSecretName := kafka.Name + STRIMZI_CLUSTER_CA_CERT_SECRET_SUFFIX
+		switch authentication {
+		case STRIMZI_AUTHENTICATION_TLS:
+			security.Tls = ar.ApicurioRegistrySpecConfigurationKafkaSecurityTls{
+				TruststoreSecretName: truststoreSecretName,
+				KeystoreSecretName:   user.Status.Secret,
+			}
+		case STRIMZI_AUTHENTICATION_SCRAM:
+			security.Scram = ar.ApicurioRegistrySpecConfigurationKafkaSecurityScram{
+				TruststoreSecretName: truststoreSecretName,
+				User:                 user.Status.Username,
+				PasswordSecretName:   user.Status.Secret,
+				Mechanism:            "SCRAM-SHA-512",
+			}
+		}
+	}
+	return bootstrapServers, security, "", false
+}
+
+// The TLS and SCRAM configuration requires a TLS listener, the configuration without a KafkaUser requires a plain one
+func isListenerCompatible(listener client.StrimziKafkaListener, authentication string) bool {
+	listenerAuthentication := ""
+	if listener.Authentication != nil {
+		listenerAuthentication = listener.Authentication.Type
+	}
+	if listenerAuthentication != authentication {
+		return false
+	}
+	if authentication == "" {
+		return !listener.Tls
+	}
+	return listener.Tls
+}
@@ -0,0 +1,329 @@
+package kafkasql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	loop_impl "github.com/Apicurio/apicurio-registry-operator/controllers/loop/impl"
+	services2 "github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+func TestKafkasqlProperties(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewKafkasqlCF(ctx, services))
+
+	spec := &v1.ApicurioRegistry{
+		Spec: v1.ApicurioRegistrySpec{
+			Configuration: v1.ApicurioRegistrySpecConfiguration{
+				Persistence: PERSISTENCE_ID,
+				Kafkasql: v1.ApicurioRegistrySpecConfigurationKafkasql{
+					BootstrapServers:      "kafka:9092",
+					Topic:                 "registry-a-journal",
+					ConsumerGroupIdPrefix: "registry-a-",
+					Properties: v1.ApicurioRegistrySpecConfigurationKafkasqlProperties{
+						Producer: map[string]string{
+							"request.timeout.ms": "5000",
+						},
+						Consumer: map[string]string{
+							"group.id": "registry",
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+
+	assertEnv := func(name string, expected string) {
+		entry, exists := ctx.GetEnvCache().Get(name)
+		c.AssertEquals(t, expected != "", exists)
+		if exists {
+			c.AssertEquals(t, expected, entry.GetValue().Value)
+		}
+	}
+	assertEnv(ENV_KAFKA_BOOTSTRAP_SERVERS, "kafka:9092")
+	assertEnv(ENV_REGISTRY_KAFKASQL_TOPIC, "registry-a-journal")
+	assertEnv(ENV_REGISTRY_KAFKASQL_CONSUMER_GROUP_PREFIX, "registry-a-")
+	assertEnv("REGISTRY_KAFKASQL_PRODUCER_REQUEST_TIMEOUT_MS", "5000")
+	assertEnv(ENV_REGISTRY_PROPERTIES_PREFIX, "REGISTRY_")
+	// Invalid properties are skipped and reported
+	assertEnv("REGISTRY_KAFKASQL_CONSUMER_GROUP_ID", "")
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_INVALID), condition.Reason)
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.kafkasql.properties.consumer[group.id]"))
+
+	// Removed options are removed from the env. variables
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Kafkasql.Topic = ""
+	spec.Spec.Configuration.Kafkasql.Properties = v1.ApicurioRegistrySpecConfigurationKafkasqlProperties{}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	assertEnv(ENV_KAFKA_BOOTSTRAP_SERVERS, "kafka:9092")
+	assertEnv(ENV_REGISTRY_KAFKASQL_TOPIC, "")
+	assertEnv("REGISTRY_KAFKASQL_PRODUCER_REQUEST_TIMEOUT_MS", "")
+	assertEnv(ENV_REGISTRY_PROPERTIES_PREFIX, "")
+
+	// The prefix is also required by the security configuration, and has a single owner
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Kafkasql.Security.Scram.User = "registry"
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	assertEnv(ENV_REGISTRY_PROPERTIES_PREFIX, "REGISTRY_")
+	entry, _ := ctx.GetEnvCache().Get(ENV_REGISTRY_PROPERTIES_PREFIX)
+	c.AssertEquals(t, "KafkasqlCF", entry.GetOwner())
+}
+
+func TestStrimziResolve(t *testing.T) {
+	kafka := &client.StrimziKafka{
+		ObjectMeta: meta.ObjectMeta{Name: "my-cluster"},
+		Spec: client.StrimziKafkaSpec{
+			Kafka: client.StrimziKafkaClusterSpec{
+				Listeners: []client.StrimziKafkaListener{
+					{Name: "plain", Type: "internal"},
+					{Name: "scram", Type: "internal", Tls: true,
+						Authentication: &client.StrimziKafkaListenerAuthentication{Type: STRIMZI_AUTHENTICATION_SCRAM}},
+					{Name: "tls", Type: "internal", Tls: true,
+						Authentication: &client.StrimziKafkaListenerAuthentication{Type: STRIMZI_AUTHENTICATION_TLS}},
+				},
+			},
+		},
+		Status: client.StrimziKafkaStatus{
+			Listeners: []client.StrimziKafkaListenerStatus{
+				{Name: "plain", BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+				{Name: "scram", BootstrapServers: "my-cluster-kafka-bootstrap:9093"},
+			},
+		},
+	}
+	user := &client.StrimziKafkaUser{
+		ObjectMeta: meta.ObjectMeta{Name: "my-user"},
+		Spec: client.StrimziKafkaUserSpec{
+			Authentication: client.StrimziKafkaListenerAuthentication{Type: STRIMZI_AUTHENTICATION_SCRAM},
+		},
+		Status: client.StrimziKafkaUserStatus{Username: "my-user", Secret: "my-user"},
+	}
+
+	// No authentication
+	bootstrapServers, security, problem, _ := resolveStrimzi(kafka, nil, "")
+	c.AssertEquals(t, "", problem)
+	c.AssertEquals(t, "my-cluster-kafka-bootstrap:9092", bootstrapServers)
+	c.AssertEquals(t, v1.ApicurioRegistrySpecConfigurationKafkaSecurity{}, security)
+
+	// SCRAM
+	bootstrapServers, security, problem, _ = resolveStrimzi(kafka, user, "")
+	c.AssertEquals(t, "", problem)
+	c.AssertEquals(t, "my-cluster-kafka-bootstrap:9093", bootstrapServers)
+	c.AssertEquals(t, v1.ApicurioRegistrySpecConfigurationKafkaSecurityScram{
+		TruststoreSecretName: "my-cluster-cluster-ca-cert",
+		User:                 "my-user",
+		PasswordSecretName:   "my-user",
+		Mechanism:            "SCRAM-SHA-512",
+	}, security.Scram)
+
+	// Incompatible listener
+	_, _, problem, waiting := resolveStrimzi(kafka, user, "plain")
+	c.AssertEquals(t, true, problem != "")
+	c.AssertEquals(t, false, waiting)
+
+	// TLS, the listener address is not reported yet
+	user.Spec.Authentication.Type = STRIMZI_AUTHENTICATION_TLS
+	_, _, problem, waiting = resolveStrimzi(kafka, user, "")
+	c.AssertEquals(t, true, problem != "")
+	c.AssertEquals(t, true, waiting)
+}
+
+func TestKafkaTopic(t *testing.T) {
+	topic := newKafkaTopic("registry", "my-cluster", KAFKASQL_DEFAULT_TOPIC, 3)
+	c.AssertEquals(t, "kafkasql-journal", topic.Name)
+	c.AssertEquals(t, "my-cluster", topic.Labels[client.STRIMZI_CLUSTER_LABEL])
+	c.AssertEquals(t, int32(1), topic.Spec.Partitions)
+	c.AssertEquals(t, int32(3), topic.Spec.Replicas)
+	c.AssertEquals(t, "compact", topic.Spec.Config["cleanup.policy"])
+
+	// The topic name is not a valid resource name
+	topic = newKafkaTopic("registry", "my-cluster", "Registry_Journal", 1)
+	c.AssertEquals(t, "registry-kafkasql-topic", topic.Name)
+	c.AssertEquals(t, "Registry_Journal", topic.GetTopicName())
+}
+
+func TestKafkasqlOAuth(t *testing.T) {
+	ctx := context.NewLoopContextMock()
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewKafkasqlSecurityOAuthCF(ctx, services))
+
+	spec := &v1.ApicurioRegistry{
+		Spec: v1.ApicurioRegistrySpec{
+			Configuration: v1.ApicurioRegistrySpecConfiguration{
+				Persistence: PERSISTENCE_ID,
+				Kafkasql: v1.ApicurioRegistrySpecConfigurationKafkasql{
+					BootstrapServers: "kafka:9093",
+					Security: v1.ApicurioRegistrySpecConfigurationKafkaSecurity{
+						Oauth: v1.ApicurioRegistrySpecConfigurationKafkaSecurityOauth{
+							TokenEndpointUri: "https://keycloak/realms/kafka/protocol/openid-connect/token",
+							ClientId:         "registry",
+							ClientSecretName: "registry-client",
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+
+	entry, exists := ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SASL_MECHANISM)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, "OAUTHBEARER", entry.GetValue().Value)
+	entry, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKASQL_OAUTH_CLIENT_SECRET)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, "registry-client", entry.GetValue().ValueFrom.SecretKeyRef.Name)
+	entry, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, true, strings.Contains(entry.GetValue().Value,
+		"oauth.token.endpoint.uri=\"https://keycloak/realms/kafka/protocol/openid-connect/token\""))
+
+	// Missing required option, the env. variables are removed
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Kafkasql.Security.Oauth.ClientId = ""
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	_, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SASL_JAAS_CONFIG)
+	c.AssertEquals(t, false, exists)
+	condition := services.GetConditionManager().GetConfigurationErrorCondition().GetData()
+	c.AssertEquals(t, string(conditions.CONFIGURATION_ERROR_CONDITION_REASON_REQUIRED), condition.Reason)
+	c.AssertEquals(t, true, strings.Contains(condition.Message, "spec.configuration.kafkasql.security.oauth.clientId"))
+}
+
+func TestKafkasqlStoreFormat(t *testing.T) {
+	secret := func(keys ...string) *core.Secret {
+		res := &core.Secret{ObjectMeta: meta.ObjectMeta{Name: "my-secret"}, Data: map[string][]byte{}}
+		for _, k := range keys {
+			res.Data[k] = []byte{}
+		}
+		return res
+	}
+
+	// Detected from the Secret keys
+	truststore, problem := getTruststore("", secret("ca.crt"))
+	c.AssertEquals(t, "", problem)
+	c.AssertEquals(t, kafkaStore{format: STORE_FORMAT_PEM, key: "ca.crt"}, truststore)
+	truststore, _ = getTruststore("", secret("ca.crt", "ca.p12", "ca.password"))
+	c.AssertEquals(t, STORE_FORMAT_PKCS12, truststore.format)
+	keystore, _ := getKeystore("", secret("tls.crt", "tls.key"))
+	c.AssertEquals(t, kafkaStore{format: STORE_FORMAT_PEM, key: "tls.crt", privateKeyKey: "tls.key"}, keystore)
+	keystore, _ = getKeystore("", secret("user.crt", "user.key"))
+	c.AssertEquals(t, kafkaStore{format: STORE_FORMAT_PEM, key: "user.crt", privateKeyKey: "user.key"}, keystore)
+	_, problem = getKeystore("", secret("ca.crt"))
+	c.AssertEquals(t, true, problem != "")
+
+	// The PEM private key must be in the unencrypted PKCS #8 format
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystoreSecret := secret("tls.crt")
+	keystoreSecret.Name = "registry-tls"
+	keystoreSecret.Data["tls.key"] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+	_, problem = getKeystore("", keystoreSecret)
+	c.AssertEquals(t, "", problem)
+	_, problem = getKeystore("", secret("tls.crt", "tls.key"))
+	c.AssertEquals(t, "the tls.key key of Secret my-secret must contain a PEM private key", problem)
+	rsaSecret := secret("tls.crt")
+	rsaSecret.Data["tls.key"] = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte{0}})
+	_, problem = getKeystore(STORE_FORMAT_PEM, rsaSecret)
+	c.AssertEquals(t, true, strings.HasPrefix(problem, "the tls.key key of Secret my-secret contains a private key of type RSA PRIVATE KEY"))
+	encryptedSecret := secret("user.crt")
+	encryptedSecret.Data["user.key"] = pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{0}})
+	keystore, problem = getKeystore("", encryptedSecret)
+	c.AssertEquals(t, "user.key", keystore.privateKeyKey)
+	c.AssertEquals(t, true, strings.HasPrefix(problem, "the user.key key of Secret my-secret contains an encrypted private key"))
+
+	// Configured explicitly, the Secrets are read to inspect the private key
+	secrets := map[string]*core.Secret{"registry-tls": keystoreSecret, "kafka-ca": secret("ca.crt")}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		value, exists := secrets[name]
+		if r.Method != http.MethodGet || !strings.Contains(r.URL.Path, "/secrets/") || !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(value); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	ctx := context.NewLoopContextMock()
+	ctx.SetClients(client.NewClients(zap.NewNop(), runtime.NewScheme(), &rest.Config{Host: server.URL}, nil))
+	services := services2.NewLoopServicesMock(ctx)
+	loop := loop_impl.NewControlLoopImpl(ctx, services)
+	loop.AddControlFunction(NewKafkasqlSecurityTLSCF(ctx, services))
+
+	spec := &v1.ApicurioRegistry{
+		Spec: v1.ApicurioRegistrySpec{
+			Configuration: v1.ApicurioRegistrySpecConfiguration{
+				Persistence: PERSISTENCE_ID,
+				Kafkasql: v1.ApicurioRegistrySpecConfigurationKafkasql{
+					BootstrapServers: "kafka:9093",
+					Security: v1.ApicurioRegistrySpecConfigurationKafkaSecurity{
+						Tls: v1.ApicurioRegistrySpecConfigurationKafkaSecurityTls{
+							TruststoreSecretName: "kafka-ca",
+							KeystoreSecretName:   "registry-tls",
+							Format:               STORE_FORMAT_PEM,
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	ctx.GetResourceCache().Set(resources.RC_KEY_DEPLOYMENT, resources.NewResourceCacheEntry(ctx.GetAppName(), &apps.Deployment{}))
+	loop.Run()
+
+	entry, exists := ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_CERTIFICATES)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, "ca.crt", entry.GetValue().ValueFrom.SecretKeyRef.Key)
+	entry, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_KEY)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, "tls.key", entry.GetValue().ValueFrom.SecretKeyRef.Key)
+	_, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_LOCATION)
+	c.AssertEquals(t, false, exists)
+
+	// Switching to PKCS12 removes the PEM env. variables
+	spec = spec.DeepCopy()
+	spec.Spec.Configuration.Kafkasql.Security.Tls.Format = STORE_FORMAT_PKCS12
+	ctx.GetResourceCache().Set(resources.RC_KEY_SPEC, resources.NewResourceCacheEntry(ctx.GetAppName(), spec))
+	loop.Run()
+	_, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_KEY)
+	c.AssertEquals(t, false, exists)
+	entry, exists = ctx.GetEnvCache().Get(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_LOCATION)
+	c.AssertEquals(t, true, exists)
+	c.AssertEquals(t, "/etc/"+KEYSTORE_SECRET_VOLUME_NAME+"/user.p12", entry.GetValue().Value)
+}
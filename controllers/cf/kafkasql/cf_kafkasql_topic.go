@@ -0,0 +1,206 @@
+package kafkasql
+
+import (
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/services"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
+	"go.uber.org/zap"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var _ loop.ControlFunction = &KafkasqlTopicCF{}
+
+const (
+	KAFKASQL_DEFAULT_TOPIC = "kafkasql-journal"
+	// The storage topic must have a single partition, so the order of the messages is kept
+	KAFKASQL_TOPIC_PARTITIONS             = 1
+	KAFKASQL_TOPIC_MAX_REPLICATION_FACTOR = 3
+	KAFKASQL_TOPIC_CLEANUP_POLICY         = "cleanup.policy"
+	KAFKASQL_TOPIC_CLEANUP_POLICY_COMPACT = "compact"
+	kafkasqlTopicResourceNameSuffix       = "-kafkasql-topic"
+)
+
+// This CF creates the storage topic as a Strimzi KafkaTopic, when the Kafka cluster is referenced
+// in `spec.configuration.kafkasql.strimzi`. An existing KafkaTopic for the same topic is used as it is.
+// The topic readiness is reported in the StorageReady condition,
+// and ReplicasCF does not start the deployment until the topic is ready. It must be executed before ReplicasCF.
+type KafkasqlTopicCF struct {
+	ctx              context.LoopContext
+	log              *zap.SugaredLogger
+	services         services.LoopServices
+	svcResourceCache resources.ResourceCache
+
+	enabled           bool
+	specEntry         resources.ResourceCacheEntry
+	kafkaName         string
+	topicName         string
+	replicationFactor int32
+	topicExists       bool
+	topicReady        bool
+	topicMessage      string
+	readFailed        bool
+}
+
+func NewKafkasqlTopicCF(ctx context.LoopContext, services services.LoopServices) loop.ControlFunction {
+	res := &KafkasqlTopicCF{
+		ctx:              ctx,
+		services:         services,
+		svcResourceCache: ctx.GetResourceCache(),
+	}
+	res.log = ctx.GetLog().Sugar().With("cf", res.Describe())
+	return res
+}
+
+func (this *KafkasqlTopicCF) Describe() string {
+	return "KafkasqlTopicCF"
+}
+
+func (this *KafkasqlTopicCF) Sense() {
+	// The topic is checked once per loop, the condition is reported at the same time
+	if this.ctx.GetAttempts() != 0 {
+		return
+	}
+	this.enabled = false
+	this.topicExists = false
+	this.topicReady = false
+	this.topicMessage = ""
+	this.readFailed = false
+
+	// Observation #1
+	// Read the config values
+	var specExists bool
+	if this.specEntry, specExists = this.svcResourceCache.Get(resources.RC_KEY_SPEC); !specExists {
+		return
+	}
+	spec := this.specEntry.GetValue().(*ar.ApicurioRegistry)
+	config := spec.Spec.Configuration.Kafkasql
+	// Invalid configuration is reported by KafkasqlStrimziCF and KafkasqlCF
+	if spec.Spec.Configuration.Persistence != PERSISTENCE_ID || config.Strimzi.KafkaRef == nil ||
+		!this.ctx.GetSupportedFeatures().SupportsStrimzi {
+		return
+	}
+	this.topicName = config.Topic
+	if this.topicName == "" {
+		this.topicName = KAFKASQL_DEFAULT_TOPIC
+	}
+	if validateTopic(this.topicName) != "" {
+		return
+	}
+	this.enabled = true
+	this.kafkaName = config.Strimzi.KafkaRef.Name
+	this.replicationFactor = config.Strimzi.TopicReplicationFactor
+
+	// Observation #2
+	// Find the KafkaTopic for the topic
+	topics, err := this.ctx.GetClients().Strimzi().GetKafkaTopics(this.ctx.GetAppNamespace(), meta.ListOptions{
+		LabelSelector: client.STRIMZI_CLUSTER_LABEL + "=" + this.kafkaName,
+	})
+	if err != nil {
+		this.log.Warnw("could not read KafkaTopic resources", "error", err)
+		this.readFailed = true
+		return
+	}
+	for _, t := range topics {
+		if t.GetTopicName() == this.topicName {
+			this.topicExists = true
+			if ready := client.GetStrimziReadyCondition(t.Status.Conditions); ready != nil {
+				this.topicReady = ready.Status == "True"
+				this.topicMessage = ready.Message
+			}
+		}
+	}
+}
+
+func (this *KafkasqlTopicCF) Compare() bool {
+	// Condition #1
+	// The topic has to be created
+	// Condition #2
+	// Report the topic readiness once per loop
+	return this.enabled && this.ctx.GetAttempts() == 0
+}
+
+func (this *KafkasqlTopicCF) Respond() {
+	condition := this.services.GetConditionManager().GetStorageReadyCondition()
+
+	// Response #1
+	// Create the topic
+	if !this.topicExists && !this.readFailed {
+		if err := this.createTopic(); err != nil {
+			this.log.Errorw("could not create KafkaTopic", "topic", this.topicName, "error", err)
+			condition.TransitionKafkaTopicNotReady("Could not create KafkaTopic for topic " + this.topicName + ": " + err.Error())
+		} else {
+			this.log.Infow("created KafkaTopic", "topic", this.topicName)
+			condition.TransitionKafkaTopicNotReady("Waiting for the Kafka topic " + this.topicName + " to be created")
+		}
+		this.ctx.SetRequeueDelaySec(5)
+		return
+	}
+
+	// Response #2
+	// Report the readiness
+	if this.topicReady {
+		condition.TransitionKafkaTopicReady(this.topicName)
+	} else {
+		message := "Waiting for the Kafka topic " + this.topicName + " to be ready"
+		if this.topicMessage != "" {
+			message += ": " + this.topicMessage
+		}
+		condition.TransitionKafkaTopicNotReady(message)
+		this.ctx.SetRequeueDelaySec(10)
+	}
+}
+
+func (this *KafkasqlTopicCF) createTopic() error {
+	replicationFactor := this.replicationFactor
+	if replicationFactor < 1 {
+		// Use the number of brokers, up to the maximum
+		kafka, err := this.ctx.GetClients().Strimzi().GetKafka(this.ctx.GetAppNamespace(), common.Name(this.kafkaName))
+		if err != nil {
+			return err
+		}
+		replicationFactor = kafka.Spec.Kafka.Replicas
+		if replicationFactor > KAFKASQL_TOPIC_MAX_REPLICATION_FACTOR {
+			replicationFactor = KAFKASQL_TOPIC_MAX_REPLICATION_FACTOR
+		}
+		if replicationFactor < 1 {
+			replicationFactor = 1
+		}
+	}
+	topic := newKafkaTopic(this.ctx.GetAppName().Str(), this.kafkaName, this.topicName, replicationFactor)
+	return this.ctx.GetClients().Strimzi().CreateKafkaTopic(this.specEntry.GetValue().(*ar.ApicurioRegistry),
+		this.ctx.GetAppNamespace(), topic)
+}
+
+func (this *KafkasqlTopicCF) Cleanup() bool {
+	// The KafkaTopic is deleted together with the ApicurioRegistry, because it is owned by it
+	return true
+}
+
+func newKafkaTopic(appName string, kafkaName string, topicName string, replicationFactor int32) *client.StrimziKafkaTopic {
+	// Kafka topic names may contain characters that are not allowed in the resource name
+	name := topicName
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		name = appName + kafkasqlTopicResourceNameSuffix
+	}
+	return &client.StrimziKafkaTopic{
+		ObjectMeta: meta.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				client.STRIMZI_CLUSTER_LABEL: kafkaName,
+			},
+		},
+		Spec: client.StrimziKafkaTopicSpec{
+			TopicName:  topicName,
+			Partitions: KAFKASQL_TOPIC_PARTITIONS,
+			Replicas:   replicationFactor,
+			Config: map[string]interface{}{
+				KAFKASQL_TOPIC_CLEANUP_POLICY: KAFKASQL_TOPIC_CLEANUP_POLICY_COMPACT,
+			},
+		},
+	}
+}
@@ -0,0 +1,204 @@
+package kafkasql
+
+import (
+	"encoding/pem"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	STORE_FORMAT_PKCS12 = "PKCS12"
+	STORE_FORMAT_PEM    = "PEM"
+
+	TRUSTSTORE_PKCS12_KEY   = "ca.p12"
+	TRUSTSTORE_PASSWORD_KEY = "ca.password"
+	TRUSTSTORE_PEM_KEY      = "ca.crt"
+
+	KEYSTORE_PKCS12_KEY   = "user.p12"
+	KEYSTORE_PASSWORD_KEY = "user.password"
+	// Keys of a `kubernetes.io/tls` Secret, e.g. created by cert-manager
+	KEYSTORE_PEM_CERTIFICATE_KEY = "tls.crt"
+	KEYSTORE_PEM_PRIVATE_KEY_KEY = "tls.key"
+	// Keys of a Secret created by Strimzi for a KafkaUser
+	KEYSTORE_STRIMZI_PEM_CERTIFICATE_KEY = "user.crt"
+	KEYSTORE_STRIMZI_PEM_PRIVATE_KEY_KEY = "user.key"
+)
+
+const ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_CERTIFICATES = "REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_CERTIFICATES"
+const ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_CERTIFICATE_CHAIN = "REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_CERTIFICATE_CHAIN"
+const ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_KEY = "REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_KEY"
+
+// Format and keys of a truststore or keystore Secret.
+// PKCS12 stores are mounted as files, PEM certificates and keys are passed to Kafka as values.
+type kafkaStore struct {
+	format string
+	// PKCS12 file, or PEM certificates
+	key string
+	// PKCS12 only
+	passwordKey string
+	// PEM keystore only
+	privateKeyKey string
+}
+
+func validateStoreFormat(format string) string {
+	if format != "" && format != STORE_FORMAT_PKCS12 && format != STORE_FORMAT_PEM {
+		return "supported values are " + STORE_FORMAT_PKCS12 + " and " + STORE_FORMAT_PEM
+	}
+	return ""
+}
+
+// Return the truststore keys for the given format.
+// If the format is not set, it is detected from the keys of the Secret, which may be nil if it could not be read.
+func getTruststore(format string, secret *core.Secret) (kafkaStore, string) {
+	pkcs12 := kafkaStore{format: STORE_FORMAT_PKCS12, key: TRUSTSTORE_PKCS12_KEY, passwordKey: TRUSTSTORE_PASSWORD_KEY}
+	pemStore := kafkaStore{format: STORE_FORMAT_PEM, key: TRUSTSTORE_PEM_KEY}
+	switch {
+	case format == STORE_FORMAT_PEM:
+		return pemStore, ""
+	case format == STORE_FORMAT_PKCS12 || secret == nil || common.SecretHasField(secret, TRUSTSTORE_PKCS12_KEY):
+		return pkcs12, ""
+	case common.SecretHasField(secret, TRUSTSTORE_PEM_KEY):
+		return pemStore, ""
+	}
+	return pkcs12, "Secret " + secret.Name + " must contain either the " + TRUSTSTORE_PKCS12_KEY + " and " +
+		TRUSTSTORE_PASSWORD_KEY + " keys, or the " + TRUSTSTORE_PEM_KEY + " key"
+}
+
+// Return the keystore keys for the given format.
+// If the format is not set, it is detected from the keys of the Secret, which may be nil if it could not be read.
+// The private key of a PEM keystore is inspected if the Secret has been read.
+func getKeystore(format string, secret *core.Secret) (kafkaStore, string) {
+	pkcs12 := kafkaStore{format: STORE_FORMAT_PKCS12, key: KEYSTORE_PKCS12_KEY, passwordKey: KEYSTORE_PASSWORD_KEY}
+	pemStore := kafkaStore{format: STORE_FORMAT_PEM, key: KEYSTORE_PEM_CERTIFICATE_KEY, privateKeyKey: KEYSTORE_PEM_PRIVATE_KEY_KEY}
+	strimziPem := kafkaStore{format: STORE_FORMAT_PEM, key: KEYSTORE_STRIMZI_PEM_CERTIFICATE_KEY, privateKeyKey: KEYSTORE_STRIMZI_PEM_PRIVATE_KEY_KEY}
+	hasStrimziPem := secret != nil && common.SecretHasField(secret, KEYSTORE_STRIMZI_PEM_CERTIFICATE_KEY) &&
+		common.SecretHasField(secret, KEYSTORE_STRIMZI_PEM_PRIVATE_KEY_KEY)
+	var res kafkaStore
+	switch {
+	case format == STORE_FORMAT_PEM && hasStrimziPem && !common.SecretHasField(secret, KEYSTORE_PEM_CERTIFICATE_KEY):
+		res = strimziPem
+	case format == STORE_FORMAT_PEM:
+		res = pemStore
+	case format == STORE_FORMAT_PKCS12 || secret == nil || common.SecretHasField(secret, KEYSTORE_PKCS12_KEY):
+		res = pkcs12
+	case common.SecretHasField(secret, KEYSTORE_PEM_CERTIFICATE_KEY) && common.SecretHasField(secret, KEYSTORE_PEM_PRIVATE_KEY_KEY):
+		res = pemStore
+	case hasStrimziPem:
+		res = strimziPem
+	default:
+		return pkcs12, "Secret " + secret.Name + " must contain either the " + KEYSTORE_PKCS12_KEY + " and " +
+			KEYSTORE_PASSWORD_KEY + " keys, or the " + KEYSTORE_PEM_CERTIFICATE_KEY + " and " + KEYSTORE_PEM_PRIVATE_KEY_KEY + " keys"
+	}
+	if res.format == STORE_FORMAT_PEM && secret != nil {
+		return res, validatePemPrivateKey(secret, res.privateKeyKey)
+	}
+	return res, ""
+}
+
+// Kafka only supports PEM private keys in the unencrypted PKCS #8 format, i.e. with the "PRIVATE KEY" header.
+// cert-manager uses the PKCS #1 format by default, unless the private key encoding of the Certificate is set to PKCS8.
+func validatePemPrivateKey(secret *core.Secret, key string) string {
+	block, _ := pem.Decode(secret.Data[key])
+	prefix := "the " + key + " key of Secret " + secret.Name
+	switch {
+	case block == nil:
+		return prefix + " must contain a PEM private key"
+	case block.Type == "PRIVATE KEY":
+		return ""
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		return prefix + " contains an encrypted private key, which is not supported, use the unencrypted PKCS #8 format"
+	default:
+		return prefix + " contains a private key of type " + block.Type + ", which is not supported, use the unencrypted PKCS #8 format " +
+			"(if the Secret is created by cert-manager, set the privateKey.encoding field of the Certificate to PKCS8)"
+	}
+}
+
+// Read the Secret to detect the format if it is not configured, and to inspect the PEM private key.
+// Returns nil if the PKCS12 format is configured, or if the Secret could not be read,
+// the PKCS12 format is used in that case if the format is not configured.
+func readStoreSecret(ctx context.LoopContext, format string, secretName string) *core.Secret {
+	if format == STORE_FORMAT_PKCS12 {
+		return nil
+	}
+	secret, err := ctx.GetClients().Kube().GetSecret(ctx.GetAppNamespace(), common.Name(secretName), &meta.GetOptions{})
+	if err != nil {
+		ctx.GetLog().Sugar().Warnw("could not read the truststore or keystore Secret",
+			"secret", secretName, "error", err)
+		return nil
+	}
+	return secret
+}
+
+func secretKeyEnv(name string, secretName string, key string) *core.EnvVar {
+	return &core.EnvVar{
+		Name: name,
+		ValueFrom: &core.EnvVarSource{
+			SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// Set the truststore env. variables, the PKCS12 truststore must be mounted in the given directory
+func addTruststoreEnv(envCache env.EnvCache, owner string, truststore kafkaStore, secretName string, mountPath string) error {
+	entries := []*core.EnvVar{{Name: ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_TYPE, Value: truststore.format}}
+	if truststore.format == STORE_FORMAT_PEM {
+		entries = append(entries, secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_CERTIFICATES, secretName, truststore.key))
+	} else {
+		entries = append(entries,
+			&core.EnvVar{Name: ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_LOCATION, Value: mountPath + "/" + truststore.key},
+			secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_TRUSTSTORE_PASSWORD, secretName, truststore.passwordKey))
+	}
+	return setStoreEnv(envCache, owner, entries)
+}
+
+// Set the keystore env. variables, the PKCS12 keystore must be mounted in the given directory.
+// The PEM private key must be in the unencrypted PKCS #8 format.
+func addKeystoreEnv(envCache env.EnvCache, owner string, keystore kafkaStore, secretName string, mountPath string) error {
+	entries := []*core.EnvVar{{Name: ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_TYPE, Value: keystore.format}}
+	if keystore.format == STORE_FORMAT_PEM {
+		entries = append(entries,
+			secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_CERTIFICATE_CHAIN, secretName, keystore.key),
+			secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_KEY, secretName, keystore.privateKeyKey))
+	} else {
+		entries = append(entries,
+			&core.EnvVar{Name: ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_LOCATION, Value: mountPath + "/" + keystore.key},
+			secretKeyEnv(ENV_REGISTRY_KAFKA_COMMON_SSL_KEYSTORE_PASSWORD, secretName, keystore.passwordKey))
+	}
+	return setStoreEnv(envCache, owner, entries)
+}
+
+func setStoreEnv(envCache env.EnvCache, owner string, entries []*core.EnvVar) error {
+	builtEntries := make([]env.EnvCacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		builtEntries = append(builtEntries, env.NewEnvCacheEntryBuilder(entry).SetOwner(owner).Build())
+	}
+	return setEnvEntries(envCache, builtEntries...)
+}
+
+// Set all the entries, and return the first error
+func setEnvEntries(envCache env.EnvCache, entries ...env.EnvCacheEntry) error {
+	var res error
+	for _, entry := range entries {
+		if err := envCache.Set(entry); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+// Return the format of the store configured in the env. variables, or an empty string
+func getStoreEnvFormat(envCache env.EnvCache, typeEnvName string) string {
+	if entry, exists := envCache.Get(typeEnvName); exists {
+		return entry.GetValue().Value
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package cf
+
+import (
+	"strconv"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+)
+
+const (
+	SqlPostgresClusterKindCnpg = "Cluster"
+	SqlPostgresClusterKindPgo  = "PostgresCluster"
+
+	sqlPostgresClusterRefOptionPath = "spec.configuration.sql.postgresClusterRef"
+	sqlPostgresDefaultPort          = 5432
+
+	// CloudNativePG conventions
+	cnpgDefaultDatabase    = "app"
+	cnpgAppSecretSuffix    = "-app"
+	cnpgWriteServiceSuffix = "-rw"
+	cnpgSecretUserKey      = "username"
+	cnpgSecretPasswordKey  = "password"
+
+	// PGO conventions
+	pgoPrimaryServiceSuffix = "-primary"
+	pgoUserSecretInfix      = "-pguser-"
+	pgoSecretUserKey        = "user"
+	pgoSecretPasswordKey    = "password"
+)
+
+// Connection to a PostgreSQL cluster managed by an operator.
+// The credentials are read from the Secret created by the operator.
+type sqlPostgresConnection struct {
+	url         string
+	secretName  string
+	userKey     string
+	passwordKey string
+}
+
+// Return the connection to the database of the application user, `app` by default
+func resolveCnpgCluster(cluster *client.CnpgCluster, namespace common.Namespace) sqlPostgresConnection {
+	service := cluster.Status.WriteService
+	if service == "" {
+		service = cluster.Name + cnpgWriteServiceSuffix
+	}
+	database := cnpgDefaultDatabase
+	secretName := cluster.Name + cnpgAppSecretSuffix
+	if bootstrap := cluster.Spec.Bootstrap; bootstrap != nil && bootstrap.Initdb != nil {
+		if bootstrap.Initdb.Database != "" {
+			database = bootstrap.Initdb.Database
+		}
+		if bootstrap.Initdb.Secret != nil && bootstrap.Initdb.Secret.Name != "" {
+			secretName = bootstrap.Initdb.Secret.Name
+		}
+	}
+	return sqlPostgresConnection{
+		url:         sqlPostgresJdbcUrl(service, namespace, sqlPostgresDefaultPort, database),
+		secretName:  secretName,
+		userKey:     cnpgSecretUserKey,
+		passwordKey: cnpgSecretPasswordKey,
+	}
+}
+
+// Return the connection to the first database of the first user that has one.
+// If no users are defined, PGO creates a user and a database with the same name as the cluster.
+// Returns a problem description instead, if there is no such user.
+func resolvePgoPostgresCluster(cluster *client.PgoPostgresCluster, namespace common.Namespace) (sqlPostgresConnection, string) {
+	user := cluster.Name
+	database := cluster.Name
+	if len(cluster.Spec.Users) > 0 {
+		found := false
+		for _, u := range cluster.Spec.Users {
+			if len(u.Databases) > 0 {
+				user = u.Name
+				database = u.Databases[0]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return sqlPostgresConnection{}, "PostgresCluster " + cluster.Name + " does not define a user with a database"
+		}
+	}
+	port := int32(sqlPostgresDefaultPort)
+	if cluster.Spec.Port != nil {
+		port = *cluster.Spec.Port
+	}
+	return sqlPostgresConnection{
+		url:         sqlPostgresJdbcUrl(cluster.Name+pgoPrimaryServiceSuffix, namespace, port, database),
+		secretName:  cluster.Name + pgoUserSecretInfix + user,
+		userKey:     pgoSecretUserKey,
+		passwordKey: pgoSecretPasswordKey,
+	}, ""
+}
+
+func sqlPostgresJdbcUrl(service string, namespace common.Namespace, port int32, database string) string {
+	return sqlJdbcUrlPrefix + "//" + service + "." + namespace.Str() + ".svc:" + strconv.Itoa(int(port)) + "/" + database
+}
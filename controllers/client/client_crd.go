@@ -12,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // =====
@@ -22,11 +23,14 @@ type CRDClient struct {
 	//ctx.client should be used instead of this rest client
 	client *rest.RESTClient
 	codec  runtime.ParameterCodec
+	scheme *runtime.Scheme
 }
 
 func NewCRDClient(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *CRDClient {
 
 	scheme.AddKnownTypes(ar.GroupVersion, &ar.ApicurioRegistry{}, &ar.ApicurioRegistryList{})
+	scheme.AddKnownTypes(ar.GroupVersion, &ar.ApicurioRegistryBackup{}, &ar.ApicurioRegistryBackupList{})
+	scheme.AddKnownTypes(ar.GroupVersion, &ar.ApicurioRegistryRestore{}, &ar.ApicurioRegistryRestoreList{})
 	meta.AddToGroupVersion(scheme, ar.GroupVersion)
 
 	config2 := rest.CopyConfig(config)
@@ -43,6 +47,7 @@ func NewCRDClient(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config)
 		client: c,
 		log:    log,
 		codec:  runtime.NewParameterCodec(scheme),
+		scheme: scheme,
 	}
 }
 
@@ -120,3 +125,103 @@ func (this *CRDClient) PatchApicurioRegistryStatus(namespace common.Namespace, n
 
 	return &result.Status, err
 }
+
+// ===
+// ApicurioRegistryBackup
+
+// Returns nil if the resource is not found, but request was OK
+func (this *CRDClient) GetApicurioRegistryBackup(namespace common.Namespace, name common.Name) (*ar.ApicurioRegistryBackup, error) {
+	result := &ar.ApicurioRegistryBackup{}
+	err := this.client.
+		Get().
+		Resource("apicurioregistrybackups").
+		Namespace(namespace.Str()).
+		Name(name.Str()).
+		Do(ctx.TODO()).
+		Into(result)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return result, err
+}
+
+func (this *CRDClient) CreateApicurioRegistryBackup(owner meta.Object, namespace common.Namespace, value *ar.ApicurioRegistryBackup) (*ar.ApicurioRegistryBackup, error) {
+	if err := controllerutil.SetControllerReference(owner, value, this.scheme); err != nil {
+		return nil, err
+	}
+	result := &ar.ApicurioRegistryBackup{}
+	err := this.client.
+		Post().
+		Resource("apicurioregistrybackups").
+		Namespace(namespace.Str()).
+		Body(value).
+		Do(ctx.TODO()).
+		Into(result)
+
+	return result, err
+}
+
+func (this *CRDClient) UpdateApicurioRegistryBackupStatus(namespace common.Namespace, value *ar.ApicurioRegistryBackup) (*ar.ApicurioRegistryBackup, error) {
+	result := &ar.ApicurioRegistryBackup{}
+	err := this.client.
+		Put().
+		Resource("apicurioregistrybackups").
+		SubResource("status").
+		Namespace(namespace.Str()).
+		Name(value.Name).
+		Body(value).
+		Do(ctx.TODO()).
+		Into(result)
+
+	return result, err
+}
+
+// ===
+// ApicurioRegistryRestore
+
+// Returns nil if the resource is not found, but request was OK
+func (this *CRDClient) GetApicurioRegistryRestore(namespace common.Namespace, name common.Name) (*ar.ApicurioRegistryRestore, error) {
+	result := &ar.ApicurioRegistryRestore{}
+	err := this.client.
+		Get().
+		Resource("apicurioregistryrestores").
+		Namespace(namespace.Str()).
+		Name(name.Str()).
+		Do(ctx.TODO()).
+		Into(result)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return result, err
+}
+
+func (this *CRDClient) CreateApicurioRegistryRestore(owner meta.Object, namespace common.Namespace, value *ar.ApicurioRegistryRestore) (*ar.ApicurioRegistryRestore, error) {
+	if err := controllerutil.SetControllerReference(owner, value, this.scheme); err != nil {
+		return nil, err
+	}
+	result := &ar.ApicurioRegistryRestore{}
+	err := this.client.
+		Post().
+		Resource("apicurioregistryrestores").
+		Namespace(namespace.Str()).
+		Body(value).
+		Do(ctx.TODO()).
+		Into(result)
+
+	return result, err
+}
+
+func (this *CRDClient) UpdateApicurioRegistryRestoreStatus(namespace common.Namespace, value *ar.ApicurioRegistryRestore) (*ar.ApicurioRegistryRestore, error) {
+	result := &ar.ApicurioRegistryRestore{}
+	err := this.client.
+		Put().
+		Resource("apicurioregistryrestores").
+		SubResource("status").
+		Namespace(namespace.Str()).
+		Name(value.Name).
+		Body(value).
+		Do(ctx.TODO()).
+		Into(result)
+
+	return result, err
+}
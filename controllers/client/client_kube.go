@@ -6,6 +6,7 @@ import (
 	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	policy_v1 "k8s.io/api/policy/v1"
@@ -15,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	cr_client "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -23,14 +25,17 @@ import (
 type KubeClient struct {
 	log    *zap.Logger
 	client kubernetes.Interface
-	scheme *runtime.Scheme
+	// Reads the resources watched by the controllers from the manager cache
+	cachedReader cr_client.Reader
+	scheme       *runtime.Scheme
 }
 
-func NewKubeClient(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *KubeClient {
+func NewKubeClient(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config, cachedReader cr_client.Reader) *KubeClient {
 	return &KubeClient{
-		client: kubernetes.NewForConfigOrDie(config),
-		log:    log,
-		scheme: scheme,
+		client:       kubernetes.NewForConfigOrDie(config),
+		cachedReader: cachedReader,
+		log:          log,
+		scheme:       scheme,
 	}
 }
 
@@ -290,6 +295,75 @@ func (this *KubeClient) GetPod(namespace common.Namespace, name common.Name) (*c
 		Get(ctx.TODO(), name.Str(), meta.GetOptions{})
 }
 
+func (this *KubeClient) GetPods(namespace common.Namespace, options meta.ListOptions) (*core.PodList, error) {
+	return this.client.CoreV1().Pods(namespace.Str()).
+		List(ctx.TODO(), options)
+}
+
+// Return the pods from the manager cache, which contains only the Apicurio Registry pods
+func (this *KubeClient) GetCachedPods(namespace common.Namespace, labels map[string]string) (*core.PodList, error) {
+	res := &core.PodList{}
+	if err := this.cachedReader.List(ctx.TODO(), res, cr_client.InNamespace(namespace.Str()), cr_client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ===
+// Job
+
+func (this *KubeClient) CreateJob(owner meta.Object, namespace common.Namespace, value *batch.Job) (*batch.Job, error) {
+	if owner == nil {
+		return nil, errors.New("Could not find the owner resource. Retrying.")
+	}
+	if err := controllerutil.SetControllerReference(owner, value, this.scheme); err != nil {
+		return nil, err
+	}
+	return this.client.BatchV1().Jobs(namespace.Str()).Create(ctx.TODO(), value, meta.CreateOptions{})
+}
+
+func (this *KubeClient) GetJob(namespace common.Namespace, name common.Name) (*batch.Job, error) {
+	return this.client.BatchV1().Jobs(namespace.Str()).
+		Get(ctx.TODO(), name.Str(), meta.GetOptions{})
+}
+
+func (this *KubeClient) DeleteJob(value *batch.Job) error {
+	propagation := meta.DeletePropagationBackground
+	return this.client.BatchV1().Jobs(value.Namespace).
+		Delete(ctx.TODO(), value.Name, meta.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// ===
+// ConfigMap
+
+func (this *KubeClient) CreateConfigMap(owner meta.Object, namespace common.Namespace, value *core.ConfigMap) (*core.ConfigMap, error) {
+	if owner == nil {
+		return nil, errors.New("Could not find the owner resource. Retrying.")
+	}
+	if err := controllerutil.SetControllerReference(owner, value, this.scheme); err != nil {
+		return nil, err
+	}
+	return this.client.CoreV1().ConfigMaps(namespace.Str()).Create(ctx.TODO(), value, meta.CreateOptions{})
+}
+
+func (this *KubeClient) GetConfigMap(namespace common.Namespace, name common.Name) (*core.ConfigMap, error) {
+	return this.client.CoreV1().ConfigMaps(namespace.Str()).
+		Get(ctx.TODO(), name.Str(), meta.GetOptions{})
+}
+
+func (this *KubeClient) DeleteConfigMap(value *core.ConfigMap) error {
+	return this.client.CoreV1().ConfigMaps(value.Namespace).
+		Delete(ctx.TODO(), value.Name, meta.DeleteOptions{})
+}
+
+// ===
+// PersistentVolumeClaim
+
+func (this *KubeClient) GetPersistentVolumeClaim(namespace common.Namespace, name common.Name) (*core.PersistentVolumeClaim, error) {
+	return this.client.CoreV1().PersistentVolumeClaims(namespace.Str()).
+		Get(ctx.TODO(), name.Str(), meta.GetOptions{})
+}
+
 func (this *KubeClient) CreateSecret(owner meta.Object, namespace common.Namespace, value *core.Secret) (*core.Secret, error) {
 	if owner == nil {
 		return nil, errors.New("Could not find ApicurioRegistry. Retrying.")
@@ -309,6 +383,11 @@ func (this *KubeClient) GetSecret(namespace common.Namespace, name common.Name,
 		Get(ctx.TODO(), name.Str(), *options)
 }
 
+func (this *KubeClient) UpdateSecret(namespace common.Namespace, value *core.Secret) (*core.Secret, error) {
+	return this.client.CoreV1().Secrets(namespace.Str()).
+		Update(ctx.TODO(), value, meta.UpdateOptions{})
+}
+
 func (this *KubeClient) DeleteSecret(value *core.Secret, options *meta.DeleteOptions) error {
 	return this.client.CoreV1().Secrets(value.Namespace).
 		Delete(ctx.TODO(), value.Name, *options)
@@ -0,0 +1,112 @@
+package client
+
+import (
+	ctx "context"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"go.uber.org/zap"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// PostgreSQL clusters managed by CloudNativePG or Crunchy Postgres for Kubernetes (PGO) are read using the dynamic client,
+// and converted to the minimal types below, which contain only the fields used by the operator.
+
+const CNPG_API_GROUP_VERSION = "postgresql.cnpg.io/v1"
+const PGO_API_GROUP_VERSION = "postgres-operator.crunchydata.com/v1beta1"
+
+var CnpgClusterGVR = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}
+var PgoPostgresClusterGVR = schema.GroupVersionResource{Group: "postgres-operator.crunchydata.com", Version: "v1beta1", Resource: "postgresclusters"}
+
+type CnpgCluster struct {
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	Spec            CnpgClusterSpec   `json:"spec,omitempty"`
+	Status          CnpgClusterStatus `json:"status,omitempty"`
+}
+
+type CnpgClusterSpec struct {
+	Bootstrap *CnpgBootstrap `json:"bootstrap,omitempty"`
+}
+
+type CnpgBootstrap struct {
+	Initdb *CnpgBootstrapInitdb `json:"initdb,omitempty"`
+}
+
+type CnpgBootstrapInitdb struct {
+	// Defaults to `app`
+	Database string `json:"database,omitempty"`
+	// Secret with the credentials of the database owner, defaults to `<cluster name>-app`
+	Secret *CnpgLocalObjectReference `json:"secret,omitempty"`
+}
+
+type CnpgLocalObjectReference struct {
+	Name string `json:"name,omitempty"`
+}
+
+type CnpgClusterStatus struct {
+	// Name of the read-write Service, `<cluster name>-rw`
+	WriteService string `json:"writeService,omitempty"`
+}
+
+type PgoPostgresCluster struct {
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	Spec            PgoPostgresClusterSpec `json:"spec,omitempty"`
+}
+
+type PgoPostgresClusterSpec struct {
+	// Defaults to 5432
+	Port  *int32    `json:"port,omitempty"`
+	Users []PgoUser `json:"users,omitempty"`
+}
+
+type PgoUser struct {
+	Name      string   `json:"name,omitempty"`
+	Databases []string `json:"databases,omitempty"`
+}
+
+// =====
+
+type PostgresClient struct {
+	log    *zap.Logger
+	client dynamic.Interface
+}
+
+func NewPostgresClient(log *zap.Logger, config *rest.Config) *PostgresClient {
+	return &PostgresClient{
+		log:    log,
+		client: dynamic.NewForConfigOrDie(config),
+	}
+}
+
+// ===
+// CloudNativePG Cluster
+
+func (this *PostgresClient) GetCnpgCluster(namespace common.Namespace, name common.Name) (*CnpgCluster, error) {
+	res := &CnpgCluster{}
+	if err := this.get(CnpgClusterGVR, namespace, name, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ===
+// PGO PostgresCluster
+
+func (this *PostgresClient) GetPgoPostgresCluster(namespace common.Namespace, name common.Name) (*PgoPostgresCluster, error) {
+	res := &PgoPostgresCluster{}
+	if err := this.get(PgoPostgresClusterGVR, namespace, name, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (this *PostgresClient) get(gvr schema.GroupVersionResource, namespace common.Namespace, name common.Name, target interface{}) error {
+	obj, err := this.client.Resource(gvr).Namespace(namespace.Str()).Get(ctx.TODO(), name.Str(), meta.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), target)
+}
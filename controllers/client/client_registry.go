@@ -0,0 +1,123 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	core "k8s.io/api/core/v1"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// =====
+
+const REGISTRY_API_V2_PATH = "/apis/registry/v2"
+
+// The system info is small, so the request should not take long
+const systemInfoTimeout = 5 * time.Second
+
+// The CA that signs the serving certificates of Services on OpenShift, it is mounted into every pod
+const SERVICE_CA_FILE = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+// RegistryEndpoint describes how to connect to an Apicurio Registry instance
+type RegistryEndpoint struct {
+	// Base URL, for example: `https://example-service.namespace.svc:8443`
+	BaseUrl string
+	// CA certificates in the PEM format, trusted in addition to the system ones
+	CaBundle string
+	// Name used to verify the HTTPS certificate, if the base URL uses an IP address
+	ServerName string
+}
+
+// SystemInfo describes the Apicurio Registry application
+type SystemInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// RegistryClient calls the REST API of an Apicurio Registry instance.
+// Authentication is not supported, the callers must check that it is not enabled.
+type RegistryClient struct {
+	log *zap.Logger
+}
+
+func NewRegistryClient(log *zap.Logger) *RegistryClient {
+	return &RegistryClient{
+		log: log,
+	}
+}
+
+// GetRegistryCaBundle returns the CA certificates that can verify the HTTPS certificate of an Apicurio Registry instance:
+// the OpenShift service CA, if available, and the `ca.crt` key of the HTTPS Secret, if present.
+func GetRegistryCaBundle(httpsSecret *core.Secret) string {
+	res := ""
+	if data, err := ioutil.ReadFile(SERVICE_CA_FILE); err == nil {
+		res += strings.TrimSpace(string(data)) + "\n"
+	}
+	if httpsSecret != nil {
+		if data, exists := httpsSecret.Data["ca.crt"]; exists {
+			res += strings.TrimSpace(string(data)) + "\n"
+		}
+	}
+	return res
+}
+
+func (this *RegistryClient) newHttpClient(endpoint *RegistryEndpoint, timeout time.Duration) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if endpoint.CaBundle != "" && !pool.AppendCertsFromPEM([]byte(endpoint.CaBundle)) {
+		return nil, fmt.Errorf("could not parse the CA certificates of %s", endpoint.BaseUrl)
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				ServerName: endpoint.ServerName,
+			},
+		},
+	}, nil
+}
+
+// GetSystemInfo returns the name and version of the Apicurio Registry application
+func (this *RegistryClient) GetSystemInfo(endpoint *RegistryEndpoint) (*SystemInfo, error) {
+	httpClient, err := this.newHttpClient(endpoint, systemInfoTimeout)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(endpoint.BaseUrl, "/") + REGISTRY_API_V2_PATH + "/system/info"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkRegistryResponse(res, url); err != nil {
+		return nil, err
+	}
+	info := &SystemInfo{}
+	if err := json.NewDecoder(res.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("could not read the response from %s: %w", url, err)
+	}
+	return info, nil
+}
+
+func checkRegistryResponse(res *http.Response, url string) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+	return fmt.Errorf("request to %s has failed with status %d: %s", url, res.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/pem"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistrySystemInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != REGISTRY_API_V2_PATH+"/system/info" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"Apicurio Registry (SQL)","description":"High performance, runtime registry for schemas and API designs.","version":"2.5.0.Final","builtOn":"2023-10-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	client := NewRegistryClient(zap.NewNop())
+	info, err := client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL + "/"})
+	c.AssertEquals(t, nil, err)
+	c.AssertEquals(t, "2.5.0.Final", info.Version)
+	c.AssertEquals(t, "Apicurio Registry (SQL)", info.Name)
+
+	_, err = client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL + "/missing"})
+	c.AssertEquals(t, true, err != nil)
+}
+
+func TestRegistryTls(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":"2.5.0.Final"}`))
+	}))
+	defer server.Close()
+	caBundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	client := NewRegistryClient(zap.NewNop())
+	// The certificate is not trusted
+	_, err := client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL})
+	c.AssertEquals(t, true, err != nil)
+
+	info, err := client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL, CaBundle: caBundle})
+	c.AssertEquals(t, nil, err)
+	c.AssertEquals(t, "2.5.0.Final", info.Version)
+
+	// The certificate of the test server is valid for example.com and the loopback addresses
+	_, err = client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL, CaBundle: caBundle, ServerName: "example.com"})
+	c.AssertEquals(t, nil, err)
+	_, err = client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL, CaBundle: caBundle, ServerName: "registry.example.org"})
+	c.AssertEquals(t, true, err != nil)
+
+	_, err = client.GetSystemInfo(&RegistryEndpoint{BaseUrl: server.URL, CaBundle: "invalid"})
+	c.AssertEquals(t, true, err != nil)
+}
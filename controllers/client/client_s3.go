@@ -0,0 +1,365 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =====
+
+const S3_DEFAULT_REGION = "us-east-1"
+
+// S3Client is a minimal client for S3-compatible object storage (e.g. Amazon S3 or MinIO),
+// supporting only the operations needed to manage backup files.
+// Requests use path-style addressing and are signed using AWS Signature Version 4.
+type S3Client struct {
+	log        *zap.Logger
+	httpClient *http.Client
+	endpoint   *url.URL
+	bucket     string
+	region     string
+	accessKey  string
+	secretKey  string
+	now        func() time.Time
+}
+
+type S3Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+func NewS3Client(log *zap.Logger, endpoint string, bucket string, region string, accessKey string, secretKey string, insecure bool) (*S3Client, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, errors.New("S3 endpoint must be an absolute http or https URL, got " + endpoint)
+	}
+	if bucket == "" {
+		return nil, errors.New("S3 bucket must not be empty")
+	}
+	if region == "" {
+		region = S3_DEFAULT_REGION
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &S3Client{
+		log: log,
+		httpClient: &http.Client{
+			Timeout:   5 * time.Minute,
+			Transport: transport,
+		},
+		endpoint:  parsed,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		now:       time.Now,
+	}, nil
+}
+
+// PutObject uploads the content, which is read twice (to compute the payload hash and to send it),
+// so large objects do not have to be kept in memory.
+func (this *S3Client) PutObject(key string, content io.ReadSeeker, contentType string) error {
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	res, err := this.do(http.MethodPut, key, nil, headers, content, size, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return checkS3Response(res, "put object "+key)
+}
+
+// GetObject returns the object content, which must be closed by the caller.
+func (this *S3Client) GetObject(key string) (io.ReadCloser, int64, error) {
+	res, err := this.do(http.MethodGet, key, nil, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkS3Response(res, "get object "+key); err != nil {
+		res.Body.Close()
+		return nil, 0, err
+	}
+	return res.Body, res.ContentLength, nil
+}
+
+func (this *S3Client) DeleteObject(key string) error {
+	res, err := this.do(http.MethodDelete, key, nil, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return checkS3Response(res, "delete object "+key)
+}
+
+// ListObjects returns all objects with the given key prefix, sorted by key.
+func (this *S3Client) ListObjects(prefix string) ([]S3Object, error) {
+	result := make([]S3Object, 0)
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		res, err := this.do(http.MethodGet, "", query, nil, nil, 0, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return nil, fmt.Errorf("could not list objects with prefix %s: status %d: %s", prefix, res.StatusCode, s3ErrorMessage(body))
+		}
+		list := &s3ListBucketResult{}
+		if err := xml.Unmarshal(body, list); err != nil {
+			return nil, err
+		}
+		for _, c := range list.Contents {
+			result = append(result, S3Object{Key: c.Key, Size: c.Size, LastModified: c.LastModified})
+		}
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = list.NextContinuationToken
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+type s3ListBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func s3ErrorMessage(body []byte) string {
+	e := &s3Error{}
+	if err := xml.Unmarshal(body, e); err == nil && e.Code != "" {
+		return e.Code + ": " + e.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func checkS3Response(res *http.Response, operation string) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+	return fmt.Errorf("could not %s: status %d: %s", operation, res.StatusCode, s3ErrorMessage(body))
+}
+
+// SHA-256 of an empty payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// PresignPutObject returns a URL that can be used to upload the object without the credentials, until it expires.
+// The upload can be performed e.g. by `curl --upload-file`, the payload is not signed.
+func (this *S3Client) PresignPutObject(key string, expires time.Duration) string {
+	target := this.objectUrl(key)
+	this.presign(http.MethodPut, target, expires)
+	return target.String()
+}
+
+// PresignGetObject returns a URL that can be used to download the object without the credentials, until it expires
+func (this *S3Client) PresignGetObject(key string, expires time.Duration) string {
+	target := this.objectUrl(key)
+	this.presign(http.MethodGet, target, expires)
+	return target.String()
+}
+
+// objectUrl returns the path-style URL of the object, or of the bucket if the key is empty
+func (this *S3Client) objectUrl(key string) *url.URL {
+	target := *this.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + this.bucket
+	if key != "" {
+		target.Path = target.Path + "/" + key
+	}
+	target.RawPath = s3EncodePath(target.Path)
+	return &target
+}
+
+func (this *S3Client) do(method string, key string, query url.Values, headers map[string]string,
+	body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	target := this.objectUrl(key)
+	if query != nil {
+		target.RawQuery = s3EncodeQuery(query)
+	}
+
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	this.sign(req, target.RawPath, payloadHash)
+	return this.httpClient.Do(req)
+}
+
+// presign adds AWS Signature Version 4 query parameters to the URL, only the host header is signed
+func (this *S3Client) presign(method string, target *url.URL, expires time.Duration) {
+	now := this.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + this.region + "/s3/aws4_request"
+
+	query := target.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", this.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	target.RawQuery = s3EncodeQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3EncodePath(target.Path),
+		target.RawQuery,
+		"host:" + target.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	target.RawQuery += "&X-Amz-Signature=" + this.signature(now, canonicalRequest)
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (this *S3Client) sign(req *http.Request, canonicalPath string, payloadHashHex string) {
+	now := this.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHashHex)
+
+	signedHeaderNames := make([]string, 0)
+	for name := range req.Header {
+		signedHeaderNames = append(signedHeaderNames, strings.ToLower(name))
+	}
+	sort.Strings(signedHeaderNames)
+	canonicalHeaders := ""
+	for _, name := range signedHeaderNames {
+		canonicalHeaders += name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n"
+	}
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHashHex,
+	}, "\n")
+	scope := date + "/" + this.region + "/s3/aws4_request"
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+this.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+this.signature(now, canonicalRequest))
+}
+
+// signature returns the hex-encoded signature of the canonical request
+func (this *S3Client) signature(now time.Time, canonicalRequest string) string {
+	date := now.Format("20060102")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		date + "/" + this.region + "/s3/aws4_request",
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+this.secretKey), date)
+	key = hmacSHA256(key, this.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EncodePath encodes each path segment as required by the SigV4 canonical URI
+func s3EncodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = s3Encode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3EncodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Encode(k)+"="+s3Encode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3Encode(value string) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	ctx "context"
+	"errors"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"go.uber.org/zap"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Strimzi resources are read using the dynamic client, and converted to the minimal types below,
+// which contain only the fields used by the operator.
+
+const STRIMZI_API_GROUP_VERSION = "kafka.strimzi.io/v1beta2"
+
+var StrimziKafkaGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkas"}
+var StrimziKafkaUserGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkausers"}
+var StrimziKafkaTopicGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkatopics"}
+
+// Label that references the Kafka cluster of a KafkaTopic or KafkaUser
+const STRIMZI_CLUSTER_LABEL = "strimzi.io/cluster"
+
+type StrimziCondition struct {
+	Type    string `json:"type,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type StrimziKafka struct {
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	Spec            StrimziKafkaSpec   `json:"spec,omitempty"`
+	Status          StrimziKafkaStatus `json:"status,omitempty"`
+}
+
+type StrimziKafkaSpec struct {
+	Kafka StrimziKafkaClusterSpec `json:"kafka,omitempty"`
+}
+
+type StrimziKafkaClusterSpec struct {
+	Replicas  int32                  `json:"replicas,omitempty"`
+	Listeners []StrimziKafkaListener `json:"listeners,omitempty"`
+}
+
+type StrimziKafkaListener struct {
+	Name           string                              `json:"name,omitempty"`
+	Type           string                              `json:"type,omitempty"`
+	Tls            bool                                `json:"tls,omitempty"`
+	Authentication *StrimziKafkaListenerAuthentication `json:"authentication,omitempty"`
+}
+
+type StrimziKafkaListenerAuthentication struct {
+	Type string `json:"type,omitempty"`
+}
+
+type StrimziKafkaStatus struct {
+	Conditions []StrimziCondition           `json:"conditions,omitempty"`
+	Listeners  []StrimziKafkaListenerStatus `json:"listeners,omitempty"`
+}
+
+type StrimziKafkaListenerStatus struct {
+	Name             string `json:"name,omitempty"`
+	Type             string `json:"type,omitempty"`
+	BootstrapServers string `json:"bootstrapServers,omitempty"`
+}
+
+type StrimziKafkaUser struct {
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	Spec            StrimziKafkaUserSpec   `json:"spec,omitempty"`
+	Status          StrimziKafkaUserStatus `json:"status,omitempty"`
+}
+
+type StrimziKafkaUserSpec struct {
+	Authentication StrimziKafkaListenerAuthentication `json:"authentication,omitempty"`
+}
+
+type StrimziKafkaUserStatus struct {
+	Conditions []StrimziCondition `json:"conditions,omitempty"`
+	Username   string             `json:"username,omitempty"`
+	Secret     string             `json:"secret,omitempty"`
+}
+
+type StrimziKafkaTopic struct {
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	Spec            StrimziKafkaTopicSpec   `json:"spec,omitempty"`
+	Status          StrimziKafkaTopicStatus `json:"status,omitempty"`
+}
+
+type StrimziKafkaTopicSpec struct {
+	// Defaults to the resource name
+	TopicName  string                 `json:"topicName,omitempty"`
+	Partitions int32                  `json:"partitions,omitempty"`
+	Replicas   int32                  `json:"replicas,omitempty"`
+	Config     map[string]interface{} `json:"config,omitempty"`
+}
+
+type StrimziKafkaTopicStatus struct {
+	Conditions []StrimziCondition `json:"conditions,omitempty"`
+}
+
+// Return the name of the Kafka topic
+func (this *StrimziKafkaTopic) GetTopicName() string {
+	if this.Spec.TopicName != "" {
+		return this.Spec.TopicName
+	}
+	return this.Name
+}
+
+// Return the Ready condition of the Strimzi resource, or nil
+func GetStrimziReadyCondition(conditions []StrimziCondition) *StrimziCondition {
+	for i, c := range conditions {
+		if c.Type == "Ready" {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// =====
+
+type StrimziClient struct {
+	log    *zap.Logger
+	client dynamic.Interface
+	scheme *runtime.Scheme
+}
+
+func NewStrimziClient(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *StrimziClient {
+	return &StrimziClient{
+		log:    log,
+		client: dynamic.NewForConfigOrDie(config),
+		scheme: scheme,
+	}
+}
+
+// ===
+// Kafka
+
+func (this *StrimziClient) GetKafka(namespace common.Namespace, name common.Name) (*StrimziKafka, error) {
+	res := &StrimziKafka{}
+	if err := this.get(StrimziKafkaGVR, namespace, name, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ===
+// KafkaUser
+
+func (this *StrimziClient) GetKafkaUser(namespace common.Namespace, name common.Name) (*StrimziKafkaUser, error) {
+	res := &StrimziKafkaUser{}
+	if err := this.get(StrimziKafkaUserGVR, namespace, name, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ===
+// KafkaTopic
+
+func (this *StrimziClient) GetKafkaTopics(namespace common.Namespace, options meta.ListOptions) ([]StrimziKafkaTopic, error) {
+	list, err := this.client.Resource(StrimziKafkaTopicGVR).Namespace(namespace.Str()).List(ctx.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]StrimziKafkaTopic, len(list.Items))
+	for i, item := range list.Items {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), &res[i]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (this *StrimziClient) CreateKafkaTopic(owner meta.Object, namespace common.Namespace, obj *StrimziKafkaTopic) error {
+	if owner == nil {
+		return errors.New("Could not find ApicurioRegistry. Retrying.")
+	}
+	if err := controllerutil.SetControllerReference(owner, obj, this.scheme); err != nil {
+		return err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	value := &unstructured.Unstructured{Object: content}
+	value.SetAPIVersion(STRIMZI_API_GROUP_VERSION)
+	value.SetKind("KafkaTopic")
+	_, err = this.client.Resource(StrimziKafkaTopicGVR).Namespace(namespace.Str()).Create(ctx.TODO(), value, meta.CreateOptions{})
+	return err
+}
+
+func (this *StrimziClient) get(gvr schema.GroupVersionResource, namespace common.Namespace, name common.Name, target interface{}) error {
+	obj, err := this.client.Resource(gvr).Namespace(namespace.Str()).Get(ctx.TODO(), name.Str(), meta.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), target)
+}
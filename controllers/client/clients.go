@@ -5,6 +5,7 @@ import (
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	cr_client "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Clients struct {
@@ -15,11 +16,15 @@ type Clients struct {
 	ocpClient        *OCPClient
 	crdClient        *CRDClient
 	monitoringClient *MonitoringClient
+	strimziClient    *StrimziClient
+	postgresClient   *PostgresClient
 	discoveryClient  *DiscoveryClient
+	registryClient   *RegistryClient
 	scheme           *runtime.Scheme
 }
 
-func NewClients(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *Clients {
+// The cached reader is the client of the manager, which reads the watched resources from the cache
+func NewClients(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config, cachedReader cr_client.Reader) *Clients {
 	this := &Clients{
 		scheme: scheme,
 		log:    log,
@@ -32,7 +37,7 @@ func NewClients(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *C
 	//config := ctx.GetClientConfig()
 	log.Sugar().Debugw("client config values", "config", config)
 
-	this.kubeClient = NewKubeClient(log, scheme, config)
+	this.kubeClient = NewKubeClient(log, scheme, config, cachedReader)
 
 	this.ocpClient = NewOCPClient(log, scheme, config)
 
@@ -40,8 +45,14 @@ func NewClients(log *zap.Logger, scheme *runtime.Scheme, config *rest.Config) *C
 
 	this.monitoringClient = NewMonitoringClient(log, scheme, config)
 
+	this.strimziClient = NewStrimziClient(log, scheme, config)
+
+	this.postgresClient = NewPostgresClient(log, config)
+
 	this.discoveryClient = NewDiscoveryClient(log, config)
 
+	this.registryClient = NewRegistryClient(log)
+
 	return this
 }
 
@@ -61,6 +72,18 @@ func (this *Clients) Monitoring() *MonitoringClient {
 	return this.monitoringClient
 }
 
+func (this *Clients) Strimzi() *StrimziClient {
+	return this.strimziClient
+}
+
+func (this *Clients) Postgres() *PostgresClient {
+	return this.postgresClient
+}
+
 func (this *Clients) Discovery() *DiscoveryClient {
 	return this.discoveryClient
 }
+
+func (this *Clients) Registry() *RegistryClient {
+	return this.registryClient
+}
@@ -19,9 +19,12 @@ func (s Name) Str() string {
 //}
 
 type SupportedFeatures struct {
-	IsOCP               bool
-	SupportsPDBv1       bool
-	SupportsPDBv1beta1  bool
-	PreferredPDBVersion string
-	SupportsMonitoring  bool
+	IsOCP                   bool
+	SupportsPDBv1           bool
+	SupportsPDBv1beta1      bool
+	PreferredPDBVersion     string
+	SupportsMonitoring      bool
+	SupportsStrimzi         bool
+	SupportsCloudNativePG   bool
+	SupportsCrunchyPostgres bool
 }
@@ -323,6 +323,15 @@ func LabelsUpdate(target *map[string]string, source map[string]string) {
 	}
 }
 
+func ContainsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func GetContainerByName(containers []core.Container, name string) *core.Container {
 	for i, c := range containers {
 		if c.Name == name {
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	"go.uber.org/zap"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// fakeApiServer is an in-memory Kubernetes API server that supports a single namespace,
+// and the get, list, create, update and delete requests used by the controllers
+type fakeApiServer struct {
+	t       *testing.T
+	lock    sync.Mutex
+	objects map[string]map[string]json.RawMessage
+	// Method and path of the requests that modify objects, in order
+	requests []string
+}
+
+func newFakeApiServer(t *testing.T) (*fakeApiServer, *client.Clients) {
+	fakeServer := &fakeApiServer{t: t, objects: make(map[string]map[string]json.RawMessage)}
+	server := httptest.NewServer(fakeServer)
+	t.Cleanup(server.Close)
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fakeServer, client.NewClients(zap.NewNop(), scheme, &rest.Config{Host: server.URL}, nil)
+}
+
+func (this *fakeApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	// /api/v1/namespaces/<namespace>/<resource>[/<name>[/status]]
+	// /apis/<group>/<version>/namespaces/<namespace>/<resource>[/<name>[/status]]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if parts[0] == "api" {
+		parts = append([]string{"api", ""}, parts[1:]...)
+	}
+	if len(parts) < 6 || parts[3] != "namespaces" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resource := parts[5]
+	name := ""
+	if len(parts) > 6 {
+		name = parts[6]
+	}
+	if this.objects[resource] == nil {
+		this.objects[resource] = make(map[string]json.RawMessage)
+	}
+	if r.Method != http.MethodGet {
+		this.requests = append(this.requests, r.Method+" "+resource+"/"+name)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		selector := strings.SplitN(r.URL.Query().Get("labelSelector"), "=", 2)
+		items := make([]json.RawMessage, 0)
+		for _, object := range this.objects[resource] {
+			metadata := &meta.PartialObjectMetadata{}
+			if err := json.Unmarshal(object, metadata); err != nil {
+				this.t.Error(err)
+			}
+			if len(selector) == 2 && metadata.Labels[selector[0]] != selector[1] {
+				continue
+			}
+			items = append(items, object)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"metadata": map[string]string{}, "items": items})
+	case r.Method == http.MethodGet:
+		if object, exists := this.objects[resource][name]; exists {
+			w.Write(object)
+			return
+		}
+		this.writeStatus(w, http.StatusNotFound, meta.StatusReasonNotFound)
+	case r.Method == http.MethodPost && name == "":
+		var body json.RawMessage
+		metadata := &meta.PartialObjectMetadata{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || json.Unmarshal(body, metadata) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, exists := this.objects[resource][metadata.Name]; exists {
+			this.writeStatus(w, http.StatusConflict, meta.StatusReasonAlreadyExists)
+			return
+		}
+		this.objects[resource][metadata.Name] = body
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	case r.Method == http.MethodPut && name != "":
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, exists := this.objects[resource][name]; !exists {
+			this.writeStatus(w, http.StatusNotFound, meta.StatusReasonNotFound)
+			return
+		}
+		this.objects[resource][name] = body
+		w.Write(body)
+	case r.Method == http.MethodDelete && name != "":
+		if _, exists := this.objects[resource][name]; !exists {
+			this.writeStatus(w, http.StatusNotFound, meta.StatusReasonNotFound)
+			return
+		}
+		delete(this.objects[resource], name)
+		this.writeStatus(w, http.StatusOK, "")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (this *fakeApiServer) writeStatus(w http.ResponseWriter, code int, reason meta.StatusReason) {
+	status := meta.StatusSuccess
+	if code >= 300 {
+		status = meta.StatusFailure
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&meta.Status{
+		TypeMeta: meta.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   status,
+		Reason:   reason,
+		Code:     int32(code),
+	})
+}
+
+// put stores the object, replacing the existing one
+func (this *fakeApiServer) put(resource string, name string, value interface{}) {
+	this.t.Helper()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	object, err := json.Marshal(value)
+	if err != nil {
+		this.t.Fatal(err)
+	}
+	if this.objects[resource] == nil {
+		this.objects[resource] = make(map[string]json.RawMessage)
+	}
+	this.objects[resource][name] = object
+}
+
+// get reads the object into the value, and returns false if it does not exist
+func (this *fakeApiServer) get(resource string, name string, value interface{}) bool {
+	this.t.Helper()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	object, exists := this.objects[resource][name]
+	if !exists {
+		return false
+	}
+	if err := json.Unmarshal(object, value); err != nil {
+		this.t.Fatal(err)
+	}
+	return true
+}
+
+func (this *fakeApiServer) delete(resource string, name string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.objects[resource], name)
+}
+
+func (this *fakeApiServer) count(resource string) int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return len(this.objects[resource])
+}
+
+// takeRequests returns the modifying requests received since the last call
+func (this *fakeApiServer) takeRequests() []string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	res := this.requests
+	this.requests = nil
+	return res
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
+	"k8s.io/client-go/tools/record"
 	"time"
 )
 
@@ -24,4 +25,5 @@ type LoopContext interface {
 	GetAttempts() int
 	GetTestingSupport() *c.TestSupport
 	GetSupportedFeatures() *c.SupportedFeatures
+	GetEventRecorder() record.EventRecorder
 }
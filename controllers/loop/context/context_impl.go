@@ -6,6 +6,7 @@ import (
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
+	"k8s.io/client-go/tools/record"
 	"time"
 )
 
@@ -24,19 +25,21 @@ type loopContext struct {
 	clients       *client.Clients
 	testing       *c.TestSupport
 	features      *c.SupportedFeatures
+	eventRecorder record.EventRecorder
 }
 
 // Create a new context when the operator is deployed, provide mostly static data
-func NewLoopContext(appName c.Name, appNamespace c.Namespace, log *zap.Logger, clients *client.Clients, testing *c.TestSupport, features *c.SupportedFeatures) LoopContext {
+func NewLoopContext(appName c.Name, appNamespace c.Namespace, log *zap.Logger, clients *client.Clients, testing *c.TestSupport, features *c.SupportedFeatures, eventRecorder record.EventRecorder) LoopContext {
 	this := &loopContext{
-		appName:      appName,
-		appNamespace: appNamespace,
-		requeue:      false,
-		requeueDelay: 0,
-		clients:      clients,
-		testing:      testing,
-		log:          log,
-		features:     features,
+		appName:       appName,
+		appNamespace:  appNamespace,
+		requeue:       false,
+		requeueDelay:  0,
+		clients:       clients,
+		testing:       testing,
+		log:           log,
+		features:      features,
+		eventRecorder: eventRecorder,
 	}
 	this.resourceCache = resources.NewResourceCache()
 	this.envCache = env.NewEnvCache(log)
@@ -106,3 +109,7 @@ func (this *loopContext) GetTestingSupport() *c.TestSupport {
 func (this *loopContext) GetSupportedFeatures() *c.SupportedFeatures {
 	return this.features
 }
+
+func (this *loopContext) GetEventRecorder() record.EventRecorder {
+	return this.eventRecorder
+}
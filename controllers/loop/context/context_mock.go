@@ -6,6 +6,7 @@ import (
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/env"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"go.uber.org/zap"
+	"k8s.io/client-go/tools/record"
 	"time"
 )
 
@@ -18,6 +19,9 @@ type LoopContextMock struct {
 	resourceCache resources.ResourceCache
 	envCache      env.EnvCache
 	attempts      int
+	requeue       bool
+	requeueDelay  time.Duration
+	clients       *client.Clients
 }
 
 func NewLoopContextMock() *LoopContextMock {
@@ -44,27 +48,42 @@ func (this *LoopContextMock) GetAppNamespace() c.Namespace {
 }
 
 func (this *LoopContextMock) SetRequeueNow() {
-	panic("Not implemented")
+	this.SetRequeueDelaySec(0)
 }
 
 func (this *LoopContextMock) SetRequeueDelaySoon() {
-	panic("Not implemented")
+	this.SetRequeueDelaySec(5)
 }
 
 func (this *LoopContextMock) SetRequeueDelaySec(delay uint) {
-	panic("Not implemented")
+	d := time.Duration(delay) * time.Second
+	if this.requeue == false || d < this.requeueDelay {
+		this.requeueDelay = d
+		this.requeue = true
+	}
 }
 
 func (this *LoopContextMock) GetAndResetRequeue() (bool, time.Duration) {
-	panic("Not implemented")
+	defer func() {
+		this.requeue = false
+		this.requeueDelay = 0
+	}()
+	return this.requeue, this.requeueDelay
 }
 
 func (this *LoopContextMock) GetResourceCache() resources.ResourceCache {
 	return this.resourceCache
 }
 
+func (this *LoopContextMock) SetClients(clients *client.Clients) {
+	this.clients = clients
+}
+
 func (this *LoopContextMock) GetClients() *client.Clients {
-	panic("Not implemented")
+	if this.clients == nil {
+		panic("Not implemented")
+	}
+	return this.clients
 }
 
 func (this *LoopContextMock) GetEnvCache() env.EnvCache {
@@ -86,3 +105,7 @@ func (this *LoopContextMock) GetTestingSupport() *c.TestSupport {
 func (this *LoopContextMock) GetSupportedFeatures() *c.SupportedFeatures {
 	panic("Not implemented")
 }
+
+func (this *LoopContextMock) GetEventRecorder() record.EventRecorder {
+	panic("Not implemented")
+}
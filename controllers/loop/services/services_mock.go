@@ -11,10 +11,15 @@ import (
 var _ LoopServices = &LoopServicesMock{}
 
 type LoopServicesMock struct {
+	conditionManager conditions.ConditionManager
+	kubeFactory      *factory.KubeFactory
 }
 
 func NewLoopServicesMock(ctx context.LoopContext) *LoopServicesMock {
-	this := &LoopServicesMock{}
+	this := &LoopServicesMock{
+		conditionManager: conditions.NewConditionManager(ctx),
+		kubeFactory:      factory.NewKubeFactory(ctx),
+	}
 	return this
 }
 
@@ -33,7 +38,7 @@ func (this *LoopServicesMock) GetPatchers() *patcher.Patchers {
 }
 
 func (this *LoopServicesMock) GetKubeFactory() *factory.KubeFactory {
-	panic("Not implemented")
+	return this.kubeFactory
 }
 
 func (this *LoopServicesMock) GetMonitoringFactory() *factory.MonitoringFactory {
@@ -41,7 +46,7 @@ func (this *LoopServicesMock) GetMonitoringFactory() *factory.MonitoringFactory
 }
 
 func (this *LoopServicesMock) GetConditionManager() conditions.ConditionManager {
-	panic("Not implemented")
+	return this.conditionManager
 }
 
 func (this *LoopServicesMock) GetStatus() *status.Status {
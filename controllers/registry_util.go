@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	ar "github.com/Apicurio/apicurio-registry-operator/api/v1"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/cf"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/client"
+	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
+	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	"go.uber.org/zap"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	api_meta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Image used by the Jobs that export or import the backup files, it must contain `sh` and `curl`
+	ENV_OPERATOR_BACKUP_JOB_IMAGE = "REGISTRY_BACKUP_JOB_IMAGE"
+	DEFAULT_BACKUP_JOB_IMAGE      = "registry.access.redhat.com/ubi8/ubi-minimal:8.7"
+
+	BACKUP_S3_ACCESS_KEY_ID_KEY     = "AWS_ACCESS_KEY_ID"
+	BACKUP_S3_SECRET_ACCESS_KEY_KEY = "AWS_SECRET_ACCESS_KEY"
+
+	REGISTRY_AUTH_TOKEN_KEY         = "token"
+	REGISTRY_AUTH_CLIENT_ID_KEY     = "clientId"
+	REGISTRY_AUTH_CLIENT_SECRET_KEY = "clientSecret"
+
+	jobPollDelay   = 10 * time.Second
+	jobBackupMount = "/backup"
+	// Delay before checking again whether the ApicurioRegistry configuration is supported
+	registryRecheckDelay = time.Minute
+)
+
+// Configures the options of the curl commands that call Apicurio Registry.
+// The HTTPS certificate is verified using the provided CA bundle, and the CA certificates of the system.
+// If credentials are provided, the access token is sent in the Authorization header,
+// which is read from a file, so it is not visible in the process list.
+// The client credentials are exchanged for an access token first,
+// the token endpoint is discovered from the OIDC provider configuration if it is not provided.
+const registryCurlScript = `registry_curl_options="--fail --silent --show-error"
+if [ -n "${REGISTRY_CA_BUNDLE:-}" ]; then
+  printf '%s\n' "$REGISTRY_CA_BUNDLE" > /tmp/registry-ca.crt
+  for f in /etc/pki/tls/certs/ca-bundle.crt /etc/ssl/certs/ca-certificates.crt; do
+    if [ -f "$f" ]; then
+      cat "$f" >> /tmp/registry-ca.crt
+    fi
+  done
+  registry_curl_options="$registry_curl_options --cacert /tmp/registry-ca.crt"
+fi
+json_value() {
+  tr ',' '\n' | sed -n "s/^[[:space:]{]*\"$1\"[[:space:]]*:[[:space:]]*\"\([^\"]*\)\".*/\1/p" | head -n 1
+}
+if [ -n "${REGISTRY_TOKEN:-}" ] || [ -n "${REGISTRY_CLIENT_ID:-}" ]; then
+  token="${REGISTRY_TOKEN:-}"
+  if [ -z "$token" ]; then
+    token_endpoint="${REGISTRY_TOKEN_ENDPOINT:-}"
+    if [ -z "$token_endpoint" ]; then
+      token_endpoint=$(curl $registry_curl_options "${REGISTRY_OIDC_SERVER_URL%/}/.well-known/openid-configuration" | json_value token_endpoint)
+    fi
+    if [ -z "$token_endpoint" ]; then
+      echo "could not discover the token endpoint of $REGISTRY_OIDC_SERVER_URL" >&2
+      exit 1
+    fi
+    token=$(curl $registry_curl_options --data-urlencode grant_type=client_credentials \
+      --data-urlencode "client_id=$REGISTRY_CLIENT_ID" --data-urlencode "client_secret=$REGISTRY_CLIENT_SECRET" \
+      "$token_endpoint" | json_value access_token)
+    if [ -z "$token" ]; then
+      echo "could not get an access token from $token_endpoint" >&2
+      exit 1
+    fi
+  fi
+  printf 'Authorization: Bearer %s\n' "$token" > /tmp/registry-auth-header
+  registry_curl_options="$registry_curl_options -H @/tmp/registry-auth-header"
+fi
+`
+
+// getRegistryEndpoint returns the cluster-internal URL of the Apicurio Registry Service,
+// e.g. `http://example-service.namespace.svc:8080`, and the CA certificates to verify it if HTTPS is used.
+func getRegistryEndpoint(clients *client.Clients, registry *ar.ApicurioRegistry) (*client.RegistryEndpoint, error) {
+	url, err := getRegistryServiceUrl(clients, registry)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &client.RegistryEndpoint{BaseUrl: url}
+	if strings.HasPrefix(url, "https://") {
+		var httpsSecret *core.Secret
+		if secretName := registry.Spec.Configuration.Security.Https.SecretName; secretName != "" {
+			if httpsSecret, err = clients.Kube().GetSecret(c.Namespace(registry.Namespace), c.Name(secretName), &meta.GetOptions{}); err != nil {
+				return nil, errors.New("could not read HTTPS Secret " + secretName + ": " + err.Error())
+			}
+		}
+		endpoint.CaBundle = client.GetRegistryCaBundle(httpsSecret)
+	}
+	return endpoint, nil
+}
+
+// getRegistryJobEnv returns the env. variables used by registryCurlScript to call the Apicurio Registry.
+// The credentials are read from the Secret by the Job.
+func getRegistryJobEnv(clients *client.Clients, registry *ar.ApicurioRegistry, auth *ar.ApicurioRegistryBackupRegistryAuth) ([]core.EnvVar, error) {
+	endpoint, err := getRegistryEndpoint(clients, registry)
+	if err != nil {
+		return nil, err
+	}
+	res := []core.EnvVar{
+		{Name: "REGISTRY_URL", Value: endpoint.BaseUrl},
+		{Name: "REGISTRY_CA_BUNDLE", Value: endpoint.CaBundle},
+	}
+	if auth.SecretName == "" {
+		return res, nil
+	}
+	secret, err := clients.Kube().GetSecret(c.Namespace(registry.Namespace), c.Name(auth.SecretName), &meta.GetOptions{})
+	if err != nil {
+		return nil, errors.New("could not read Apicurio Registry credentials Secret " + auth.SecretName + ": " + err.Error())
+	}
+	secretKeyEnv := func(name string, key string) core.EnvVar {
+		return core.EnvVar{Name: name, ValueFrom: &core.EnvVarSource{
+			SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{Name: auth.SecretName},
+				Key:                  key,
+			},
+		}}
+	}
+	if _, exists := secret.Data[REGISTRY_AUTH_TOKEN_KEY]; exists {
+		return append(res, secretKeyEnv("REGISTRY_TOKEN", REGISTRY_AUTH_TOKEN_KEY)), nil
+	}
+	_, clientIdExists := secret.Data[REGISTRY_AUTH_CLIENT_ID_KEY]
+	_, clientSecretExists := secret.Data[REGISTRY_AUTH_CLIENT_SECRET_KEY]
+	if !clientIdExists || !clientSecretExists {
+		return nil, errors.New("Apicurio Registry credentials Secret " + auth.SecretName + " must contain the " + REGISTRY_AUTH_TOKEN_KEY +
+			" key, or the " + REGISTRY_AUTH_CLIENT_ID_KEY + " and " + REGISTRY_AUTH_CLIENT_SECRET_KEY + " keys")
+	}
+	res = append(res,
+		secretKeyEnv("REGISTRY_CLIENT_ID", REGISTRY_AUTH_CLIENT_ID_KEY),
+		secretKeyEnv("REGISTRY_CLIENT_SECRET", REGISTRY_AUTH_CLIENT_SECRET_KEY))
+	security := registry.Spec.Configuration.Security
+	switch {
+	case auth.TokenEndpoint != "":
+		res = append(res, core.EnvVar{Name: "REGISTRY_TOKEN_ENDPOINT", Value: auth.TokenEndpoint})
+	case security.Keycloak.Url != "":
+		res = append(res, core.EnvVar{Name: "REGISTRY_TOKEN_ENDPOINT", Value: strings.TrimSuffix(security.Keycloak.Url, "/") +
+			"/realms/" + security.Keycloak.Realm + "/protocol/openid-connect/token"})
+	case security.Oidc.ServerUrl != "":
+		res = append(res, core.EnvVar{Name: "REGISTRY_OIDC_SERVER_URL", Value: security.Oidc.ServerUrl})
+	default:
+		return nil, errors.New("the token endpoint can not be derived from the ApicurioRegistry configuration, " +
+			"spec.registryAuth.tokenEndpoint is required")
+	}
+	return res, nil
+}
+
+// validateRegistryAuthCredentials returns an error if authentication is enabled in the ApicurioRegistry,
+// and the credentials are not configured
+func validateRegistryAuthCredentials(registry *ar.ApicurioRegistry, auth *ar.ApicurioRegistryBackupRegistryAuth) error {
+	if isRegistryAuthEnabled(registry) && auth.SecretName == "" {
+		return errors.New("ApicurioRegistry " + registry.Name + " has authentication enabled, spec.registryAuth.secretName is required")
+	}
+	return nil
+}
+
+func isRegistryAuthEnabled(registry *ar.ApicurioRegistry) bool {
+	security := registry.Spec.Configuration.Security
+	if security.Keycloak.Url != "" || security.Oidc.ServerUrl != "" {
+		return true
+	}
+	for _, e := range registry.Spec.Configuration.Env {
+		if e.Name == cf.ENV_REGISTRY_AUTH_ENABLED && strings.EqualFold(e.Value, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// getRegistryServiceUrl returns the cluster-internal URL of the Apicurio Registry Service,
+// as reported in the ApicurioRegistry status, e.g. `http://example-service.namespace.svc:8080`.
+func getRegistryServiceUrl(clients *client.Clients, registry *ar.ApicurioRegistry) (string, error) {
+	serviceName := ""
+	for _, r := range registry.Status.ManagedResources {
+		if r.Kind == "Service" {
+			serviceName = r.Name
+		}
+	}
+	if serviceName == "" {
+		return "", errors.New("Service of ApicurioRegistry " + registry.Name + " has not been created yet")
+	}
+	service, err := clients.Kube().GetService(c.Namespace(registry.Namespace), c.Name(serviceName))
+	if err != nil {
+		return "", err
+	}
+	scheme := "http"
+	port := int32(8080)
+	for _, p := range service.Spec.Ports {
+		if p.Name == "https" {
+			scheme = "https"
+			port = p.Port
+		}
+	}
+	return scheme + "://" + service.Name + "." + service.Namespace + ".svc:" + strconv.Itoa(int(port)), nil
+}
+
+func isRegistryReady(registry *ar.ApicurioRegistry) bool {
+	return api_meta.IsStatusConditionTrue(registry.Status.Conditions, string(conditions.CONDITION_TYPE_READY))
+}
+
+// newS3Client creates a client using the credentials from the given Secret
+func newS3Client(log *zap.Logger, clients *client.Clients, namespace string, endpoint string, bucket string, region string,
+	credentialsSecretName string, insecure bool) (*client.S3Client, error) {
+
+	secret, err := clients.Kube().GetSecret(c.Namespace(namespace), c.Name(credentialsSecretName), &meta.GetOptions{})
+	if err != nil {
+		return nil, errors.New("could not read S3 credentials Secret " + credentialsSecretName + ": " + err.Error())
+	}
+	accessKey, ok1 := secret.Data[BACKUP_S3_ACCESS_KEY_ID_KEY]
+	secretKey, ok2 := secret.Data[BACKUP_S3_SECRET_ACCESS_KEY_KEY]
+	if !ok1 || !ok2 {
+		return nil, errors.New("S3 credentials Secret " + credentialsSecretName + " must contain keys " +
+			BACKUP_S3_ACCESS_KEY_ID_KEY + " and " + BACKUP_S3_SECRET_ACCESS_KEY_KEY)
+	}
+	return client.NewS3Client(log.Named("s3"), endpoint, bucket, region,
+		string(accessKey), string(secretKey), insecure)
+}
+
+// getJobName returns the name of the Job that executes the given run of the owner, e.g. a scheduled backup.
+// The name is deterministic, so a retried reconciliation finds the Job instead of creating another one.
+// Job name is used as a label value, which is limited to 63 characters.
+func getJobName(ownerName string, run string) string {
+	if max := 62 - len(run); len(ownerName) > max {
+		ownerName = ownerName[:max]
+	}
+	return strings.TrimSuffix(ownerName, "-") + "-" + run
+}
+
+// getOrCreateJob returns the existing Job with the same name, if it is owned by the owner, or creates it
+func getOrCreateJob(clients *client.Clients, owner meta.Object, job *batch.Job) (*batch.Job, error) {
+	namespace := c.Namespace(job.Namespace)
+	existing, err := clients.Kube().GetJob(namespace, c.Name(job.Name))
+	if api_errors.IsNotFound(err) {
+		existing, err = clients.Kube().CreateJob(owner, namespace, job)
+		if api_errors.IsAlreadyExists(err) {
+			existing, err = clients.Kube().GetJob(namespace, c.Name(job.Name))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !meta.IsControlledBy(existing, owner) {
+		return nil, errors.New("Job " + job.Name + " already exists and is not owned by " + owner.GetName())
+	}
+	return existing, nil
+}
+
+// newBackupJob creates a Job that executes the shell script with the volume mounted at `/backup`
+func newBackupJob(name string, namespace string, labels map[string]string, script string, env []core.EnvVar, volume core.VolumeSource) *batch.Job {
+	image := os.Getenv(ENV_OPERATOR_BACKUP_JOB_IMAGE)
+	if image == "" {
+		image = DEFAULT_BACKUP_JOB_IMAGE
+	}
+	backoffLimit := int32(2)
+	return &batch.Job{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: core.PodTemplateSpec{
+				ObjectMeta: meta.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: core.PodSpec{
+					RestartPolicy: core.RestartPolicyNever,
+					Containers: []core.Container{
+						{
+							Name:    "job",
+							Image:   image,
+							Command: []string{"/bin/sh", "-c", script},
+							Env:     env,
+							VolumeMounts: []core.VolumeMount{
+								{Name: "backup", MountPath: jobBackupMount},
+							},
+						},
+					},
+					Volumes: []core.Volume{
+						{
+							Name:         "backup",
+							VolumeSource: volume,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// getJobResult returns true if the Job has finished, with an error if it has failed
+func getJobResult(job *batch.Job) (bool, error) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != core.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batch.JobComplete:
+			return true, nil
+		case batch.JobFailed:
+			return true, fmt.Errorf("Job %s has failed: %s, see the Job logs for details", job.Name, cond.Message)
+		}
+	}
+	return false, nil
+}
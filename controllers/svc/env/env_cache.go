@@ -1,6 +1,8 @@
 package env
 
 import (
+	"strings"
+
 	core "k8s.io/api/core/v1"
 )
 
@@ -9,7 +11,6 @@ type Priority int
 const (
 	// TODO DEPRECATION:
 	// - Remove support for variables from Deployment
-	// - Make sure unused env. variables are deleted (see cf_kafkasql_security_scram)
 	// - Operator-set variables are now lowest precedence
 	PRIORITY_DEPLOYMENT Priority = 0 // TODO Deprecated
 	PRIORITY_SPEC       Priority = 1
@@ -30,6 +31,9 @@ type EnvCacheEntry interface {
 
 	GetPriority() Priority
 
+	// Name of the CF that manages the entry, or an empty string
+	GetOwner() string
+
 	IsLocked() bool
 }
 
@@ -38,6 +42,9 @@ type EnvCacheEntryBuilder interface {
 
 	SetPriority(priority Priority) EnvCacheEntryBuilder
 
+	// Entries with an owner are deleted by the owner when they are no longer needed
+	SetOwner(owner string) EnvCacheEntryBuilder
+
 	Lock() EnvCacheEntryBuilder
 
 	Build() EnvCacheEntry
@@ -47,18 +54,27 @@ type EnvCache interface {
 	// Returns the value and a boolean representing if the value exists
 	Get(key string) (EnvCacheEntry, bool)
 
-	Set(value EnvCacheEntry)
+	// Returns an error if the entry name is not a valid env. variable name,
+	// the entry is not added in that case
+	Set(value EnvCacheEntry) error
 
 	// Get the entries based on the declared dependencies,
-	// entry that has a dependency goes after it
-	// panics if there is a dependency chain longer than 20 items
-	GetSorted() []core.EnvVar
+	// entry that has a dependency goes after it.
+	// Returns a DependencyCycleError if the dependencies form a cycle,
+	// together with the entries sorted while ignoring the dependencies that close the cycle.
+	GetSorted() ([]core.EnvVar, error)
 
 	// Try to delete and return true if the key existed
 	Delete(value EnvCacheEntry) bool
 
 	DeleteByName(key string) bool
 
+	// Return the entries (not marked for deletion) with the given owner
+	GetByOwner(owner string) []EnvCacheEntry
+
+	// Try to delete the entries with the given owner and return true if any existed
+	DeleteByOwner(owner string) bool
+
 	// Return true if the enty with the given key was marked fo deletion in the
 	// given period
 	WasDeleted(key string) bool
@@ -70,3 +86,12 @@ type EnvCache interface {
 
 	IsChanged() bool
 }
+
+// Names of the entries that form a dependency cycle, in the order of addition
+type DependencyCycleError struct {
+	Names []string
+}
+
+func (this *DependencyCycleError) Error() string {
+	return "dependency cycle between environment variables " + strings.Join(this.Names, ", ")
+}
@@ -1,17 +1,21 @@
 package env
 
 import (
+	"errors"
+	"reflect"
+	"strings"
+
 	c "github.com/Apicurio/apicurio-registry-operator/controllers/common"
 	"go.uber.org/zap"
 	core "k8s.io/api/core/v1"
-	"reflect"
-	"strings"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type envCacheEntry struct {
 	value        *core.EnvVar
 	dependencies []string
 	priority     Priority
+	owner        string
 	locked       bool
 }
 
@@ -39,6 +43,10 @@ func (this *envCacheEntry) GetPriority() Priority {
 	return this.priority
 }
 
+func (this *envCacheEntry) GetOwner() string {
+	return this.owner
+}
+
 // ===
 
 type envCacheEntryBuilder struct {
@@ -84,10 +92,13 @@ func (this *envCacheEntryBuilder) SetPriority(priority Priority) EnvCacheEntryBu
 	return this
 }
 
+func (this *envCacheEntryBuilder) SetOwner(owner string) EnvCacheEntryBuilder {
+	this.entry.owner = owner
+	return this
+}
+
+// The entry name is validated when the entry is added to the cache
 func (this *envCacheEntryBuilder) Build() EnvCacheEntry {
-	if strings.TrimSpace(this.entry.GetName()) == "" {
-		panic("Environment variable can not be empty nor whitespace")
-	}
 	return this.entry
 }
 
@@ -95,7 +106,7 @@ func (this *envCacheEntryBuilder) Build() EnvCacheEntry {
 
 type envCache struct {
 	cache   map[string]EnvCacheEntry
-	sorted  []EnvCacheEntry
+	order   []string // Names in the order of addition
 	deleted map[string]bool
 	changed bool
 	log     *zap.Logger
@@ -108,7 +119,7 @@ var _ EnvCache = &envCache{}
 func NewEnvCache(log *zap.Logger) EnvCache {
 	return &envCache{
 		cache:   make(map[string]EnvCacheEntry, 0),
-		sorted:  make([]EnvCacheEntry, 0),
+		order:   make([]string, 0),
 		deleted: make(map[string]bool, 0),
 		changed: false,
 		log:     log,
@@ -127,8 +138,12 @@ func (this *envCache) Get(key string) (EnvCacheEntry, bool) {
 
 // This function does not overwrite (and mark the cache as changed) when an existing
 // env. value is found (using deep equal)
-// OR the new entry has a lower priority
-func (this *envCache) Set(value EnvCacheEntry) {
+// OR the new entry has a lower priority.
+// An entry marked for deletion is added again.
+func (this *envCache) Set(value EnvCacheEntry) error {
+	if errs := validation.IsEnvVarName(value.GetName()); len(errs) > 0 {
+		return errors.New("invalid environment variable name '" + value.GetName() + "': " + strings.Join(errs, ", "))
+	}
 	if oldValue, exists := this.cache[value.GetName()]; exists && !this.WasDeleted(value.GetName()) {
 		changed := !reflect.DeepEqual(value, oldValue)
 		if value.GetPriority().toInt() >= oldValue.GetPriority().toInt() {
@@ -139,9 +154,14 @@ func (this *envCache) Set(value EnvCacheEntry) {
 			}
 		}
 	} else {
+		if _, exists := this.cache[value.GetName()]; !exists {
+			this.order = append(this.order, value.GetName())
+		}
 		this.cache[value.GetName()] = value
+		delete(this.deleted, value.GetName())
 		this.changed = true
 	}
+	return nil
 }
 
 func (this *envCache) Delete(value EnvCacheEntry) bool {
@@ -157,6 +177,24 @@ func (this *envCache) DeleteByName(name string) bool {
 	return false
 }
 
+func (this *envCache) GetByOwner(owner string) []EnvCacheEntry {
+	res := make([]EnvCacheEntry, 0)
+	for k, v := range this.cache {
+		if v.GetOwner() == owner && !this.WasDeleted(k) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func (this *envCache) DeleteByOwner(owner string) bool {
+	res := false
+	for _, v := range this.GetByOwner(owner) {
+		res = this.DeleteByName(v.GetName()) || res
+	}
+	return res
+}
+
 func (this *envCache) WasDeleted(name string) bool {
 	_, exists := this.deleted[name]
 	return exists
@@ -165,6 +203,7 @@ func (this *envCache) WasDeleted(name string) bool {
 func (this *envCache) Clear() {
 	this.changed = true
 	this.cache = make(map[string]EnvCacheEntry, 0)
+	this.order = make([]string, 0)
 	this.deleted = make(map[string]bool, 0)
 }
 
@@ -173,6 +212,13 @@ func (this *envCache) ProcessAndAdvanceToNextPeriod() {
 	for k, _ := range this.deleted {
 		delete(this.cache, k)
 	}
+	order := make([]string, 0, len(this.cache))
+	for _, k := range this.order {
+		if _, exists := this.cache[k]; exists {
+			order = append(order, k)
+		}
+	}
+	this.order = order
 	this.deleted = make(map[string]bool, 0)
 }
 
@@ -180,43 +226,65 @@ func (this *envCache) IsChanged() bool {
 	return this.changed
 }
 
-// Copies the slice but not the values
-func (this *envCache) GetSorted() []core.EnvVar {
-	this.computeSorted()
-	res := make([]core.EnvVar, 0)
-	for _, v := range this.sorted {
-		res = append(res, *v.GetValue())
+// Depth-first topological sort. The entries are visited ordered by priority, and then by the order of addition,
+// so the ordering is only changed as far as necessary to satisfy the dependencies.
+func (this *envCache) GetSorted() ([]core.EnvVar, error) {
+	const (
+		notVisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(this.cache))
+	path := make([]string, 0)
+	inCycle := make(map[string]bool, 0)
+	res := make([]core.EnvVar, 0, len(this.cache))
+
+	var visit func(entry EnvCacheEntry)
+	visit = func(entry EnvCacheEntry) {
+		state[entry.GetName()] = inProgress
+		path = append(path, entry.GetName())
+		for _, dependencyName := range entry.GetDependencies() {
+			dependency, exists := this.Get(dependencyName)
+			if !exists {
+				this.log.Sugar().Infow("Dependency for an entry not found", "entryName", entry.GetName(), "dependencyName", dependencyName)
+				continue
+			}
+			switch state[dependencyName] {
+			case notVisited:
+				visit(dependency)
+			case inProgress:
+				// The dependency closes a cycle, it is ignored
+				for i := len(path) - 1; i >= 0; i-- {
+					inCycle[path[i]] = true
+					if path[i] == dependencyName {
+						break
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[entry.GetName()] = done
+		res = append(res, *entry.GetValue())
 	}
-	return res
-}
 
-func (this *envCache) computeSorted() {
-	this.sorted = make([]EnvCacheEntry, 0) // TODO Either use the cached value or use as a recursion argument
-	processed := make(map[string]bool, len(this.cache))
 	for p := 0; p <= PRIORITY_MAX.toInt(); p++ { // Make sure the variables are ordered by priority if possible
-		for _, v := range this.cache {
-			if v.GetPriority().toInt() == p {
-				this.processWithDependencies(0, processed, v)
+		for _, name := range this.order {
+			if entry, exists := this.Get(name); exists && entry.GetPriority().toInt() == p && state[name] == notVisited {
+				visit(entry)
 			}
 		}
 	}
-}
 
-func (this *envCache) processWithDependencies(depth int, processed map[string]bool, val EnvCacheEntry) {
-	if depth > len(this.cache) {
-		panic("Cycle detected during the processing of environment variables.")
-	}
-	if _, exists := processed[val.GetName()]; !exists && !this.WasDeleted(val.GetName()) { // Was not yet processed
-		for _, dependencyName := range val.GetDependencies() { // Process & add deps first
-			if d, exists := this.Get(dependencyName); exists {
-				this.processWithDependencies(depth+1, processed, d)
-			} else {
-				this.log.Sugar().Infow("Dependency for an entry not found", "entryName", val.GetName(), "dependencyName", dependencyName)
+	if len(inCycle) > 0 {
+		names := make([]string, 0, len(inCycle))
+		for _, name := range this.order {
+			if inCycle[name] {
+				names = append(names, name)
 			}
 		}
-		this.sorted = append(this.sorted, val)
-		processed[val.GetName()] = true
+		return res, &DependencyCycleError{Names: names}
 	}
+	return res, nil
 }
 
 func ParseJavaOptionsMap(envCache EnvCache) map[string]string {
@@ -235,7 +303,7 @@ func ParseJavaOptionsMap(envCache EnvCache) map[string]string {
 	return javaOptions
 }
 
-func SaveJavaOptionsMap(envCache EnvCache, options map[string]string, lock bool) {
+func SaveJavaOptionsMap(envCache EnvCache, options map[string]string, lock bool) error {
 	const name = "JAVA_OPTIONS"
 	javaOptions := ""
 	for k, v := range options {
@@ -252,8 +320,8 @@ func SaveJavaOptionsMap(envCache EnvCache, options map[string]string, lock bool)
 		if lock {
 			entry = entry.Lock()
 		}
-		envCache.Set(entry.Build())
-	} else {
-		envCache.DeleteByName(name)
+		return envCache.Set(entry.Build())
 	}
+	envCache.DeleteByName(name)
+	return nil
 }
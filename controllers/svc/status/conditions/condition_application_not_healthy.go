@@ -37,9 +37,19 @@ func (this *ApplicationNotHealthyCondition) TransitionNotLive() {
 	}
 }
 
-func (this *ApplicationNotHealthyCondition) TransitionHealthy() {
+func (this *ApplicationNotHealthyCondition) TransitionReplicasNotHealthy(message string) {
 	if this.data.Reason != string(APPLICATION_NOT_HEALTHY_REASON_READINESS) &&
 		this.data.Reason != string(APPLICATION_NOT_HEALTHY_REASON_LIVENESS) {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(APPLICATION_NOT_HEALTHY_REASON_REPLICAS)
+		this.data.Message = message
+	}
+}
+
+func (this *ApplicationNotHealthyCondition) TransitionHealthy() {
+	if this.data.Reason != string(APPLICATION_NOT_HEALTHY_REASON_READINESS) &&
+		this.data.Reason != string(APPLICATION_NOT_HEALTHY_REASON_LIVENESS) &&
+		this.data.Reason != string(APPLICATION_NOT_HEALTHY_REASON_REPLICAS) {
 		this.data.Status = metav1.ConditionFalse
 		this.data.Reason = "" // The condition will be inactive
 		this.data.Message = ""
@@ -0,0 +1,43 @@
+package conditions
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ConfigurationWarningCondition struct {
+	condition
+}
+
+var _ Condition = &ConfigurationWarningCondition{}
+
+func NewConfigurationWarningCondition() *ConfigurationWarningCondition {
+	this := &ConfigurationWarningCondition{}
+	this.SetType(CONDITION_TYPE_CONFIGURATION_WARNING)
+	this.Reset()
+	return this
+}
+
+func (this *ConfigurationWarningCondition) IsActive() bool {
+	return this.data.Status == metav1.ConditionTrue
+}
+
+// Transitions in decreasing order of priority
+
+func (this *ConfigurationWarningCondition) TransitionEnvFromConflict(names []string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(CONFIGURATION_WARNING_CONDITION_REASON_ENV_FROM_CONFLICT)
+	this.data.Message = "Environment variables from spec.configuration.envFrom are overridden by the operator: " +
+		strings.Join(names, ", ")
+}
+
+func (this *ConfigurationWarningCondition) TransitionPropertyOverridden(names []string) {
+	if this.data.Reason != string(CONFIGURATION_WARNING_CONDITION_REASON_ENV_FROM_CONFLICT) {
+
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(CONFIGURATION_WARNING_CONDITION_REASON_PROPERTY_OVERRIDDEN)
+		this.data.Message = "Properties from spec.configuration.propertiesConfigMapRef are overridden by environment variables set by the operator: " +
+			strings.Join(names, ", ")
+	}
+}
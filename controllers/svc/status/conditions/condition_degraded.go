@@ -0,0 +1,47 @@
+package conditions
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DegradedCondition struct {
+	condition
+}
+
+var _ Condition = &DegradedCondition{}
+
+func NewDegradedCondition() *DegradedCondition {
+	this := &DegradedCondition{}
+	this.SetType(CONDITION_TYPE_DEGRADED)
+	this.Reset()
+	return this
+}
+
+func (this *DegradedCondition) IsActive() bool {
+	return this.data.Status == metav1.ConditionTrue
+}
+
+// Transitions in decreasing order of priority
+
+func (this *DegradedCondition) TransitionImagePullBackOff(message string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(DEGRADED_REASON_IMAGE_PULL_BACK_OFF)
+	this.data.Message = message
+}
+
+func (this *DegradedCondition) TransitionOOMKilled(message string) {
+	if this.data.Reason != string(DEGRADED_REASON_IMAGE_PULL_BACK_OFF) {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(DEGRADED_REASON_OOM_KILLED)
+		this.data.Message = message
+	}
+}
+
+func (this *DegradedCondition) TransitionCrashLoopBackOff(message string) {
+	if this.data.Reason != string(DEGRADED_REASON_IMAGE_PULL_BACK_OFF) &&
+		this.data.Reason != string(DEGRADED_REASON_OOM_KILLED) {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(DEGRADED_REASON_CRASH_LOOP_BACK_OFF)
+		this.data.Message = message
+	}
+}
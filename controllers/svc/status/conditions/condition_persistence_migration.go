@@ -0,0 +1,61 @@
+package conditions
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type PersistenceMigrationCondition struct {
+	condition
+}
+
+var _ Condition = &PersistenceMigrationCondition{}
+
+func NewPersistenceMigrationCondition() *PersistenceMigrationCondition {
+	this := &PersistenceMigrationCondition{}
+	this.SetType(CONDITION_TYPE_PERSISTENCE_MIGRATION)
+	this.Reset()
+	return this
+}
+
+// The condition is displayed after a migration has been started, until the next one
+func (this *PersistenceMigrationCondition) IsActive() bool {
+	return this.data.Status != metav1.ConditionUnknown
+}
+
+// The condition is True while the migration is in progress
+
+func (this *PersistenceMigrationCondition) TransitionExporting(from string, to string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_EXPORTING)
+	this.data.Message = "Exporting data from the " + from + " storage before migrating to the " + to + " storage."
+}
+
+func (this *PersistenceMigrationCondition) TransitionRollingOut(to string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_ROLLING_OUT)
+	this.data.Message = "Waiting for Apicurio Registry with the " + to + " storage to be rolled out."
+}
+
+func (this *PersistenceMigrationCondition) TransitionImporting(to string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_IMPORTING)
+	this.data.Message = "Importing data into the " + to + " storage."
+}
+
+func (this *PersistenceMigrationCondition) TransitionCleaningUp(from string) {
+	this.data.Status = metav1.ConditionTrue
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_CLEANING_UP)
+	this.data.Message = "Finishing the migration from the " + from + " storage."
+}
+
+func (this *PersistenceMigrationCondition) TransitionCompleted(from string, to string) {
+	this.data.Status = metav1.ConditionFalse
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_COMPLETED)
+	this.data.Message = "Data has been migrated from the " + from + " storage to the " + to + " storage."
+}
+
+func (this *PersistenceMigrationCondition) TransitionFailed(details string) {
+	this.data.Status = metav1.ConditionFalse
+	this.data.Reason = string(PERSISTENCE_MIGRATION_REASON_FAILED)
+	this.data.Message = "Persistence migration has failed: " + details
+}
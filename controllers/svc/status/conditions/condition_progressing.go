@@ -0,0 +1,47 @@
+package conditions
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ProgressingCondition struct {
+	condition
+}
+
+var _ Condition = &ProgressingCondition{}
+
+func NewProgressingCondition() *ProgressingCondition {
+	this := &ProgressingCondition{}
+	this.SetType(CONDITION_TYPE_PROGRESSING)
+	this.Reset()
+	return this
+}
+
+func (this *ProgressingCondition) IsActive() bool {
+	return this.data.Status != metav1.ConditionUnknown
+}
+
+// Transitions in decreasing order of priority
+
+// The rollout has stalled, the status is False so the clients waiting for the rollout to finish do not wait forever
+func (this *ProgressingCondition) TransitionProgressDeadlineExceeded(message string) {
+	this.data.Status = metav1.ConditionFalse
+	this.data.Reason = string(PROGRESSING_REASON_DEADLINE_EXCEEDED)
+	this.data.Message = message
+}
+
+func (this *ProgressingCondition) TransitionRolloutInProgress(message string) {
+	if this.data.Reason != string(PROGRESSING_REASON_DEADLINE_EXCEEDED) {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(PROGRESSING_REASON_ROLLOUT_IN_PROGRESS)
+		this.data.Message = message
+	}
+}
+
+func (this *ProgressingCondition) TransitionRolloutComplete(message string) {
+	if this.data.Status == metav1.ConditionUnknown {
+		this.data.Status = metav1.ConditionFalse
+		this.data.Reason = string(PROGRESSING_REASON_ROLLOUT_COMPLETE)
+		this.data.Message = message
+	}
+}
@@ -0,0 +1,60 @@
+package conditions
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type StorageReadyCondition struct {
+	condition
+}
+
+var _ Condition = &StorageReadyCondition{}
+
+func NewStorageReadyCondition() *StorageReadyCondition {
+	this := &StorageReadyCondition{}
+	this.SetType(CONDITION_TYPE_STORAGE_READY)
+	this.Reset()
+	return this
+}
+
+func (this *StorageReadyCondition) IsActive() bool {
+	return this.data.Status != metav1.ConditionUnknown
+}
+
+// The registry deployment must not be started until the storage is ready
+func (this *StorageReadyCondition) IsBlockingDeployment() bool {
+	return this.data.Status == metav1.ConditionFalse &&
+		this.data.Reason == string(STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_NOT_READY)
+}
+
+// Transitions in decreasing order of priority
+
+func (this *StorageReadyCondition) TransitionKafkaTopicNotReady(message string) {
+	this.data.Status = metav1.ConditionFalse
+	this.data.Reason = string(STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_NOT_READY)
+	this.data.Message = message
+}
+
+func (this *StorageReadyCondition) TransitionStorageUnreachable(message string) {
+	if this.data.Reason != string(STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_NOT_READY) {
+		this.data.Status = metav1.ConditionFalse
+		this.data.Reason = string(STORAGE_READY_CONDITION_REASON_UNREACHABLE)
+		this.data.Message = message
+	}
+}
+
+func (this *StorageReadyCondition) TransitionKafkaTopicReady(topicName string) {
+	if this.data.Status != metav1.ConditionFalse {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_READY)
+		this.data.Message = "Kafka topic " + topicName + " is ready"
+	}
+}
+
+func (this *StorageReadyCondition) TransitionStorageReachable(message string) {
+	if this.data.Status == metav1.ConditionUnknown {
+		this.data.Status = metav1.ConditionTrue
+		this.data.Reason = string(STORAGE_READY_CONDITION_REASON_REACHABLE)
+		this.data.Message = message
+	}
+}
@@ -10,6 +10,11 @@ const (
 	CONDITION_TYPE_READY                   ConditionType = "Ready"
 	CONDITION_TYPE_CONFIGURATION_ERROR     ConditionType = "ConfigurationError"
 	CONDITION_TYPE_APPLICATION_NOT_HEALTHY ConditionType = "ApplicationNotHealthy"
+	CONDITION_TYPE_PERSISTENCE_MIGRATION   ConditionType = "PersistenceMigration"
+	CONDITION_TYPE_CONFIGURATION_WARNING   ConditionType = "ConfigurationWarning"
+	CONDITION_TYPE_STORAGE_READY           ConditionType = "StorageReady"
+	CONDITION_TYPE_DEGRADED                ConditionType = "Degraded"
+	CONDITION_TYPE_PROGRESSING             ConditionType = "Progressing"
 	// CONDITION_TYPE_OPERATOR_ERROR ConditionType = "OperatorError" // General error
 )
 
@@ -54,6 +59,29 @@ const (
 	CONFIGURATION_ERROR_CONDITION_REASON_INVALID             ConfigurationErrorConditionReason = "InvalidValue"
 )
 
+// ========== ConfigurationWarningCondition ==========
+
+type ConfigurationWarningConditionReason string
+
+const (
+	// Priority ordered
+	CONFIGURATION_WARNING_CONDITION_REASON_ENV_FROM_CONFLICT   ConfigurationWarningConditionReason = "EnvFromConflict"
+	CONFIGURATION_WARNING_CONDITION_REASON_PROPERTY_OVERRIDDEN ConfigurationWarningConditionReason = "PropertyOverridden"
+)
+
+// ========== StorageReadyCondition ==========
+
+type StorageReadyConditionReason string
+
+const (
+	// Priority ordered
+	STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_NOT_READY StorageReadyConditionReason = "KafkaTopicNotReady"
+	// The storage connectivity checks do not block the deployment
+	STORAGE_READY_CONDITION_REASON_UNREACHABLE       StorageReadyConditionReason = "StorageUnreachable"
+	STORAGE_READY_CONDITION_REASON_KAFKA_TOPIC_READY StorageReadyConditionReason = "KafkaTopicReady"
+	STORAGE_READY_CONDITION_REASON_REACHABLE         StorageReadyConditionReason = "StorageReachable"
+)
+
 // ========== ApplicationNotHealthyCondition ==========
 
 type ApplicationNotHealthyConditionReason string
@@ -62,6 +90,43 @@ const (
 	// Priority ordered
 	APPLICATION_NOT_HEALTHY_REASON_READINESS ApplicationNotHealthyConditionReason = "ReadinessProbeFailed"
 	APPLICATION_NOT_HEALTHY_REASON_LIVENESS  ApplicationNotHealthyConditionReason = "LivenessProbeFailed"
+	// Some of the replicas are not healthy, but the application is available
+	APPLICATION_NOT_HEALTHY_REASON_REPLICAS ApplicationNotHealthyConditionReason = "ReplicasNotHealthy"
+)
+
+// ========== DegradedCondition ==========
+
+type DegradedConditionReason string
+
+const (
+	// Priority ordered
+	DEGRADED_REASON_IMAGE_PULL_BACK_OFF DegradedConditionReason = "ImagePullBackOff"
+	DEGRADED_REASON_OOM_KILLED          DegradedConditionReason = "OOMKilled"
+	DEGRADED_REASON_CRASH_LOOP_BACK_OFF DegradedConditionReason = "CrashLoopBackOff"
+)
+
+// ========== ProgressingCondition ==========
+
+type ProgressingConditionReason string
+
+const (
+	// Priority ordered
+	PROGRESSING_REASON_DEADLINE_EXCEEDED   ProgressingConditionReason = "ProgressDeadlineExceeded"
+	PROGRESSING_REASON_ROLLOUT_IN_PROGRESS ProgressingConditionReason = "RolloutInProgress"
+	PROGRESSING_REASON_ROLLOUT_COMPLETE    ProgressingConditionReason = "RolloutComplete"
+)
+
+// ========== PersistenceMigrationCondition ==========
+
+type PersistenceMigrationConditionReason string
+
+const (
+	PERSISTENCE_MIGRATION_REASON_EXPORTING   PersistenceMigrationConditionReason = "Exporting"
+	PERSISTENCE_MIGRATION_REASON_ROLLING_OUT PersistenceMigrationConditionReason = "RollingOut"
+	PERSISTENCE_MIGRATION_REASON_IMPORTING   PersistenceMigrationConditionReason = "Importing"
+	PERSISTENCE_MIGRATION_REASON_CLEANING_UP PersistenceMigrationConditionReason = "CleaningUp"
+	PERSISTENCE_MIGRATION_REASON_COMPLETED   PersistenceMigrationConditionReason = "Completed"
+	PERSISTENCE_MIGRATION_REASON_FAILED      PersistenceMigrationConditionReason = "Failed"
 )
 
 // ========== ConditionManager ==========
@@ -73,6 +138,16 @@ type ConditionManager interface {
 
 	GetApplicationNotHealthyCondition() *ApplicationNotHealthyCondition
 
+	GetPersistenceMigrationCondition() *PersistenceMigrationCondition
+
+	GetConfigurationWarningCondition() *ConfigurationWarningCondition
+
+	GetStorageReadyCondition() *StorageReadyCondition
+
+	GetDegradedCondition() *DegradedCondition
+
+	GetProgressingCondition() *ProgressingCondition
+
 	// Runs after the control loop is stable
 	AfterLoop()
 
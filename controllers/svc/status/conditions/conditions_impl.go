@@ -54,12 +54,17 @@ var _ ConditionManager = &conditionManager{}
 
 func NewConditionManager(ctx context.LoopContext) ConditionManager {
 	this := &conditionManager{
-		conditionMap: make(map[ConditionType]Condition, 3),
+		conditionMap: make(map[ConditionType]Condition, 5),
 		ctx:          ctx,
 	}
 	this.conditionMap[CONDITION_TYPE_READY] = NewReadyCondition()
 	this.conditionMap[CONDITION_TYPE_CONFIGURATION_ERROR] = NewConfigurationErrorCondition()
 	this.conditionMap[CONDITION_TYPE_APPLICATION_NOT_HEALTHY] = NewApplicationNotHealthyCondition()
+	this.conditionMap[CONDITION_TYPE_PERSISTENCE_MIGRATION] = NewPersistenceMigrationCondition()
+	this.conditionMap[CONDITION_TYPE_CONFIGURATION_WARNING] = NewConfigurationWarningCondition()
+	this.conditionMap[CONDITION_TYPE_STORAGE_READY] = NewStorageReadyCondition()
+	this.conditionMap[CONDITION_TYPE_DEGRADED] = NewDegradedCondition()
+	this.conditionMap[CONDITION_TYPE_PROGRESSING] = NewProgressingCondition()
 	return this
 }
 
@@ -75,6 +80,26 @@ func (this *conditionManager) GetApplicationNotHealthyCondition() *ApplicationNo
 	return this.conditionMap[CONDITION_TYPE_APPLICATION_NOT_HEALTHY].(*ApplicationNotHealthyCondition)
 }
 
+func (this *conditionManager) GetPersistenceMigrationCondition() *PersistenceMigrationCondition {
+	return this.conditionMap[CONDITION_TYPE_PERSISTENCE_MIGRATION].(*PersistenceMigrationCondition)
+}
+
+func (this *conditionManager) GetConfigurationWarningCondition() *ConfigurationWarningCondition {
+	return this.conditionMap[CONDITION_TYPE_CONFIGURATION_WARNING].(*ConfigurationWarningCondition)
+}
+
+func (this *conditionManager) GetStorageReadyCondition() *StorageReadyCondition {
+	return this.conditionMap[CONDITION_TYPE_STORAGE_READY].(*StorageReadyCondition)
+}
+
+func (this *conditionManager) GetDegradedCondition() *DegradedCondition {
+	return this.conditionMap[CONDITION_TYPE_DEGRADED].(*DegradedCondition)
+}
+
+func (this *conditionManager) GetProgressingCondition() *ProgressingCondition {
+	return this.conditionMap[CONDITION_TYPE_PROGRESSING].(*ProgressingCondition)
+}
+
 // Mark the status as `Reconciling` if there was a CF execution, (and reschedule) otherwise
 // mask as `Reconciled`
 func (this *conditionManager) AfterLoop() {
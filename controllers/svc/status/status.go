@@ -1,16 +1,20 @@
 package status
 
 import (
+	"net"
 	"strconv"
 
 	api "github.com/Apicurio/apicurio-registry-operator/api/v1"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/loop/context"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/resources"
 	"github.com/Apicurio/apicurio-registry-operator/controllers/svc/status/conditions"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
 )
 
 // status
 const CFG_STA_IMAGE = "CFG_STA_IMAGE"
+const CFG_STA_VERSION = "CFG_STA_VERSION"
 
 const CFG_STA_DEPLOYMENT_NAME = "CFG_STA_DEPLOYMENT_NAME"
 const CFG_STA_SERVICE_NAME = "CFG_STA_SERVICE_NAME"
@@ -42,6 +46,7 @@ func (this *Status) init() {
 	// DO NOT USE `spec` ! It's nil at this point
 	// status
 	this.set(this.config, CFG_STA_IMAGE, "")
+	this.set(this.config, CFG_STA_VERSION, "")
 
 	this.set(this.config, CFG_STA_DEPLOYMENT_NAME, "")
 	this.set(this.config, CFG_STA_SERVICE_NAME, "")
@@ -100,8 +105,13 @@ func (this *Status) ComputeStatus() {
 		entry.ApplyPatch(func(value interface{}) interface{} {
 			status := value.(*api.ApicurioRegistryStatus).DeepCopy()
 
+			// Generation of the spec used by the loop
+			if specEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SPEC); exists {
+				status.ObservedGeneration = specEntry.GetValue().(*api.ApicurioRegistry).Generation
+			}
+
 			// Info
-			status.Info.Host = this.GetConfig(CFG_STA_ROUTE)
+			status.Info = this.computeInfo(status)
 
 			// Conditions
 			status.Conditions = this.conditions.Execute()
@@ -150,3 +160,63 @@ func (this *Status) ComputeStatus() {
 		})
 	}
 }
+
+func (this *Status) computeInfo(status *api.ApicurioRegistryStatus) api.ApicurioRegistryStatusInfo {
+	res := api.ApicurioRegistryStatusInfo{
+		Host:    this.GetConfig(CFG_STA_ROUTE),
+		Image:   this.GetConfig(CFG_STA_IMAGE),
+		Version: this.GetConfig(CFG_STA_VERSION),
+	}
+	if status.Persistence != nil {
+		res.Persistence = status.Persistence.Type
+	}
+	if serviceEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_SERVICE); exists &&
+		serviceEntry.GetName() != resources.RC_NOT_CREATED_NAME_EMPTY {
+		res.HttpsEnabled, res.ServiceUrl = getServiceUrl(serviceEntry.GetValue().(*core.Service))
+	}
+	if res.Host != "" {
+		var ingress *networking.Ingress
+		if ingressEntry, exists := this.ctx.GetResourceCache().Get(resources.RC_KEY_INGRESS); exists {
+			ingress = ingressEntry.GetValue().(*networking.Ingress)
+		}
+		baseUrl := getIngressScheme(ingress, res.Host) + res.Host
+		res.UiUrl = baseUrl + "/ui"
+		res.RestApiUrl = baseUrl + "/apis/registry/v2"
+		res.CcompatApiUrl = baseUrl + "/apis/ccompat/v6"
+	}
+	return res
+}
+
+// Return whether HTTPS is enabled, and the URL of the Service, which uses HTTPS if it is enabled
+func getServiceUrl(service *core.Service) (bool, string) {
+	host := service.Name + "." + service.Namespace + ".svc"
+	for _, port := range service.Spec.Ports {
+		if port.Name == "https" {
+			return true, "https://" + net.JoinHostPort(host, strconv.Itoa(int(port.Port)))
+		}
+	}
+	for _, port := range service.Spec.Ports {
+		if port.Name == "http" {
+			return false, "http://" + net.JoinHostPort(host, strconv.Itoa(int(port.Port)))
+		}
+	}
+	return false, ""
+}
+
+// The Ingress routes the requests to the HTTP port of the Service,
+// so HTTPS is used only if TLS is terminated by the Ingress, or by the OpenShift Route created for the Ingress
+func getIngressScheme(ingress *networking.Ingress, host string) string {
+	if ingress != nil {
+		for _, tls := range ingress.Spec.TLS {
+			for _, tlsHost := range tls.Hosts {
+				if tlsHost == host {
+					return "https://"
+				}
+			}
+		}
+		if ingress.Annotations["route.openshift.io/termination"] != "" {
+			return "https://"
+		}
+	}
+	return "http://"
+}
@@ -11,6 +11,7 @@ require (
 	github.com/openshift/client-go v0.0.0-20210112165513-ebc401615f47
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.46.0
 	github.com/prometheus-operator/prometheus-operator/pkg/client v0.46.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/sykesm/zap-logfmt v0.0.4
 	go.uber.org/zap v1.19.1
 	k8s.io/api v0.23.0
@@ -35,9 +35,12 @@ import (
 	"github.com/go-logr/zapr"
 	ocp_apps "github.com/openshift/api/apps/v1"
 	monitoring "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	// +kubebuilder:scaffold:imports
@@ -63,6 +66,14 @@ func initControllers(mgr manager.Manager) error {
 		setupLog.Error(err, "unable to create controller", "controller", "ApicurioRegistry")
 		return errors.New("unable to create ApicurioRegistry controller")
 	}
+	if _, err := controllers.NewApicurioRegistryBackupReconciler(mgr, rootLog); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ApicurioRegistryBackup")
+		return errors.New("unable to create ApicurioRegistryBackup controller")
+	}
+	if _, err := controllers.NewApicurioRegistryRestoreReconciler(mgr, rootLog); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ApicurioRegistryRestore")
+		return errors.New("unable to create ApicurioRegistryRestore controller")
+	}
 
 	return nil
 }
@@ -86,11 +97,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	var newCache cache.NewCacheFunc = nil // Defaults to all
-	if namespaces != "" {
-		newCache = cache.MultiNamespacedCacheBuilder(strings.Split(namespaces, ","))
-	}
-
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -98,7 +104,7 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "0eef189c.apicur.io",
-		NewCache:               newCache,
+		NewCache:               newCacheFunc(namespaces),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -128,6 +134,20 @@ func main() {
 	}
 }
 
+// Only the Apicurio Registry pods are watched, so the other pods in the watched namespaces are not cached
+func newCacheFunc(namespaces string) cache.NewCacheFunc {
+	newCache := cache.New // Defaults to all
+	if namespaces != "" {
+		newCache = cache.MultiNamespacedCacheBuilder(strings.Split(namespaces, ","))
+	}
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		opts.SelectorsByObject = cache.SelectorsByObject{
+			&core.Pod{}: {Label: labels.SelectorFromSet(map[string]string{"apicur.io/type": "apicurio-registry"})},
+		}
+		return newCache(config, opts)
+	}
+}
+
 // From k8sutil
 func getWatchNamespace() (string, error) {
 	ns, found := os.LookupEnv("WATCH_NAMESPACE")